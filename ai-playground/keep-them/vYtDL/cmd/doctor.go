@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/innate/yt-dl/internal/config"
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+var (
+	flagDoctorBin     string
+	flagDoctorOutput  string
+	flagDoctorCookies string
+)
+
+// staleAfter is how old a yt-dlp release can get before doctor suggests an
+// update; sites change faster than that.
+const staleAfter = 90 * 24 * time.Hour
+
+// doctorFlags are the options buildArgs uses when the installed tool has them.
+var doctorFlags = []string{
+	"--download-sections", "--write-subs", "--extractor-retries", "--progress",
+	"--cookies-from-browser", "--extractor-args", "--limit-rate",
+}
+
+func init() {
+	dc := doctorCmd
+	cfg := config.Load()
+
+	dc.Flags().StringVar(&flagDoctorBin, "yt-dlp-bin", cfg.YTDLPBin,
+		"Path to the yt-dlp/youtube-dl binary")
+	dc.Flags().StringVarP(&flagDoctorOutput, "output", "o", ".",
+		"Output directory to test for write access")
+	dc.Flags().StringVar(&flagDoctorCookies, "cookies", "",
+		"Netscape-format cookies file to validate")
+
+	rootCmd.AddCommand(dc)
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check yt-dlp, ffmpeg, the output directory and cookies",
+	Long: `Check the environment downloads depend on: which yt-dlp binary is used
+and what it supports, ffmpeg and ffprobe, write access to the output
+directory and the format of the cookies file.
+
+Exits non-zero when a check fails; warnings do not fail.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+// doctorCheck is the outcome of one doctor check.
+type doctorCheck struct {
+	Name   string
+	Status string // "ok", "warn", "fail" or "skip"
+	Detail string
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	var checks []doctorCheck
+	binCheck, caps := checkBinary(flagDoctorBin)
+	checks = append(checks, binCheck)
+	checks = append(checks, checkVersion(caps)...)
+	checks = append(checks,
+		checkTool("ffmpeg", "fail", "needed to merge video and audio and for --start/--end"),
+		checkTool("ffprobe", "warn", "used by yt-dlp to inspect merged files"),
+		checkOutputDir(flagDoctorOutput),
+		checkCookies(flagDoctorCookies),
+	)
+
+	failed := 0
+	for _, c := range checks {
+		fmt.Printf("[%-4s] %-10s %s\n", c.Status, c.Name, c.Detail)
+		if c.Status == "fail" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("doctor: %d check(s) failed", failed)
+	}
+	return nil
+}
+
+// checkBinary resolves the binary the way downloads do and detects what it
+// supports. caps is nil when the binary cannot be run.
+func checkBinary(configured string) (doctorCheck, *ytdl.Capabilities) {
+	check := doctorCheck{Name: "yt-dlp"}
+	bin, err := ytdl.New(ytdl.WithBinary(configured)).ResolveBinary()
+	if err != nil {
+		check.Status, check.Detail = "fail", err.Error()
+		return check, nil
+	}
+	if _, err := exec.LookPath(bin); err != nil {
+		check.Status = "fail"
+		check.Detail = fmt.Sprintf("%s is not executable (set yt_dlp_bin in config.json or pass --yt-dlp-bin)", bin)
+		if fallback, err := ytdl.New().ResolveBinary(); err == nil {
+			check.Detail += fmt.Sprintf("; %s would work", fallback)
+		}
+		return check, nil
+	}
+	caps, err := ytdl.DetectCapabilities(bin)
+	if err != nil {
+		check.Status, check.Detail = "fail", err.Error()
+		return check, nil
+	}
+	check.Status, check.Detail = "ok", bin
+	return check, &caps
+}
+
+func checkVersion(caps *ytdl.Capabilities) []doctorCheck {
+	if caps == nil {
+		return []doctorCheck{{Name: "version", Status: "skip", Detail: "no usable binary"}}
+	}
+	version := doctorCheck{Name: "version", Status: "ok", Detail: caps.Version}
+	if released := caps.ReleaseDate(); released.IsZero() {
+		version.Status = "warn"
+		version.Detail += " (not a dated yt-dlp release; youtube-dl lacks several options)"
+	} else if age := time.Since(released); age > staleAfter {
+		version.Status = "warn"
+		version.Detail += fmt.Sprintf(" is %d days old; update with yt-dlp -U", int(age.Hours()/24))
+	}
+
+	features := doctorCheck{Name: "features", Status: "ok", Detail: "all options used by yt-dl are supported"}
+	var missing []string
+	for _, flag := range doctorFlags {
+		if !caps.Supports(flag) {
+			missing = append(missing, flag)
+		}
+	}
+	if len(missing) > 0 {
+		features.Status = "warn"
+		features.Detail = "missing " + strings.Join(missing, ", ") + "; downloads fall back or skip them"
+	}
+	return []doctorCheck{version, features}
+}
+
+// checkTool looks for name in PATH and reports the first line of -version.
+func checkTool(name, missingStatus, why string) doctorCheck {
+	check := doctorCheck{Name: name}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		check.Status, check.Detail = missingStatus, "not found in PATH; "+why
+		return check
+	}
+	out, err := exec.Command(path, "-version").Output()
+	if err != nil {
+		check.Status, check.Detail = missingStatus, fmt.Sprintf("%s -version: %v", path, err)
+		return check
+	}
+	first, _, _ := strings.Cut(string(out), "\n")
+	check.Status, check.Detail = "ok", strings.TrimSpace(first)
+	return check
+}
+
+// checkOutputDir creates and removes a file in dir.
+func checkOutputDir(dir string) doctorCheck {
+	check := doctorCheck{Name: "output"}
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, ".yt-dl-doctor-*")
+	if err != nil {
+		check.Status, check.Detail = "fail", fmt.Sprintf("cannot write to %s: %v", dir, err)
+		return check
+	}
+	f.Close()
+	os.Remove(f.Name())
+	check.Status, check.Detail = "ok", dir+" is writable"
+	return check
+}
+
+func checkCookies(path string) doctorCheck {
+	check := doctorCheck{Name: "cookies"}
+	if strings.TrimSpace(path) == "" {
+		check.Status, check.Detail = "skip", "no --cookies file given"
+		return check
+	}
+	if err := ytdl.ValidateCookiesFile(path); err != nil {
+		check.Status, check.Detail = "fail", fmt.Sprintf("%s: %v", path, err)
+		return check
+	}
+	check.Status, check.Detail = "ok", path
+	return check
+}
@@ -1,21 +1,35 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/innate/yt-dl/internal/config"
-	"github.com/innate/yt-dl/internal/downloader"
-	"github.com/innate/yt-dl/internal/record"
 	"github.com/innate/yt-dl/internal/tui"
+	"github.com/innate/yt-dl/pkg/ytdl"
 )
 
 var (
 	flagFormat      string
 	flagQuality     string
+	flagFormatID    string
+	flagPickFormat  bool
+	flagPreferV     string
+	flagPreferA     string
+	flagAvoidV      string
+	flagAvoidA      string
+	flagMaxFPS      string
+	flagNoHDR       bool
+	flagMaxSize     string
 	flagStartTime   string
 	flagEndTime     string
 	flagOutputDir   string
@@ -23,11 +37,24 @@ var (
 	flagNoSubs      bool
 	flagNoAutoSubs  bool
 	flagPlaylist    bool
+	flagItems       string
+	flagSelect      bool
+	flagRetryKinds  string
+	flagMaxRetries  int
+	flagRetryDelay  time.Duration
+	flagMaxAttempts int
+	flagCooldown    time.Duration
+	flagCooldowns   int
 	flagLogFormat   string
 	flagRecordFile  string
 	flagMappingFile string
 	flagNoTUI       bool
+	flagOutputMode  string
+	flagEventLog    string
+	flagLogLevel    string
+	flagDryRun      string
 	flagYTDLPBin    string
+	flagBackend     string
 	flagProxy       string
 	flagCookiesFile string
 	flagCookiesFrom string
@@ -36,7 +63,16 @@ var (
 	flagRetries     string
 	flagTimeout     string
 	flagForceIPv4   bool
+	flagLimitRate   string
+	flagWindow      string
+	flagExecAfter   string
+	flagWebhook     string
+	flagHookTimeout time.Duration
 	flagResetState  bool
+	flagOverride    bool
+	flagIndexPrefix bool
+	flagFeed        bool
+	flagArchive     string
 )
 
 func init() {
@@ -47,6 +83,24 @@ func init() {
 		"Output container format: mp4, webm, mkv, …")
 	dl.Flags().StringVarP(&flagQuality, "quality", "q", "",
 		"Video quality: 720, 1080, 2160, … (empty = best)")
+	dl.Flags().StringVar(&flagFormatID, "format-id", "",
+		"Explicit yt-dlp format selector, e.g. 137+140 (see the formats command); overrides --quality")
+	dl.Flags().BoolVar(&flagPickFormat, "pick-format", false,
+		"Choose the video/audio format interactively before downloading (single videos only)")
+	dl.Flags().StringVar(&flagPreferV, "prefer-vcodec", "",
+		"Comma-separated video codecs to prefer, in order: h264, h265, vp9, av1")
+	dl.Flags().StringVar(&flagPreferA, "prefer-acodec", "",
+		"Comma-separated audio codecs to prefer, in order: aac, opus, mp3")
+	dl.Flags().StringVar(&flagAvoidV, "avoid-vcodec", "",
+		"Comma-separated video codecs that must not be chosen, e.g. av1")
+	dl.Flags().StringVar(&flagAvoidA, "avoid-acodec", "",
+		"Comma-separated audio codecs that must not be chosen")
+	dl.Flags().StringVar(&flagMaxFPS, "max-fps", "",
+		"Maximum video frame rate, e.g. 30 (empty = no limit)")
+	dl.Flags().BoolVar(&flagNoHDR, "no-hdr", false,
+		"Never choose HDR video streams")
+	dl.Flags().StringVar(&flagMaxSize, "max-filesize", "",
+		"Maximum size per stream, e.g. 500M (streams of unknown size are still allowed)")
 	dl.Flags().StringVar(&flagStartTime, "start", "",
 		"Clip start time (HH:MM:SS or seconds)")
 	dl.Flags().StringVar(&flagEndTime, "end", "",
@@ -61,6 +115,28 @@ func init() {
 		"Disable auto-generated subtitle download")
 	dl.Flags().BoolVarP(&flagPlaylist, "playlist", "p", false,
 		"Treat URL as a playlist / collection")
+	dl.Flags().StringVar(&flagItems, "items", "",
+		"Only download these playlist positions, e.g. 1-10,15,20- (others are marked skipped)")
+	dl.Flags().BoolVar(&flagFeed, "feed", false,
+		"List new videos from the channel's Atom/RSS feed (or a feed URL or file) instead of the full playlist; implies --playlist")
+	dl.Flags().BoolVar(&flagIndexPrefix, "index-prefix", false,
+		"Start playlist filenames with the playlist position, e.g. \"0007 - Title.mp4\" (not with --feed)")
+	dl.Flags().StringVar(&flagArchive, "archive", "",
+		"yt-dlp download archive: skip playlist entries listed in it and add new downloads")
+	dl.Flags().BoolVar(&flagSelect, "select", false,
+		"Pick playlist entries interactively before downloading")
+	dl.Flags().StringVar(&flagRetryKinds, "retry-kinds", "",
+		"On playlist resume, only retry failed entries of these error kinds (e.g. network,rate_limited)")
+	dl.Flags().IntVar(&flagMaxRetries, "max-retries", 2,
+		"Re-run yt-dlp this many times after a network, rate-limit or unknown failure")
+	dl.Flags().DurationVar(&flagRetryDelay, "retry-delay", 10*time.Second,
+		"Backoff before the first re-run; doubles for each further one")
+	dl.Flags().DurationVar(&flagCooldown, "rate-limit-cooldown", 5*time.Minute,
+		"Pause the queue this long after HTTP 429 or a bot check; doubles on repeats (0 = off)")
+	dl.Flags().IntVar(&flagCooldowns, "max-cooldowns", 3,
+		"Fail an item after this many rate-limit cooldowns in a row; a bot check gets one")
+	dl.Flags().IntVar(&flagMaxAttempts, "max-attempts", 0,
+		"Give up on a playlist entry after this many attempts across runs (0 = no limit)")
 	dl.Flags().StringVar(&flagLogFormat, "log-format", "json",
 		"Record / mapping file format: json or csv")
 	dl.Flags().StringVar(&flagRecordFile, "record-file", "download_record",
@@ -69,8 +145,19 @@ func init() {
 		"Base name (no extension) for the subtitle-video mapping file")
 	dl.Flags().BoolVar(&flagNoTUI, "no-tui", false,
 		"Disable TUI; print plain progress to stdout")
+	dl.Flags().StringVar(&flagOutputMode, "output-mode", outputTUI,
+		"Progress output: tui, plain or ndjson (one JSON event per line on stdout)")
+	dl.Flags().StringVar(&flagEventLog, "event-log", "",
+		"Also write every progress event as NDJSON to this file")
+	dl.Flags().StringVar(&flagDryRun, "dry-run", "",
+		"Print what would be downloaded, as a table or --dry-run=json, and exit")
+	dl.Flags().Lookup("dry-run").NoOptDefVal = "table"
+	dl.Flags().StringVar(&flagLogLevel, "log-level", "info",
+		"Run log detail in <output>/.logs/run.log: debug, info, warn, error or off")
 	dl.Flags().StringVar(&flagYTDLPBin, "yt-dlp-bin", cfg.YTDLPBin,
 		"Path to the yt-dlp/youtube-dl binary")
+	dl.Flags().StringVar(&flagBackend, "backend", "",
+		"Download tool: yt-dlp, youtube-dl or http (default: http for direct media links, else --yt-dlp-bin)")
 	dl.Flags().StringVar(&flagProxy, "proxy", "",
 		"HTTP/HTTPS/SOCKS proxy URL passed through to yt-dlp")
 	dl.Flags().StringVar(&flagCookiesFile, "cookies", "",
@@ -87,8 +174,20 @@ func init() {
 		"Socket timeout in seconds passed through to yt-dlp")
 	dl.Flags().BoolVar(&flagForceIPv4, "force-ipv4", false,
 		"Force IPv4 for yt-dlp network requests")
+	dl.Flags().StringVar(&flagLimitRate, "limit-rate", cfg.LimitRate,
+		"Maximum download rate passed through to yt-dlp, e.g. 2M or 500K")
+	dl.Flags().StringVar(&flagWindow, "window", cfg.Window,
+		"Only start new downloads inside this daily window, e.g. 22:00-07:00")
+	dl.Flags().StringVar(&flagExecAfter, "exec-after", "",
+		"Shell command to run after each item, e.g. 'cp {filepath} /mnt/nas/'; the result JSON is on stdin")
+	dl.Flags().StringVar(&flagWebhook, "webhook", "",
+		"URL to POST the result JSON to after each item")
+	dl.Flags().DurationVar(&flagHookTimeout, "hook-timeout", 30*time.Second,
+		"Time limit for each --exec-after command or --webhook request")
 	dl.Flags().BoolVar(&flagResetState, "reset-playlist-state", false,
 		"Discard saved playlist state and start the playlist from the beginning")
+	dl.Flags().BoolVar(&flagOverride, "override", false,
+		"Replace the options saved in a playlist's .vytdl.yaml with this run's, instead of applying them")
 
 	rootCmd.AddCommand(dl)
 }
@@ -106,14 +205,40 @@ func runDownload(cmd *cobra.Command, args []string) error {
 	if logFormat != "json" && logFormat != "csv" {
 		return fmt.Errorf("unsupported log format %q: use json or csv", flagLogFormat)
 	}
+	outputMode := strings.ToLower(strings.TrimSpace(flagOutputMode))
+	switch outputMode {
+	case outputTUI:
+		if flagNoTUI {
+			outputMode = outputPlain
+		}
+	case outputPlain:
+	case outputNDJSON:
+		humanOut = os.Stderr
+	default:
+		return fmt.Errorf("--output-mode must be tui, plain or ndjson")
+	}
+
+	dryRun := strings.ToLower(strings.TrimSpace(flagDryRun))
+	if dryRun != "" && dryRun != "table" && dryRun != "json" {
+		return fmt.Errorf("--dry-run must be table or json")
+	}
 
-	// Resolve output directory
+	// Resolve output directory; a dry run leaves the disk alone.
 	outDir := flagOutputDir
 	if outDir == "" {
 		outDir = "."
 	}
-	if err := os.MkdirAll(outDir, 0o755); err != nil {
-		return fmt.Errorf("cannot create output directory %q: %w", outDir, err)
+	logger := slog.New(slog.DiscardHandler)
+	if dryRun == "" {
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return fmt.Errorf("cannot create output directory %q: %w", outDir, err)
+		}
+		runLog, closeLog, err := openRunLog(outDir, flagLogLevel)
+		if err != nil {
+			return err
+		}
+		defer closeLog()
+		logger = runLog
 	}
 
 	langs := []string{"en", "zh"}
@@ -128,109 +253,446 @@ func runDownload(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	opts := downloader.Options{
-		Format:             flagFormat,
-		Quality:            flagQuality,
-		StartTime:          flagStartTime,
-		EndTime:            flagEndTime,
-		OutputDir:          outDir,
-		SubtitleLangs:      langs,
-		WriteSubtitles:     !flagNoSubs,
-		WriteAutoSubs:      !flagNoAutoSubs,
-		IsPlaylist:         flagPlaylist,
-		LogFormat:          logFormat,
-		RecordFile:         flagRecordFile,
-		MappingFile:        flagMappingFile,
-		YTDLPBin:           flagYTDLPBin,
-		Proxy:              flagProxy,
-		CookiesFile:        flagCookiesFile,
-		CookiesFromBrowser: flagCookiesFrom,
-		UserAgent:          flagUserAgent,
-		ExtractorArgs:      flagExtractor,
-		Retries:            flagRetries,
-		SocketTimeout:      flagTimeout,
-		ForceIPv4:          flagForceIPv4,
-		ResetPlaylistState: flagResetState,
-	}
-
-	mgr := record.NewManager(logFormat, flagRecordFile, flagMappingFile, outDir)
-
-	// Create progress channel
-	progressCh := make(chan downloader.ProgressUpdate, 100)
-
-	// Start TUI or plain logger in a goroutine
-	tuiDone := make(chan error, 1)
-	if !flagNoTUI {
+	if flagFeed {
+		// A feed is a playlist of recent uploads; channel and playlist pages
+		// are swapped for the feed YouTube publishes for them.
+		flagPlaylist = true
+		for i, arg := range args {
+			feedURL, err := ytdl.FeedURL(arg)
+			if err != nil {
+				return fmt.Errorf("--feed: %w", err)
+			}
+			args[i] = feedURL
+		}
+	}
+	if flagPickFormat && flagPlaylist {
+		return fmt.Errorf("--pick-format cannot be combined with --playlist")
+	}
+	if (flagItems != "" || flagSelect) && !flagPlaylist {
+		return fmt.Errorf("--items and --select require --playlist")
+	}
+	if flagItems != "" {
+		if _, err := ytdl.ParseItems(flagItems); err != nil {
+			return fmt.Errorf("--items: %w", err)
+		}
+	}
+
+	switch flagBackend {
+	case ytdl.BackendAuto, ytdl.BackendYTDLP, ytdl.BackendYoutubeDL, ytdl.BackendHTTP:
+	default:
+		return fmt.Errorf("--backend must be yt-dlp, youtube-dl or http")
+	}
+	if flagBackend == ytdl.BackendHTTP && flagPlaylist {
+		return fmt.Errorf("--backend http cannot download playlists")
+	}
+
+	if webhook := strings.TrimSpace(flagWebhook); webhook != "" {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("--webhook must be an http or https URL")
+		}
+	}
+
+	retryKinds, err := ytdl.ParseErrorKinds(flagRetryKinds)
+	if err != nil {
+		return fmt.Errorf("--retry-kinds: %w", err)
+	}
+	weekdayWindows := config.Load().Windows
+	if _, err := ytdl.ParseSchedule(flagWindow, weekdayWindows); err != nil {
+		return fmt.Errorf("--window: %w", err)
+	}
+
+	opts := ytdl.Options{
+		Format:                 flagFormat,
+		Quality:                flagQuality,
+		FormatSelector:         flagFormatID,
+		PreferVideoCodecs:      splitList(flagPreferV),
+		PreferAudioCodecs:      splitList(flagPreferA),
+		AvoidVideoCodecs:       splitList(flagAvoidV),
+		AvoidAudioCodecs:       splitList(flagAvoidA),
+		MaxFPS:                 strings.TrimSpace(flagMaxFPS),
+		NoHDR:                  flagNoHDR,
+		MaxFilesize:            strings.TrimSpace(flagMaxSize),
+		StartTime:              flagStartTime,
+		EndTime:                flagEndTime,
+		OutputDir:              outDir,
+		SubtitleLangs:          langs,
+		WriteSubtitles:         !flagNoSubs,
+		WriteAutoSubs:          !flagNoAutoSubs,
+		IsPlaylist:             flagPlaylist,
+		Items:                  flagItems,
+		IndexPrefix:            flagIndexPrefix,
+		RetryKinds:             retryKinds,
+		MaxRetries:             max(flagMaxRetries, 0),
+		RetryDelay:             flagRetryDelay,
+		MaxAttempts:            max(flagMaxAttempts, 0),
+		RateLimitCooldown:      max(flagCooldown, 0),
+		MaxCooldowns:           max(flagCooldowns, 1),
+		LogFormat:              logFormat,
+		RecordFile:             flagRecordFile,
+		MappingFile:            flagMappingFile,
+		YTDLPBin:               flagYTDLPBin,
+		Backend:                flagBackend,
+		Proxy:                  flagProxy,
+		CookiesFile:            flagCookiesFile,
+		CookiesFromBrowser:     flagCookiesFrom,
+		UserAgent:              flagUserAgent,
+		ExtractorArgs:          flagExtractor,
+		Retries:                flagRetries,
+		SocketTimeout:          flagTimeout,
+		ForceIPv4:              flagForceIPv4,
+		LimitRate:              strings.TrimSpace(flagLimitRate),
+		Window:                 strings.TrimSpace(flagWindow),
+		WeekdayWindows:         weekdayWindows,
+		ExecAfter:              strings.TrimSpace(flagExecAfter),
+		Webhook:                strings.TrimSpace(flagWebhook),
+		HookTimeout:            flagHookTimeout,
+		ResetPlaylistState:     flagResetState,
+		OverridePlaylistConfig: flagOverride,
+		PlaylistConfigKeys:     playlistConfigKeys(cmd),
+		Feed:                   flagFeed,
+		Archive:                strings.TrimSpace(flagArchive),
+	}
+	if flagBackend != ytdl.BackendHTTP {
+		// Let buildArgs skip options an older yt-dlp does not have. Detection
+		// failures are left to the download itself to report.
+		if caps, err := ytdl.New(ytdl.WithOptions(opts)).DetectCapabilities(); err == nil {
+			opts.Capabilities = &caps
+		}
+	}
+	// The TUI owns the terminal while it runs; hold its warnings until after.
+	var warnings warningLog
+	warnings.hold = outputMode == outputTUI
+	client := ytdl.New(ytdl.WithOptions(opts), ytdl.WithLogger(logger), ytdl.WithWarnings(warnings.add))
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	// The picker needs the terminal, so it runs before the progress TUI starts.
+	selectors := make(map[string]string, len(args))
+	if flagPickFormat {
+		for _, url := range args {
+			list, err := client.Formats(ctx, url)
+			if err != nil {
+				return err
+			}
+			selector, err := tui.PickFormat(list)
+			if err != nil {
+				return err
+			}
+			selectors[url] = selector
+		}
+	}
+
+	jobs := make([]ytdl.Job, 0, len(args))
+	for _, url := range args {
+		job := ytdl.Job{URL: url, Playlist: flagPlaylist, FormatSelector: selectors[url]}
+		if flagSelect {
+			keys, err := selectPlaylistEntries(ctx, client, url)
+			if err != nil {
+				return err
+			}
+			job.SelectedEntries = keys
+		}
+		jobs = append(jobs, job)
+	}
+
+	if dryRun != "" {
+		return printPlans(ctx, client, jobs, dryRun == "json")
+	}
+
+	// Every view and log subscribes with its own buffer, so a slow one cannot
+	// hold up the others or the downloads.
+	var sinks sync.WaitGroup
+	if path := strings.TrimSpace(flagEventLog); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("--event-log: %w", err)
+		}
+		defer f.Close()
+		sub := client.Subscribe(256, ytdl.Block)
+		sinks.Add(1)
+		go func() {
+			defer sinks.Done()
+			writeNDJSON(f, sub.C)
+		}()
+	}
+
+	// Start TUI, NDJSON writer or plain logger in a goroutine
+	var tuiErr error
+	switch outputMode {
+	case outputNDJSON:
+		sub := client.Subscribe(256, ytdl.Block)
+		sinks.Add(1)
 		go func() {
-			tuiDone <- tui.Run(progressCh)
+			defer sinks.Done()
+			writeNDJSON(os.Stdout, sub.C)
 		}()
-	} else {
+	case outputTUI:
+		// The TUI only shows the latest numbers; older progress can go.
+		sub := client.Subscribe(0, ytdl.CoalesceProgress)
+		commandCh := make(chan ytdl.Command, 16)
+		go client.Listen(commandCh)
+		sinks.Add(1)
 		go func() {
-			for upd := range progressCh {
-				switch upd.Status {
-				case "done":
-					fmt.Printf("[done]  %s\n", upd.Title)
-				case "error":
-					fmt.Printf("[error] %s: %s\n", upd.Title, upd.Error)
-				case "downloading":
-					fmt.Printf("[%.1f%%] %s  %s  ETA %s\n",
-						upd.Percent, upd.Title, upd.Speed, upd.ETA)
-				default:
-					fmt.Printf("[%s] %s\n", upd.Status, upd.Title)
-				}
-			}
-			tuiDone <- nil
+			defer sinks.Done()
+			tuiErr = tui.Run(sub.C, commandCh)
+			// Leaving the TUI early cancels the run; unsubscribe so the
+			// downloads can wind down without waiting for it.
+			cancel()
+			sub.Unsubscribe()
+			close(commandCh)
+		}()
+	default:
+		sub := client.Subscribe(0, ytdl.Block)
+		sinks.Add(1)
+		go func() {
+			defer sinks.Done()
+			printPlain(sub.C)
 		}()
 	}
 
-	dl := downloader.New(opts, progressCh)
+	logger.Info("run started", "urls", len(args), "output", outDir, "playlist", flagPlaylist)
+	allResults, _ := client.Run(ctx, jobs...)
+	logger.Info("run finished", "results", len(allResults))
+
+	// Signal the views that all downloads are complete
+	client.Close()
+	sinks.Wait()
+	if tuiErr != nil {
+		fmt.Fprintf(os.Stderr, "TUI error: %v\n", tuiErr)
+	}
+	warnings.flush()
 
-	var allResults []downloader.DownloadResult
+	return finishRun(client, allResults, flagPlaylist, outputMode)
+}
 
-	for _, url := range args {
-		if flagPlaylist {
-			results := dl.DownloadPlaylist(url)
-			allResults = append(allResults, results...)
-		} else {
-			result := dl.DownloadSingle(url)
-			allResults = append(allResults, result)
+// printPlain prints one line per update for --no-tui runs.
+func printPlain(updates <-chan ytdl.Progress) {
+	waiting := map[string]time.Time{}
+	for upd := range updates {
+		switch upd.Status {
+		case "queued":
+			// The whole queue is announced up front; stay quiet until it starts.
+		case "retrying":
+			fmt.Printf("[retry] %s: %s; attempt %d at %s\n",
+				upd.Title, upd.Error, upd.Attempt, upd.ResumeAt.Format("15:04:05"))
+		case "cooldown":
+			fmt.Printf("[cooldown] %s: %s; queue paused until %s\n",
+				upd.Title, upd.Error, upd.ResumeAt.Format("15:04:05"))
+		case "waiting":
+			// Re-announced every minute; only print when the opening time moves.
+			if !waiting[upd.Key].Equal(upd.ResumeAt) {
+				waiting[upd.Key] = upd.ResumeAt
+				fmt.Printf("[waiting] %s: outside download window, starts at %s\n",
+					upd.Title, upd.ResumeAt.Format("Mon 15:04"))
+			}
+		case "hook_failed":
+			fmt.Printf("[hook]  %s: %s\n", upd.Title, upd.HookError)
+		case "done", "error":
+			if upd.Status == "done" {
+				fmt.Printf("[done]  %s\n", upd.Title)
+			} else if upd.ErrorKind != "" {
+				fmt.Printf("[error] %s: %s (%s)\n", upd.Title, upd.Error, upd.ErrorKind)
+			} else {
+				fmt.Printf("[error] %s: %s\n", upd.Title, upd.Error)
+			}
+		case "downloading":
+			fmt.Printf("[%.1f%%] %s  %s  ETA %s\n",
+				upd.Percent, upd.Title, upd.Speed, upd.ETA)
+		default:
+			fmt.Printf("[%s] %s\n", upd.Status, upd.Title)
+		}
+	}
+}
+
+// selectPlaylistEntries lists a playlist, annotates it with the saved state and
+// lets the user tick the entries to download.
+func selectPlaylistEntries(ctx context.Context, client *ytdl.Client, url string) ([]string, error) {
+	info, err := client.Playlist(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list playlist %s: %w", url, err)
+	}
+	if len(info.Entries) == 0 {
+		return nil, fmt.Errorf("playlist %s has no entries", url)
+	}
+
+	statuses := map[string]string{}
+	if state, err := ytdl.LoadPlaylistState(client.PlaylistDir(info.Title, url)); err == nil {
+		for _, entry := range state.Entries {
+			statuses[entry.Key()] = entry.Status
+		}
+	}
+
+	opts := client.Options()
+	var items ytdl.ItemSet
+	if opts.Items != "" {
+		items, _ = ytdl.ParseItems(opts.Items)
+	}
+	rows := make([]tui.SelectRow, 0, len(info.Entries))
+	for i, entry := range info.Entries {
+		status := statuses[entry.Key()]
+		selected := status != ytdl.StatusSucceeded && status != ytdl.StatusSkipped &&
+			status != ytdl.StatusUnavailable
+		if opts.Items != "" {
+			selected = items.Contains(i + 1)
 		}
+		rows = append(rows, tui.SelectRow{
+			Key:      entry.Key(),
+			Title:    entry.Title,
+			Duration: entry.Duration,
+			Status:   status,
+			Selected: selected,
+		})
 	}
 
-	// Signal TUI that all downloads are complete
-	close(progressCh)
-	if err := <-tuiDone; err != nil {
-		fmt.Fprintf(os.Stderr, "TUI error: %v\n", err)
+	keys, err := tui.SelectEntries(info.Title, rows)
+	if err != nil {
+		return nil, err
 	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no entries selected for %s", url)
+	}
+	return keys, nil
+}
 
-	// Write records
+// warningLog prints client warnings to stderr, or holds them until flush.
+type warningLog struct {
+	hold bool
+
+	mu   sync.Mutex
+	held []string
+}
+
+func (l *warningLog) add(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.hold {
+		l.held = append(l.held, msg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+}
+
+func (l *warningLog) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, msg := range l.held {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", msg)
+	}
+	l.held, l.hold = nil, false
+}
+
+// finishRun writes the record and mapping files and prints the run summary.
+// In ndjson mode the summary is also written to stdout as a "summary" event.
+func finishRun(client *ytdl.Client, allResults []ytdl.Result, playlistRun bool, outputMode string) error {
+	// Cancelled items are neither successes nor failures.
+	ok, fail, skipped, hookFail := 0, 0, 0, 0
 	for _, r := range allResults {
-		mgr.Add(r)
+		if r.HookError != "" {
+			hookFail++
+		}
+		switch {
+		case r.Skipped:
+			skipped++
+		case r.Success:
+			ok++
+		default:
+			fail++
+		}
 	}
-	if err := mgr.Flush(); err != nil {
+	files, err := client.SaveRecords(allResults)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "warning: failed to write records: %v\n", err)
 	} else {
-		fmt.Printf("\nDownload log   : %s\n", mgr.RecordPath())
-		fmt.Printf("Subtitle map   : %s\n", mgr.MappingPath())
+		fmt.Fprintf(humanOut, "\nDownload log   : %s\n", files.Record)
+		fmt.Fprintf(humanOut, "Subtitle map   : %s\n", files.Mapping)
+	}
+	if outputMode == outputNDJSON {
+		printNDJSONSummary(os.Stdout, allResults, files.Record, ok, fail, skipped, hookFail)
 	}
 
-	if flagPlaylist && len(allResults) == 0 {
-		fmt.Println("\nNo pending playlist items. Existing playlist state already marks all items as completed.")
+	if playlistRun && len(allResults) == 0 {
+		fmt.Fprintln(humanOut, "\nNo pending playlist items. Existing playlist state already marks all items as completed.")
 		return nil
 	}
 
 	// Summary
-	ok, fail := 0, 0
-	for _, r := range allResults {
-		if r.Success {
-			ok++
-		} else {
-			fail++
-		}
+	if skipped > 0 {
+		fmt.Fprintf(humanOut, "\nCompleted: %d succeeded, %d failed, %d cancelled.\n", ok, fail, skipped)
+	} else {
+		fmt.Fprintf(humanOut, "\nCompleted: %d succeeded, %d failed.\n", ok, fail)
+	}
+	if hookFail > 0 {
+		// Hooks do not fail the run: the files are there either way.
+		fmt.Fprintf(os.Stderr, "warning: %d post-download hook(s) failed — see hook_error in %s\n", hookFail, files.Record)
 	}
-	fmt.Printf("\nCompleted: %d succeeded, %d failed.\n", ok, fail)
 	if fail > 0 {
-		return fmt.Errorf("%d download(s) failed — see %s for details", fail, mgr.RecordPath())
+		return fmt.Errorf("%d download(s) failed — see %s for details", fail, files.Record)
 	}
 	return nil
 }
+
+// openRunLog opens <outDir>/.logs/run.log for the structured run log. Level
+// "off" returns a logger that discards everything.
+func openRunLog(outDir, level string) (*slog.Logger, func(), error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "off" {
+		return slog.New(slog.DiscardHandler), func() {}, nil
+	}
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, nil, fmt.Errorf("--log-level must be debug, info, warn, error or off")
+	}
+	dir := filepath.Join(outDir, ytdl.LogDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("cannot create log directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "run.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot open run log: %w", err)
+	}
+	return slog.New(slog.NewTextHandler(f, &slog.HandlerOptions{Level: lvl})), func() { f.Close() }, nil
+}
+
+// playlistConfigFlags maps the flags saved in a playlist's .vytdl.yaml to
+// their key there.
+var playlistConfigFlags = map[string]string{
+	"format":        "format",
+	"quality":       "quality",
+	"format-id":     "format_id",
+	"prefer-vcodec": "prefer_vcodec",
+	"prefer-acodec": "prefer_acodec",
+	"avoid-vcodec":  "avoid_vcodec",
+	"avoid-acodec":  "avoid_acodec",
+	"max-fps":       "max_fps",
+	"no-hdr":        "no_hdr",
+	"max-filesize":  "max_filesize",
+	"start":         "start",
+	"end":           "end",
+	"no-subs":       "subtitles",
+	"no-auto-subs":  "auto_subtitles",
+	"sub-langs":     "sub_langs",
+	"index-prefix":  "index_prefix",
+}
+
+// playlistConfigKeys lists the saved playlist options given on the command
+// line, so a resume only compares and overrides those.
+func playlistConfigKeys(cmd *cobra.Command) []string {
+	keys := []string{}
+	for flag, key := range playlistConfigFlags {
+		if cmd.Flags().Changed(flag) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPlaylistConfigKeysAreTheFlagsGiven(t *testing.T) {
+	for flag := range playlistConfigFlags {
+		if downloadCmd.Flags().Lookup(flag) == nil {
+			t.Errorf("no --%s flag", flag)
+		}
+	}
+	if keys := playlistConfigKeys(downloadCmd); keys == nil || len(keys) != 0 {
+		t.Fatalf("keys without flags = %#v", keys)
+	}
+	t.Cleanup(func() {
+		for _, flag := range []string{"quality", "no-subs"} {
+			downloadCmd.Flags().Lookup(flag).Changed = false
+		}
+		flagQuality, flagNoSubs = "", false
+	})
+	if err := downloadCmd.Flags().Parse([]string{"--quality", "720", "--no-subs"}); err != nil {
+		t.Fatal(err)
+	}
+	keys := playlistConfigKeys(downloadCmd)
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"quality", "subtitles"}) {
+		t.Fatalf("keys = %v", keys)
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+// printPlans shows what each job would do without downloading anything.
+func printPlans(ctx context.Context, client *ytdl.Client, jobs []ytdl.Job, asJSON bool) error {
+	plans := make([]ytdl.Plan, 0, len(jobs))
+	for _, job := range jobs {
+		plan, err := client.Plan(ctx, job)
+		if err != nil {
+			return fmt.Errorf("%s: %w", job.URL, err)
+		}
+		plans = append(plans, plan)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plans)
+	}
+
+	for i, plan := range plans {
+		if i > 0 {
+			fmt.Println()
+		}
+		if plan.Playlist {
+			fmt.Printf("%s [%s]\n", orDash(plan.Title), plan.URL)
+			fmt.Printf("state: %s\n", plan.StateFile)
+			for _, w := range plan.Warnings {
+				fmt.Printf("warning: %s\n", w)
+			}
+			fmt.Println()
+		}
+		counts := map[string]int{}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tACTION\tTITLE\tREASON\tDIR\tCOMMAND")
+		for _, e := range plan.Entries {
+			counts[e.Action]++
+			index := "-"
+			if e.Index > 0 {
+				index = fmt.Sprint(e.Index)
+			}
+			title := e.Title
+			if title == "" {
+				title = e.URL
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				index, e.Action, title, orDash(e.Reason), e.Dir, orDash(quoteCommand(e.Command)))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d to download, %d to retry, %d skipped.\n",
+			counts[ytdl.ActionDownload], counts[ytdl.ActionRetry], counts[ytdl.ActionSkip])
+	}
+	return nil
+}
+
+// quoteCommand joins args for display, quoting those the shell would split.
+func quoteCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t'\"$*?[]()&;|<>\\`") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/innate/yt-dl/internal/podcast"
+)
+
+var (
+	feedOutput      string
+	feedBaseURL     string
+	feedTitle       string
+	feedDescription string
+	feedAuthor      string
+	feedImage       string
+	feedLanguage    string
+	feedExplicit    bool
+	feedServe       string
+)
+
+func init() {
+	f := feedCmd
+	f.Flags().StringVarP(&feedOutput, "output", "o", "",
+		"Feed file to write (default: <dir>/feed.xml; - for stdout)")
+	f.Flags().StringVar(&feedBaseURL, "base-url", "",
+		"URL the directory is published under, e.g. http://nas.local:8080 (media links are <base-url>/media/<file>)")
+	f.Flags().StringVar(&feedTitle, "title", "", "Podcast title (default: playlist title)")
+	f.Flags().StringVar(&feedDescription, "description", "", "Podcast description")
+	f.Flags().StringVar(&feedAuthor, "author", "", "itunes:author of the podcast")
+	f.Flags().StringVar(&feedImage, "image", "", "Artwork URL (default: the first episode thumbnail)")
+	f.Flags().StringVar(&feedLanguage, "language", "", "Language code, e.g. en")
+	f.Flags().BoolVar(&feedExplicit, "explicit", false, "Mark the podcast as explicit")
+	f.Flags().StringVar(&feedServe, "serve", "",
+		"Serve the feed and media over HTTP at this address, e.g. :8080, instead of writing a file")
+
+	rootCmd.AddCommand(f)
+}
+
+var feedCmd = &cobra.Command{
+	Use:   "feed [flags] <playlist-dir>",
+	Short: "Publish a downloaded playlist as a podcast feed",
+	Long: `Generate a podcast RSS 2.0 feed with iTunes tags for a playlist directory,
+from its .playlist_state.json and the metadata saved for each download.
+
+Write it to a file next to the media and publish the directory with any web
+server (--base-url), or let --serve publish the feed at /feed.xml and the
+media at /media/ with range requests.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFeed,
+}
+
+func runFeed(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	opts := podcast.Options{
+		BaseURL:     feedBaseURL,
+		Title:       feedTitle,
+		Description: feedDescription,
+		Author:      feedAuthor,
+		Image:       feedImage,
+		Language:    feedLanguage,
+		Explicit:    feedExplicit,
+	}
+	if feedServe != "" {
+		return serveFeed(cmd.Context(), dir, feedServe, opts)
+	}
+
+	data, err := podcast.Build(dir, opts)
+	if err != nil {
+		return err
+	}
+	switch out := feedOutput; out {
+	case "-":
+		_, err = os.Stdout.Write(data)
+		return err
+	case "":
+		feedOutput = filepath.Join(dir, "feed.xml")
+	}
+	if err := os.WriteFile(feedOutput, data, 0o644); err != nil {
+		return fmt.Errorf("cannot write feed: %w", err)
+	}
+	fmt.Printf("Feed written to %s\n", feedOutput)
+	return nil
+}
+
+// serveFeed publishes the feed until interrupted.
+func serveFeed(ctx context.Context, dir, addr string, opts podcast.Options) error {
+	if _, err := podcast.Build(dir, podcast.Options{BaseURL: "http://localhost"}); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: podcast.Handler(dir, opts), ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("Serving %s at http://%s/feed.xml (Ctrl+C to stop)\n", dir, displayAddr(ln.Addr()))
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// displayAddr turns a wildcard listen address into one a browser can open.
+func displayAddr(addr net.Addr) string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok || !tcp.IP.IsUnspecified() {
+		return addr.String()
+	}
+	return fmt.Sprintf("localhost:%d", tcp.Port)
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/innate/yt-dl/internal/config"
+	"github.com/innate/yt-dl/internal/tui"
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+var (
+	flagFormatsJSON    bool
+	flagFormatsPick    bool
+	flagFormatsBin     string
+	flagFormatsProxy   string
+	flagFormatsCookies string
+	flagFormatsFrom    string
+)
+
+func init() {
+	fc := formatsCmd
+	cfg := config.Load()
+
+	fc.Flags().BoolVar(&flagFormatsJSON, "json", false,
+		"Print the format list as JSON instead of a table")
+	fc.Flags().BoolVar(&flagFormatsPick, "pick", false,
+		"Open an interactive picker and print the chosen format selector")
+	fc.Flags().StringVar(&flagFormatsBin, "yt-dlp-bin", cfg.YTDLPBin,
+		"Path to the yt-dlp/youtube-dl binary")
+	fc.Flags().StringVar(&flagFormatsProxy, "proxy", "",
+		"HTTP/HTTPS/SOCKS proxy URL passed through to yt-dlp")
+	fc.Flags().StringVar(&flagFormatsCookies, "cookies", "",
+		"Netscape-format cookies file passed through to yt-dlp")
+	fc.Flags().StringVar(&flagFormatsFrom, "cookies-from-browser", "",
+		"Browser profile selector passed through to yt-dlp")
+
+	rootCmd.AddCommand(fc)
+}
+
+var formatsCmd = &cobra.Command{
+	Use:   "formats [flags] <url>",
+	Short: "List the video/audio formats available for a video",
+	Long: `List the formats yt-dlp reports for a single video.
+
+Use the ID column with "download --format-id", e.g. --format-id 137+140,
+or pass --pick to choose a video and audio stream interactively.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFormats,
+}
+
+func runFormats(cmd *cobra.Command, args []string) error {
+	client := ytdl.New(
+		ytdl.WithBinary(flagFormatsBin),
+		ytdl.WithProxy(flagFormatsProxy),
+		ytdl.WithCookiesFile(flagFormatsCookies),
+		ytdl.WithCookiesFromBrowser(flagFormatsFrom),
+	)
+
+	list, err := client.Formats(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	if flagFormatsPick {
+		selector, err := tui.PickFormat(list)
+		if err != nil {
+			return err
+		}
+		fmt.Println(selector)
+		return nil
+	}
+
+	if flagFormatsJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+
+	fmt.Printf("%s [%s]\n\n", list.Title, list.VideoID)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEXT\tKIND\tRESOLUTION\tFPS\tVCODEC\tACODEC\tBITRATE\tSIZE\tNOTE")
+	for _, f := range list.Formats {
+		fps := "-"
+		if f.FPS > 0 {
+			fps = fmt.Sprintf("%g", f.FPS)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			f.ID, f.Ext, f.Kind(), orDash(f.Resolution()), fps,
+			orDash(f.VCodec), orDash(f.ACodec),
+			tui.HumanBitrate(f.TBR), tui.HumanBytes(f.Size()), f.Note)
+	}
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+// Output modes accepted by --output-mode.
+const (
+	outputTUI    = "tui"
+	outputPlain  = "plain"
+	outputNDJSON = "ndjson"
+)
+
+// humanOut receives the run summary and other text meant for people. It is
+// stderr in ndjson mode so stdout carries nothing but events.
+var humanOut io.Writer = os.Stdout
+
+// ndjsonVersion is bumped when a field changes meaning or disappears; new
+// fields may be added without a bump.
+const ndjsonVersion = 1
+
+// ndjsonEvent is one line of --output-mode ndjson.
+type ndjsonEvent struct {
+	V    int       `json:"v"`
+	Type string    `json:"type"` // start, progress, stage, done, error, skipped, summary
+	Time time.Time `json:"time"`
+
+	Key     string `json:"key,omitempty"`
+	VideoID string `json:"video_id,omitempty"`
+	Title   string `json:"title,omitempty"`
+	// Stage is the downloader status the event came from, e.g. "merging" or
+	// "retrying" on stage events.
+	Stage      string     `json:"stage,omitempty"`
+	Percent    float64    `json:"percent,omitempty"`
+	Speed      string     `json:"speed,omitempty"`
+	ETA        string     `json:"eta,omitempty"`
+	TotalBytes int64      `json:"total_bytes,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ErrorKind  string     `json:"error_kind,omitempty"`
+	HookError  string     `json:"hook_error,omitempty"`
+	OutputDir  string     `json:"output_dir,omitempty"`
+	Filename   string     `json:"filename,omitempty"`
+	Subtitles  []string   `json:"subtitles,omitempty"`
+	ResumeAt   *time.Time `json:"resume_at,omitempty"`
+	Attempt    int        `json:"attempt,omitempty"`
+
+	// Summary only.
+	Succeeded  *int          `json:"succeeded,omitempty"`
+	Failed     *int          `json:"failed,omitempty"`
+	Skipped    *int          `json:"skipped,omitempty"`
+	HookFailed *int          `json:"hook_failed,omitempty"`
+	RecordFile string        `json:"record_file,omitempty"`
+	Results    []ytdl.Record `json:"results,omitempty"`
+}
+
+// eventType maps a downloader status onto the ndjson event types. Every item
+// ends with exactly one done, error or skipped event; a failed hook follows
+// as a hook_failed stage.
+func eventType(status string) string {
+	switch status {
+	case "starting":
+		return "start"
+	case "downloading":
+		return "progress"
+	case "done", "error", "skipped":
+		return status
+	}
+	return "stage"
+}
+
+func eventFromUpdate(upd ytdl.Progress) ndjsonEvent {
+	ev := ndjsonEvent{
+		V:          ndjsonVersion,
+		Type:       eventType(upd.Status),
+		Time:       time.Now().UTC(),
+		Key:        upd.Key,
+		VideoID:    upd.VideoID,
+		Title:      upd.Title,
+		Stage:      upd.Status,
+		Percent:    upd.Percent,
+		Speed:      upd.Speed,
+		ETA:        upd.ETA,
+		TotalBytes: upd.TotalBytes,
+		Error:      upd.Error,
+		ErrorKind:  string(upd.ErrorKind),
+		HookError:  upd.HookError,
+		OutputDir:  upd.OutputDir,
+		Filename:   upd.Filename,
+		Subtitles:  upd.Subtitles,
+		Attempt:    upd.Attempt,
+	}
+	if !upd.ResumeAt.IsZero() {
+		at := upd.ResumeAt.UTC()
+		ev.ResumeAt = &at
+	}
+	return ev
+}
+
+// writeNDJSON writes every update to w until updates closes.
+func writeNDJSON(w io.Writer, updates <-chan ytdl.Progress) {
+	enc := json.NewEncoder(w)
+	for upd := range updates {
+		_ = enc.Encode(eventFromUpdate(upd))
+	}
+}
+
+// printNDJSONSummary writes the closing summary event to w. Results holds
+// the same records as the download log, so cancelled items are left out.
+func printNDJSONSummary(w io.Writer, results []ytdl.Result, recordFile string, ok, fail, skipped, hookFail int) {
+	ev := ndjsonEvent{
+		V:          ndjsonVersion,
+		Type:       "summary",
+		Time:       time.Now().UTC(),
+		Succeeded:  &ok,
+		Failed:     &fail,
+		Skipped:    &skipped,
+		HookFailed: &hookFail,
+		RecordFile: recordFile,
+		Results:    make([]ytdl.Record, 0, len(results)),
+	}
+	for _, r := range results {
+		if r.Skipped {
+			continue
+		}
+		ev.Results = append(ev.Results, ytdl.NewRecord(r))
+	}
+	_ = json.NewEncoder(w).Encode(ev)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+func TestEventTypeCoversEveryDownloaderStatus(t *testing.T) {
+	want := map[string]string{
+		"starting":    "start",
+		"downloading": "progress",
+		"queued":      "stage",
+		"waiting":     "stage",
+		"merging":     "stage",
+		"retrying":    "stage",
+		"cooldown":    "stage",
+		"paused":      "stage",
+		"hook_failed": "stage",
+		"done":        "done",
+		"error":       "error",
+		"skipped":     "skipped",
+	}
+	for status, typ := range want {
+		if got := eventType(status); got != typ {
+			t.Errorf("eventType(%q) = %q, want %q", status, got, typ)
+		}
+	}
+}
+
+func TestNDJSONItemEvent(t *testing.T) {
+	resumeAt := time.Date(2025, 3, 2, 21, 0, 0, 0, time.FixedZone("CET", 3600))
+	updates := make(chan ytdl.Progress, 2)
+	updates <- ytdl.Progress{Key: "abc", VideoID: "abc", Title: "Talk", Status: "retrying",
+		Error: "network error", ErrorKind: ytdl.ErrorNetwork, Attempt: 2, ResumeAt: resumeAt}
+	updates <- ytdl.Progress{Key: "abc", VideoID: "abc", Title: "Talk", Status: "hook_failed", HookError: "webhook: 500"}
+	close(updates)
+
+	var out bytes.Buffer
+	writeNDJSON(&out, updates)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines: %s", len(lines), out.String())
+	}
+
+	var retry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &retry); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{
+		"v": float64(ndjsonVersion), "type": "stage", "stage": "retrying", "key": "abc", "video_id": "abc",
+		"title": "Talk", "error": "network error", "error_kind": "network", "attempt": float64(2),
+		"resume_at": "2025-03-02T20:00:00Z",
+	} {
+		if retry[key] != want {
+			t.Errorf("%s = %v, want %v", key, retry[key], want)
+		}
+	}
+	if _, ok := retry["succeeded"]; ok {
+		t.Errorf("item event carries summary fields: %s", lines[0])
+	}
+
+	var hook ndjsonEvent
+	if err := json.Unmarshal([]byte(lines[1]), &hook); err != nil {
+		t.Fatal(err)
+	}
+	if hook.Type != "stage" || hook.Stage != "hook_failed" || hook.HookError != "webhook: 500" {
+		t.Fatalf("hook event = %+v, want a hook_failed stage rather than a second done", hook)
+	}
+}
+
+func TestNDJSONSummaryShape(t *testing.T) {
+	results := []ytdl.Result{
+		{VideoID: "a", Title: "A", URL: "https://example.com/a", Success: true},
+		{VideoID: "b", Title: "B", URL: "https://example.com/b", Error: "network error", ErrorKind: ytdl.ErrorNetwork},
+		{VideoID: "c", Title: "C", URL: "https://example.com/c", Skipped: true},
+	}
+	var out bytes.Buffer
+	printNDJSONSummary(&out, results, "downloads/download_record.json", 1, 1, 1, 0)
+
+	var summary map[string]any
+	if err := json.Unmarshal(out.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for key := range summary {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	want := []string{"failed", "hook_failed", "record_file", "results", "skipped", "succeeded", "time", "type", "v"}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("summary keys = %v, want %v", keys, want)
+	}
+	if summary["type"] != "summary" || summary["hook_failed"] != float64(0) {
+		t.Fatalf("summary = %v", summary)
+	}
+
+	records, _ := summary["results"].([]any)
+	if len(records) != 2 {
+		t.Fatalf("results = %v, want the two records without the cancelled item", records)
+	}
+	first, _ := records[0].(map[string]any)
+	if first["video_id"] != "a" || first["success"] != true {
+		t.Fatalf("first record = %v", first)
+	}
+	if second, _ := records[1].(map[string]any); second["error_kind"] != "network" {
+		t.Fatalf("second record = %v", second)
+	}
+}
@@ -22,6 +22,7 @@ Features:
   • Download log (JSON or CSV) tracking success / failure
   • Subtitle-video mapping file (JSON or CSV)
   • Interactive TUI with live progress bars
+  • Guided download form when run without arguments
 `,
 }
 
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/innate/yt-dl/internal/config"
+	"github.com/innate/yt-dl/internal/server"
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+var (
+	serveAddr       string
+	serveOutputDir  string
+	serveYTDLPBin   string
+	serveFormat     string
+	serveQuality    string
+	serveSubLangs   string
+	serveNoSubs     bool
+	serveLimitRate  string
+	serveLogFormat  string
+	serveMaxRetries int
+	serveLogLevel   string
+	serveAllowHosts string
+)
+
+func init() {
+	s := serveCmd
+	cfg := config.Load()
+
+	s.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8080",
+		"Address to listen on; use :8080 to allow other machines")
+	s.Flags().StringVar(&serveAllowHosts, "allow-host", "",
+		"Comma-separated extra host names the UI may be opened under, e.g. nas.example.com")
+	s.Flags().StringVarP(&serveOutputDir, "output", "o", ".",
+		"Output directory; everything in it can be browsed and downloaded")
+	s.Flags().StringVar(&serveYTDLPBin, "yt-dlp-bin", cfg.YTDLPBin,
+		"Path to the yt-dlp/youtube-dl binary")
+	s.Flags().StringVarP(&serveFormat, "format", "f", "mp4",
+		"Default output container format")
+	s.Flags().StringVarP(&serveQuality, "quality", "q", "",
+		"Default video quality: 720, 1080, … (empty = best)")
+	s.Flags().StringVar(&serveSubLangs, "sub-langs", "en,zh",
+		"Default comma-separated subtitle languages")
+	s.Flags().BoolVar(&serveNoSubs, "no-subs", false,
+		"Disable subtitle download by default")
+	s.Flags().StringVar(&serveLimitRate, "limit-rate", cfg.LimitRate,
+		"Maximum download rate passed through to yt-dlp, e.g. 2M")
+	s.Flags().StringVar(&serveLogFormat, "log-format", "json",
+		"Record / mapping file format: json or csv")
+	s.Flags().IntVar(&serveMaxRetries, "max-retries", 2,
+		"Re-run yt-dlp this many times after a network, rate-limit or unknown failure")
+	s.Flags().StringVar(&serveLogLevel, "log-level", "info",
+		"Run log detail in <output>/.logs/run.log: debug, info, warn, error or off")
+
+	rootCmd.AddCommand(s)
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [flags]",
+	Short: "Run downloads from a web UI and JSON API",
+	Long: `Serve a web UI and a JSON API for downloading into the output directory.
+
+Add URLs with the same settings an inbox sidecar accepts, follow progress
+live, browse the state of every playlist in the output directory, retry failed
+entries, and download finished videos, subtitles and record files from the
+browser. Jobs run one at a time in the order they were added.
+
+There is no authentication: the default address only accepts connections from
+this machine. Requests must name this machine in their Host header, so other
+web pages cannot reach the API through DNS rebinding; add the names of a
+reverse proxy with --allow-host.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	logFormat := strings.ToLower(strings.TrimSpace(serveLogFormat))
+	if logFormat != "json" && logFormat != "csv" {
+		return fmt.Errorf("unsupported log format %q: use json or csv", serveLogFormat)
+	}
+	outDir := serveOutputDir
+	if outDir == "" {
+		outDir = "."
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("cannot create output directory %q: %w", outDir, err)
+	}
+	logger, closeLog, err := openRunLog(outDir, serveLogLevel)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	langs := splitList(serveSubLangs)
+	if len(langs) == 0 {
+		langs = []string{"en", "zh"}
+	}
+	defaults := ytdl.DefaultOptions()
+	defaults.Format = serveFormat
+	defaults.Quality = serveQuality
+	defaults.OutputDir = outDir
+	defaults.SubtitleLangs = langs
+	defaults.WriteSubtitles = !serveNoSubs
+	defaults.WriteAutoSubs = !serveNoSubs
+	defaults.LimitRate = strings.TrimSpace(serveLimitRate)
+	defaults.LogFormat = logFormat
+	defaults.MaxRetries = max(serveMaxRetries, 0)
+	defaults.YTDLPBin = serveYTDLPBin
+	if caps, err := ytdl.New(ytdl.WithOptions(defaults)).DetectCapabilities(); err == nil {
+		defaults.Capabilities = &caps
+	}
+
+	ln, err := net.Listen("tcp", serveAddr)
+	if err != nil {
+		return err
+	}
+	srv, err := server.New(server.Config{Defaults: defaults, Logger: logger,
+		AllowedHosts: allowedHosts(ln.Addr(), splitList(serveAllowHosts))})
+	if err != nil {
+		ln.Close()
+		return err
+	}
+
+	// Interrupting stops the running download, keeping its partial file.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	var worker sync.WaitGroup
+	worker.Add(1)
+	go func() {
+		defer worker.Done()
+		srv.Run(ctx)
+	}()
+	httpSrv := &http.Server{Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		// Event streams end when the worker stops; wait for that first.
+		worker.Wait()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpSrv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("Serving %s at http://%s/ (Ctrl+C to stop)\n", outDir, displayAddr(ln.Addr()))
+	logger.Info("serve started", "addr", ln.Addr().String(), "output", outDir)
+	if err := httpSrv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		stop()
+		worker.Wait()
+		return err
+	}
+	worker.Wait()
+	return nil
+}
+
+// allowedHosts lists the host names the server answers to: this machine's
+// loopback names, the listen address, and for a wildcard address the
+// machine's host name and interface addresses, plus extra.
+func allowedHosts(addr net.Addr, extra []string) []string {
+	hosts := append([]string{"localhost", "127.0.0.1", "::1"}, extra...)
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return hosts
+	}
+	if !tcp.IP.IsUnspecified() {
+		return append(hosts, tcp.IP.String())
+	}
+	if name, err := os.Hostname(); err == nil {
+		hosts = append(hosts, name)
+		if !strings.Contains(name, ".") {
+			hosts = append(hosts, name+".local")
+		}
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				hosts = append(hosts, ipnet.IP.String())
+			}
+		}
+	}
+	return hosts
+}
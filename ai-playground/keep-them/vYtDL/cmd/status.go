@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+var (
+	flagStatusJSON    bool
+	flagStatusChanges bool
+)
+
+func init() {
+	sc := statusCmd
+	sc.Flags().BoolVar(&flagStatusJSON, "json", false,
+		"Print the saved playlist state as JSON instead of a table")
+	sc.Flags().BoolVar(&flagStatusChanges, "changes", false,
+		"Only list entries removed from the playlist, listed as private or deleted, renamed or failed")
+
+	rootCmd.AddCommand(sc)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status [flags] <playlist-dir> [dir…]",
+	Short: "Show what a downloaded playlist holds and what changed upstream",
+	Long: `Show the saved state of playlist directories: every entry with its
+playlist position and download status, and notes for videos that were removed
+from the playlist, are listed as private or deleted, or were renamed since
+they were first seen.
+
+The state is updated whenever the playlist is downloaded or resumed; status
+itself does not contact the site.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runStatus,
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	states := make([]ytdl.PlaylistState, 0, len(args))
+	for _, dir := range args {
+		state, err := ytdl.LoadPlaylistState(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+		states = append(states, state)
+	}
+
+	if flagStatusJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(states)
+	}
+
+	for i, state := range states {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := printStatus(state, args[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printStatus(state ytdl.PlaylistState, dir string) error {
+	fmt.Printf("%s [%s]\n", orDash(state.PlaylistTitle), state.PlaylistURL)
+	fmt.Printf("dir: %s, updated %s\n\n", dir, state.UpdatedAt.Local().Format("2006-01-02 15:04"))
+
+	counts := map[string]int{}
+	var removed, gone, renamed int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tSTATUS\tTITLE\tNOTE")
+	for _, e := range state.Entries {
+		counts[e.Status]++
+		if e.RemovedUpstream {
+			removed++
+		}
+		if e.ListedAs != "" {
+			gone++
+		}
+		if len(e.TitleHistory) > 0 {
+			renamed++
+		}
+		notes := entryNotes(e)
+		if flagStatusChanges && len(notes) == 0 {
+			continue
+		}
+		index := "-"
+		if e.PlaylistIndex > 0 {
+			index = fmt.Sprint(e.PlaylistIndex)
+		}
+		title := e.Title
+		if title == "" {
+			title = e.URL
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", index, e.Status, title, orDash(strings.Join(notes, "; ")))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d entries: %d succeeded, %d failed, %d unavailable, %d pending, %d skipped.\n",
+		len(state.Entries), counts[ytdl.StatusSucceeded], counts[ytdl.StatusFailed],
+		counts[ytdl.StatusUnavailable], counts[ytdl.StatusPending]+counts[ytdl.StatusRunning],
+		counts[ytdl.StatusSkipped])
+	if removed+gone+renamed > 0 {
+		fmt.Printf("Upstream: %d removed from the playlist, %d private or deleted, %d renamed.\n",
+			removed, gone, renamed)
+	}
+	return nil
+}
+
+// entryNotes explains what happened to an entry upstream, and why it failed.
+func entryNotes(e ytdl.PlaylistEntryState) []string {
+	var notes []string
+	if e.RemovedUpstream {
+		note := "removed from the playlist"
+		if !e.LastSeen.IsZero() {
+			note += ", last seen " + e.LastSeen.Local().Format("2006-01-02")
+		}
+		notes = append(notes, note)
+	}
+	if e.ListedAs != "" {
+		notes = append(notes, "listed as "+e.ListedAs)
+	}
+	if n := len(e.TitleHistory); n > 0 {
+		notes = append(notes, fmt.Sprintf("renamed from %q", e.TitleHistory[n-1].From))
+	}
+	switch e.Status {
+	case ytdl.StatusFailed, ytdl.StatusUnavailable:
+		if e.ErrorKind != "" {
+			notes = append(notes, e.ErrorKind)
+		} else if e.Error != "" {
+			notes = append(notes, e.Error)
+		}
+	}
+	return notes
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/innate/yt-dl/internal/config"
+	"github.com/innate/yt-dl/internal/inbox"
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+var (
+	watchOutputDir  string
+	watchYTDLPBin   string
+	watchFormat     string
+	watchQuality    string
+	watchSubLangs   string
+	watchNoSubs     bool
+	watchLimitRate  string
+	watchLogFormat  string
+	watchPoll       bool
+	watchInterval   time.Duration
+	watchSettle     time.Duration
+	watchOnce       bool
+	watchLogLevel   string
+	watchMaxRetries int
+)
+
+func init() {
+	w := watchCmd
+	cfg := config.Load()
+
+	w.Flags().StringVarP(&watchOutputDir, "output", "o", ".",
+		"Output directory; a sidecar \"output\" is relative to it")
+	w.Flags().StringVar(&watchYTDLPBin, "yt-dlp-bin", cfg.YTDLPBin,
+		"Path to the yt-dlp/youtube-dl binary")
+	w.Flags().StringVarP(&watchFormat, "format", "f", "mp4",
+		"Default output container format")
+	w.Flags().StringVarP(&watchQuality, "quality", "q", "",
+		"Default video quality: 720, 1080, … (empty = best)")
+	w.Flags().StringVar(&watchSubLangs, "sub-langs", "en,zh",
+		"Default comma-separated subtitle languages")
+	w.Flags().BoolVar(&watchNoSubs, "no-subs", false,
+		"Disable subtitle download by default")
+	w.Flags().StringVar(&watchLimitRate, "limit-rate", cfg.LimitRate,
+		"Maximum download rate passed through to yt-dlp, e.g. 2M")
+	w.Flags().StringVar(&watchLogFormat, "log-format", "json",
+		"Record / mapping file format: json or csv")
+	w.Flags().IntVar(&watchMaxRetries, "max-retries", 2,
+		"Re-run yt-dlp this many times after a network, rate-limit or unknown failure")
+	w.Flags().BoolVar(&watchPoll, "poll", false,
+		"Rescan the inbox periodically instead of using file system notifications")
+	w.Flags().DurationVar(&watchInterval, "poll-interval", 5*time.Second,
+		"How often to rescan when polling")
+	w.Flags().DurationVar(&watchSettle, "settle", 2*time.Second,
+		"Wait until a file has not changed for this long before reading it")
+	w.Flags().BoolVar(&watchOnce, "once", false,
+		"Process the files already in the inbox and exit")
+	w.Flags().StringVar(&watchLogLevel, "log-level", "info",
+		"Run log detail in <output>/.logs/run.log: debug, info, warn, error or off")
+
+	rootCmd.AddCommand(w)
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [flags] <inbox>",
+	Short: "Download URLs from files dropped into a folder",
+	Long: `Watch a folder for .url, .webloc and .txt files and download the URLs in them.
+
+A .txt file holds one URL per line (# starts a comment). A JSON file with the
+same name, e.g. talk.json next to talk.url, overrides the defaults for that
+file: output, format, quality, format_id, playlist, items, sub_langs, no_subs,
+start and end.
+
+Each processed file moves to done/ or failed/ inside the inbox, together with
+its sidecar and a <name>.record.json report of what was downloaded.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWatch,
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+	logFormat := strings.ToLower(strings.TrimSpace(watchLogFormat))
+	if logFormat != "json" && logFormat != "csv" {
+		return fmt.Errorf("unsupported log format %q: use json or csv", watchLogFormat)
+	}
+	outDir := watchOutputDir
+	if outDir == "" {
+		outDir = "."
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("cannot create output directory %q: %w", outDir, err)
+	}
+	logger, closeLog, err := openRunLog(outDir, watchLogLevel)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	langs := splitList(watchSubLangs)
+	if len(langs) == 0 {
+		langs = []string{"en", "zh"}
+	}
+	defaults := ytdl.DefaultOptions()
+	defaults.Format = watchFormat
+	defaults.Quality = watchQuality
+	defaults.OutputDir = outDir
+	defaults.SubtitleLangs = langs
+	defaults.WriteSubtitles = !watchNoSubs
+	defaults.WriteAutoSubs = !watchNoSubs
+	defaults.LimitRate = strings.TrimSpace(watchLimitRate)
+	defaults.LogFormat = logFormat
+	defaults.MaxRetries = max(watchMaxRetries, 0)
+	defaults.YTDLPBin = watchYTDLPBin
+	if caps, err := ytdl.New(ytdl.WithOptions(defaults)).DetectCapabilities(); err == nil {
+		defaults.Capabilities = &caps
+	}
+
+	// Interrupting leaves the file being downloaded in the inbox for next time.
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+
+	handle := func(path string) {
+		processInboxFile(ctx, dir, path, defaults, logger)
+	}
+	if watchOnce {
+		paths, err := inbox.Pending(dir)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if ctx.Err() != nil {
+				break
+			}
+			handle(path)
+		}
+		return nil
+	}
+
+	w := &inbox.Watcher{
+		Dir:      dir,
+		Poll:     watchPoll,
+		Interval: watchInterval,
+		Settle:   watchSettle,
+		OnFallback: func(err error) {
+			fmt.Fprintf(os.Stderr, "warning: file notifications unavailable (%v); polling every %s\n", err, watchInterval)
+		},
+	}
+	fmt.Printf("Watching %s for .url, .webloc and .txt files (Ctrl+C to stop)\n", dir)
+	logger.Info("watch started", "inbox", dir, "output", outDir, "poll", watchPoll)
+	return w.Watch(ctx, handle)
+}
+
+// processInboxFile downloads the URLs of one inbox file and files it under
+// done/ or failed/.
+func processInboxFile(ctx context.Context, dir, path string, defaults ytdl.Options, logger *slog.Logger) {
+	name := filepath.Base(path)
+	report := inbox.Report{ProcessedAt: time.Now()}
+	fail := func(err error) {
+		report.Error = err.Error()
+		fmt.Printf("[inbox] %s: %v\n", name, err)
+		logger.Warn("inbox file rejected", "file", name, "err", err)
+		if _, err := inbox.Finish(dir, path, false, report); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: cannot move %s: %v\n", name, err)
+		}
+	}
+
+	urls, err := inbox.ParseFile(path)
+	if err != nil {
+		fail(err)
+		return
+	}
+	report.URLs = urls
+	sidecar, err := inbox.LoadSidecar(path)
+	if err != nil {
+		fail(err)
+		return
+	}
+	opts := sidecar.Apply(defaults)
+	if err := os.MkdirAll(opts.OutputDir, 0o755); err != nil {
+		fail(fmt.Errorf("cannot create output directory %q: %w", opts.OutputDir, err))
+		return
+	}
+
+	fmt.Printf("[inbox] %s: %d URL(s) → %s\n", name, len(urls), opts.OutputDir)
+	logger.Info("inbox file started", "file", name, "urls", len(urls), "output", opts.OutputDir)
+	client := ytdl.New(ytdl.WithOptions(opts), ytdl.WithLogger(logger), ytdl.WithWarnings(func(msg string) {
+		fmt.Printf("[inbox] %s: warning: %s\n", name, msg)
+	}))
+	sub := client.Subscribe(0, ytdl.Block)
+	var printed sync.WaitGroup
+	printed.Add(1)
+	go func() {
+		defer printed.Done()
+		printPlain(sub.C)
+	}()
+	results, runErr := client.Run(ctx, sidecar.Jobs(urls)...)
+	client.Close()
+	printed.Wait()
+
+	if ctx.Err() != nil {
+		fmt.Printf("[inbox] %s: interrupted; left in the inbox\n", name)
+		return
+	}
+	if _, err := client.SaveRecords(results); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write records: %v\n", err)
+	}
+
+	ok := runErr == nil
+	failed := 0
+	for _, r := range results {
+		if !r.Skipped {
+			report.Records = append(report.Records, ytdl.NewRecord(r))
+		}
+		if !r.Success && !r.Skipped {
+			failed++
+		}
+	}
+	if failed > 0 {
+		ok = false
+		runErr = errors.Join(runErr, fmt.Errorf("%d download(s) failed", failed))
+	}
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	dest, err := inbox.Finish(dir, path, ok, report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot move %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("[inbox] %s: %d downloaded, %d failed → %s\n", name, len(report.Records)-failed, failed, dest)
+	logger.Info("inbox file finished", "file", name, "failed", failed, "moved_to", dest)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/innate/yt-dl/internal/config"
+	"github.com/innate/yt-dl/internal/tui"
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+func init() {
+	rootCmd.Args = cobra.NoArgs
+	rootCmd.RunE = runWizard
+}
+
+// runWizard is the root command: with a terminal attached it opens the
+// interactive download form, otherwise it prints help.
+func runWizard(cmd *cobra.Command, args []string) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return cmd.Help()
+	}
+
+	defaults := ytdl.DefaultOptions()
+	defaults.Quality = ""
+	cfg := config.Load()
+	defaults.YTDLPBin = cfg.YTDLPBin
+	defaults.LimitRate = cfg.LimitRate
+	defaults.Window = cfg.Window
+	defaults.WeekdayWindows = cfg.Windows
+
+	type runOutcome struct {
+		client   *ytdl.Client
+		results  []ytdl.Result
+		playlist bool
+	}
+	finished := make(chan runOutcome, 1)
+	var sub *ytdl.Subscription
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	commandCh := make(chan ytdl.Command, 16)
+	defer close(commandCh)
+
+	w, err := tui.RunWizard(tui.WizardConfig{
+		Defaults: defaults,
+		Commands: commandCh,
+		FetchPlaylist: func(url string) (ytdl.PlaylistInfo, error) {
+			return ytdl.New(ytdl.WithOptions(defaults)).Playlist(ctx, url)
+		},
+		Start: func(res tui.WizardResult) <-chan ytdl.Progress {
+			client := ytdl.New(ytdl.WithOptions(res.Options))
+			sub = client.Subscribe(0, ytdl.CoalesceProgress)
+			go client.Listen(commandCh)
+			go func() {
+				defer client.Close()
+				outcome := runOutcome{client: client}
+				jobs := make([]ytdl.Job, 0, len(res.Jobs))
+				for _, job := range res.Jobs {
+					jobs = append(jobs, ytdl.Job{URL: job.URL, Playlist: job.Playlist})
+					outcome.playlist = outcome.playlist || job.Playlist
+				}
+				// A missing output directory is reported as an error item.
+				outcome.results, _ = client.Run(ctx, jobs...)
+				finished <- outcome
+			}()
+			return sub.C
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("wizard: %w", err)
+	}
+	if !w.Started() {
+		return nil
+	}
+
+	// The user may leave the progress view early; that cancels the run, and
+	// unsubscribing keeps the remaining downloads from waiting on the view.
+	cancel()
+	sub.Unsubscribe()
+	outcome := <-finished
+	return finishRun(outcome.client, outcome.results, outcome.playlist, outputTUI)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -10,6 +10,13 @@ import (
 // Config holds runtime defaults loaded from config.json.
 type Config struct {
 	YTDLPBin string `json:"yt_dlp_bin"`
+
+	// LimitRate is the default --limit-rate, e.g. "2M".
+	LimitRate string `json:"limit_rate"`
+	// Window is the default --window, e.g. "22:00-07:00".
+	Window string `json:"window"`
+	// Windows holds per-weekday window rules keyed by mon … sun.
+	Windows map[string]string `json:"windows"`
 }
 
 // Default returns the built-in defaults.
@@ -40,6 +47,9 @@ func Load() Config {
 	if v := strings.TrimSpace(fileCfg.YTDLPBin); v != "" {
 		cfg.YTDLPBin = v
 	}
+	cfg.LimitRate = strings.TrimSpace(fileCfg.LimitRate)
+	cfg.Window = strings.TrimSpace(fileCfg.Window)
+	cfg.Windows = fileCfg.Windows
 	return cfg
 }
 
@@ -29,3 +29,20 @@ func TestLoadFallsBackToDefault(t *testing.T) {
 		t.Fatalf("expected default yt_dlp_bin %q, got %q", Default().YTDLPBin, cfg.YTDLPBin)
 	}
 }
+
+func TestLoadReadsRateAndWindows(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	data := []byte(`{"limit_rate":"2M","window":"22:00-07:00","windows":{"sat":"all","sun":"off"}}`)
+	if err := os.WriteFile(configPath, data, 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	t.Setenv("VYTDL_CONFIG", configPath)
+	cfg := Load()
+	if cfg.LimitRate != "2M" || cfg.Window != "22:00-07:00" {
+		t.Fatalf("unexpected rate/window: %q %q", cfg.LimitRate, cfg.Window)
+	}
+	if cfg.Windows["sat"] != "all" || cfg.Windows["sun"] != "off" {
+		t.Fatalf("unexpected windows: %v", cfg.Windows)
+	}
+}
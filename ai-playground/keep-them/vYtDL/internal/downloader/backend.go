@@ -0,0 +1,117 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Backend fetches media for a Downloader. The Downloader owns queueing, state,
+// retries and progress reporting; a Backend only talks to one tool or protocol.
+type Backend interface {
+	// Name identifies the backend in messages, e.g. "yt-dlp".
+	Name() string
+	// Probe returns the metadata and available formats of a single video.
+	Probe(ctx context.Context, url string) (FormatList, error)
+	// ListPlaylist returns the flat entry list of a playlist or channel.
+	ListPlaylist(ctx context.Context, url string) (PlaylistInfo, error)
+	// Download fetches url into outDir, calling progress as it goes, and
+	// writes the tool's complete output to log. It must stop promptly when
+	// ctx is cancelled. On failure the returned Fetched still carries
+	// whatever diagnostics were collected.
+	Download(ctx context.Context, url, outDir string, log io.Writer, progress func(Progress)) (Fetched, error)
+}
+
+// Progress is one progress report from a Backend.
+type Progress struct {
+	Status     string // "downloading" or "merging"
+	Percent    float64
+	Speed      string
+	ETA        string
+	TotalBytes int64      // 0 if unknown
+	Info       *VideoInfo // set once metadata for the video is known
+}
+
+// Fetched is what a Backend reports about one Download call.
+type Fetched struct {
+	// Info describes the downloaded video. Info.Filename is the output path
+	// when the backend knows it; otherwise the Downloader derives it.
+	Info VideoInfo
+	// Stderr holds diagnostic lines used to classify failures.
+	Stderr []string
+}
+
+// KindError is returned by backends that already know why a download failed,
+// so the Downloader need not classify their output.
+type KindError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *KindError) Error() string { return e.Err.Error() }
+func (e *KindError) Unwrap() error { return e.Err }
+
+// Backend names accepted by Options.Backend.
+const (
+	BackendAuto      = ""
+	BackendYTDLP     = "yt-dlp"
+	BackendYoutubeDL = "youtube-dl"
+	BackendHTTP      = "http"
+)
+
+// SetBackend makes the Downloader use b for every URL instead of choosing a
+// backend from Options. Mainly for library users and tests.
+func (d *Downloader) SetBackend(b Backend) {
+	d.backend = b
+}
+
+// backendFor returns the backend that handles url.
+func (d *Downloader) backendFor(rawURL string) (Backend, error) {
+	if d.backend != nil {
+		return d.backend, nil
+	}
+	name := strings.ToLower(strings.TrimSpace(d.opts.Backend))
+	if name == BackendAuto && IsDirectMediaURL(rawURL) {
+		name = BackendHTTP
+	}
+	switch name {
+	case BackendHTTP:
+		return newHTTPBackend(d.opts), nil
+	case BackendAuto, BackendYTDLP, BackendYoutubeDL:
+		bin, err := d.resolveYTDLPBin()
+		if err != nil {
+			return nil, &KindError{Kind: ErrorBinary, Err: err}
+		}
+		youtubeDL := name == BackendYoutubeDL ||
+			(name == BackendAuto && strings.Contains(strings.ToLower(filepath.Base(bin)), "youtube-dl"))
+		return newCLIBackend(bin, d.opts, youtubeDL), nil
+	}
+	return nil, fmt.Errorf("unknown backend %q (want yt-dlp, youtube-dl or http)", d.opts.Backend)
+}
+
+// filePrefixer is a Backend that can start the names of the files it writes
+// with a prefix, for Options.IndexPrefix.
+type filePrefixer interface {
+	withFilePrefix(prefix string) Backend
+}
+
+// directMediaExts are file extensions the HTTP backend can fetch as-is.
+var directMediaExts = map[string]bool{
+	".mp4": true, ".m4v": true, ".mkv": true, ".webm": true, ".mov": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true,
+	".flac": true, ".wav": true,
+}
+
+// IsDirectMediaURL reports whether rawURL points straight at a media file
+// rather than at a page a site extractor has to resolve.
+func IsDirectMediaURL(rawURL string) bool {
+	u, err := url.Parse(normalizeURL(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return directMediaExts[strings.ToLower(path.Ext(u.Path))]
+}
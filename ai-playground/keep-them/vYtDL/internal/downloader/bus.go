@@ -0,0 +1,216 @@
+package downloader
+
+import "sync"
+
+// Policy decides what a subscription does with "downloading" updates while
+// its buffer is full. Other updates are never dropped: views rely on seeing
+// every status change.
+type Policy int
+
+const (
+	// Block makes Publish wait for the subscriber. Nothing is lost.
+	Block Policy = iota
+	// DropProgress discards "downloading" updates while the buffer is full.
+	DropProgress
+	// CoalesceProgress replaces a queued "downloading" update with a newer one
+	// for the same item, so a slow subscriber still sees current numbers.
+	CoalesceProgress
+)
+
+// defaultBuffer is used when Subscribe is given no buffer size.
+const defaultBuffer = 64
+
+// Bus fans ProgressUpdates out to any number of subscribers, each with its
+// own buffer, so a slow progress view does not stall a log writer.
+type Bus struct {
+	mu     sync.Mutex
+	subs   []*Subscription
+	closed bool
+}
+
+// NewBus returns an empty bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscription receives a bus's updates on C, in publish order, until the
+// bus is closed or Unsubscribe is called.
+type Subscription struct {
+	C <-chan ProgressUpdate
+
+	bus    *Bus
+	out    chan ProgressUpdate
+	stop   chan struct{}
+	policy Policy
+	buffer int
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	queue     []ProgressUpdate
+	closed    bool // no more updates will arrive; C closes once queue drains
+	cancelled bool // unsubscribed; C closes right away
+	dropped   int
+}
+
+// Subscribe attaches a new subscriber. buffer <= 0 picks a default size.
+func (b *Bus) Subscribe(buffer int, policy Policy) *Subscription {
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+	out := make(chan ProgressUpdate)
+	s := &Subscription{C: out, bus: b, out: out, stop: make(chan struct{}), policy: policy, buffer: buffer}
+	s.cond = sync.NewCond(&s.mu)
+
+	b.mu.Lock()
+	if b.closed {
+		s.closed = true
+	} else {
+		b.subs = append(b.subs, s)
+	}
+	b.mu.Unlock()
+
+	go s.pump()
+	return s
+}
+
+// Publish hands u to every subscriber. It only blocks for Block subscribers
+// whose buffer is full.
+func (b *Bus) Publish(u ProgressUpdate) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	subs := append([]*Subscription(nil), b.subs...)
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.offer(u)
+	}
+}
+
+// Close ends the stream: each subscriber's C closes after it has received
+// everything already published.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.mu.Lock()
+		s.closed = true
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}
+}
+
+// Unsubscribe detaches s and closes C without delivering what is queued.
+// Use it when a subscriber stops reading before the bus closes.
+func (s *Subscription) Unsubscribe() {
+	s.mu.Lock()
+	if s.cancelled {
+		s.mu.Unlock()
+		return
+	}
+	s.cancelled = true
+	close(s.stop)
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	b := s.bus
+	b.mu.Lock()
+	for i, sub := range b.subs {
+		if sub == s {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+}
+
+// Dropped reports how many "downloading" updates the policy discarded.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+func (s *Subscription) offer(u ProgressUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancelled || s.closed {
+		return
+	}
+	progress := u.Status == "downloading"
+
+	if progress && s.policy == CoalesceProgress {
+		for i := len(s.queue) - 1; i >= 0; i-- {
+			if s.queue[i].Key == u.Key && s.queue[i].Status == "downloading" {
+				s.queue[i] = u
+				s.dropped++
+				return
+			}
+			if s.queue[i].Key == u.Key {
+				break // a newer status for the item is already queued
+			}
+		}
+	}
+
+	if len(s.queue) >= s.buffer {
+		switch {
+		case s.policy == Block:
+			for len(s.queue) >= s.buffer && !s.cancelled {
+				s.cond.Wait()
+			}
+			if s.cancelled {
+				return
+			}
+		case progress && s.policy == DropProgress:
+			s.dropped++
+			return
+		case progress && s.policy == CoalesceProgress:
+			// No update of this item to replace; make room by dropping the
+			// oldest queued progress of any item.
+			for i, queued := range s.queue {
+				if queued.Status == "downloading" {
+					s.queue = append(s.queue[:i], s.queue[i+1:]...)
+					s.dropped++
+					break
+				}
+			}
+		}
+	}
+	s.queue = append(s.queue, u)
+	s.cond.Broadcast()
+}
+
+// pump moves queued updates to C one at a time.
+func (s *Subscription) pump() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed && !s.cancelled {
+			s.cond.Wait()
+		}
+		if s.cancelled || len(s.queue) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		u := s.queue[0]
+		s.queue = s.queue[1:]
+		s.cond.Broadcast() // room for a blocked Publish
+		s.mu.Unlock()
+
+		select {
+		case s.out <- u:
+		case <-s.stop:
+			return
+		}
+	}
+}
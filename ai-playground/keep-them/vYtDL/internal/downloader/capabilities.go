@@ -0,0 +1,176 @@
+package downloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Capabilities describes what an installed yt-dlp or youtube-dl supports.
+type Capabilities struct {
+	Binary  string
+	Version string
+	Flags   map[string]bool // long options listed by --help
+}
+
+var (
+	helpFlagRe    = regexp.MustCompile(`--[a-z][a-z0-9-]+`)
+	dateVersionRe = regexp.MustCompile(`^(\d{4})\.(\d{2})\.(\d{2})`)
+)
+
+// DetectCapabilities runs bin --version and bin --help.
+func DetectCapabilities(bin string) (Capabilities, error) {
+	caps := Capabilities{Binary: bin}
+	out, err := exec.Command(bin, "--version").Output()
+	if err != nil {
+		return caps, fmt.Errorf("%s --version: %w", bin, err)
+	}
+	caps.Version = strings.TrimSpace(string(out))
+	help, err := exec.Command(bin, "--help").Output()
+	if err != nil {
+		return caps, fmt.Errorf("%s --help: %w", bin, err)
+	}
+	caps.Flags = ParseHelpFlags(string(help))
+	if len(caps.Flags) == 0 {
+		return caps, errors.New("--help listed no options")
+	}
+	return caps, nil
+}
+
+// cachedCaps is one entry of the capabilities cache, valid while the binary
+// keeps its size and modification time.
+type cachedCaps struct {
+	ModTime time.Time    `json:"mod_time"`
+	Size    int64        `json:"size"`
+	Caps    Capabilities `json:"capabilities"`
+}
+
+// CachedCapabilities is DetectCapabilities, remembering the answer in the
+// user cache directory so runs do not start the binary twice before every
+// download. An update of the binary changes its modification time and
+// invalidates the entry.
+func CachedCapabilities(bin string) (Capabilities, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return DetectCapabilities(bin)
+	}
+	return cachedCapabilities(bin, filepath.Join(dir, "vytdl", "capabilities.json"))
+}
+
+func cachedCapabilities(bin, cacheFile string) (Capabilities, error) {
+	path, err := exec.LookPath(bin)
+	if err == nil {
+		path, err = filepath.Abs(path)
+	}
+	var info os.FileInfo
+	if err == nil {
+		info, err = os.Stat(path)
+	}
+	if err != nil {
+		return DetectCapabilities(bin)
+	}
+
+	cache := map[string]cachedCaps{}
+	if data, err := os.ReadFile(cacheFile); err == nil {
+		_ = json.Unmarshal(data, &cache) // a broken cache is rebuilt
+	}
+	if hit, ok := cache[path]; ok && hit.ModTime.Equal(info.ModTime()) && hit.Size == info.Size() {
+		hit.Caps.Binary = bin
+		return hit.Caps, nil
+	}
+
+	caps, err := DetectCapabilities(bin)
+	if err != nil {
+		return caps, err
+	}
+	cache[path] = cachedCaps{ModTime: info.ModTime(), Size: info.Size(), Caps: caps}
+	if data, err := json.Marshal(cache); err == nil && os.MkdirAll(filepath.Dir(cacheFile), 0o755) == nil {
+		tempFile := cacheFile + ".tmp"
+		if os.WriteFile(tempFile, data, 0o644) == nil {
+			_ = os.Rename(tempFile, cacheFile)
+		}
+	}
+	return caps, nil
+}
+
+// ParseHelpFlags collects the long options mentioned in --help output.
+func ParseHelpFlags(help string) map[string]bool {
+	flags := map[string]bool{}
+	for _, flag := range helpFlagRe.FindAllString(help, -1) {
+		flags[flag] = true
+	}
+	return flags
+}
+
+// Supports reports whether flag is available. A nil Capabilities, or one
+// whose detection failed, supports everything.
+func (c *Capabilities) Supports(flag string) bool {
+	if c == nil || len(c.Flags) == 0 {
+		return true
+	}
+	return c.Flags[flag]
+}
+
+// ReleaseDate parses yt-dlp's date-based version ("2024.08.06"). The zero
+// time means the version is not date-based, as with youtube-dl forks.
+func (c *Capabilities) ReleaseDate() time.Time {
+	if c == nil {
+		return time.Time{}
+	}
+	m := dateVersionRe.FindStringSubmatch(c.Version)
+	if m == nil {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006.01.02", m[1]+"."+m[2]+"."+m[3])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// ResolveBinary returns the yt-dlp path a download would use: YTDLPBin when
+// set, otherwise YT_DL_BIN, then yt-dlp or youtube-dl from PATH.
+func (d *Downloader) ResolveBinary() (string, error) {
+	return d.resolveYTDLPBin()
+}
+
+// ValidateCookiesFile checks that path is a Netscape-format cookies file, the
+// only format --cookies accepts: comment lines, or seven tab-separated fields.
+func ValidateCookiesFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cookies := 0
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// Comments, except curl's prefix for HttpOnly cookies.
+		if strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "#HttpOnly_") {
+			continue
+		}
+		if fields := strings.Split(line, "\t"); len(fields) != 7 {
+			return fmt.Errorf("line %d: want 7 tab-separated fields, got %d; export the cookies in Netscape format", n, len(fields))
+		}
+		cookies++
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if cookies == 0 {
+		return errors.New("no cookies found")
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package downloader
+
+import "sync"
+
+// CommandAction is a per-item request sent back from a progress view.
+type CommandAction string
+
+const (
+	// CommandCancel stops the item (killing yt-dlp if running) and marks it skipped.
+	CommandCancel CommandAction = "cancel"
+	// CommandRetry re-enqueues a failed or cancelled item.
+	CommandRetry CommandAction = "retry"
+	// CommandPause stops the item and sets it aside; yt-dlp keeps its .part file.
+	CommandPause CommandAction = "pause"
+	// CommandResume re-enqueues a paused item, continuing from the .part file.
+	CommandResume CommandAction = "resume"
+)
+
+// Command addresses one item by its ProgressUpdate.Key.
+type Command struct {
+	Action CommandAction
+	Key    string
+}
+
+// Control carries commands from a progress view to running downloads. One
+// Control may be shared by several Downloaders in the same run.
+type Control struct {
+	mu       sync.Mutex
+	running  map[string]func()        // stops the item's download
+	stopped  map[string]CommandAction // why a running process was killed
+	canceled map[string]bool
+	paused   map[string]bool
+	retry    map[string]bool
+	aborted  bool
+	changed  chan struct{} // closed and replaced on every change
+
+	rateLimits rateLimitStreak
+}
+
+// rateLimitStreak counts cooldowns since the last successful download.
+type rateLimitStreak struct {
+	mu sync.Mutex
+	n  int
+}
+
+// hit records another cooldown and returns how many are now in a row.
+func (s *rateLimitStreak) hit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n++
+	return s.n
+}
+
+func (s *rateLimitStreak) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n = 0
+}
+
+func (s *rateLimitStreak) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
+
+// NewControl creates an idle Control.
+func NewControl() *Control {
+	return &Control{
+		running:  map[string]func(){},
+		stopped:  map[string]CommandAction{},
+		canceled: map[string]bool{},
+		paused:   map[string]bool{},
+		retry:    map[string]bool{},
+		changed:  make(chan struct{}),
+	}
+}
+
+// Listen applies commands until the channel is closed.
+func (c *Control) Listen(commands <-chan Command) {
+	for cmd := range commands {
+		c.Apply(cmd)
+	}
+}
+
+// Apply executes a single command.
+func (c *Control) Apply(cmd Command) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch cmd.Action {
+	case CommandCancel:
+		c.canceled[cmd.Key] = true
+		delete(c.paused, cmd.Key)
+		delete(c.retry, cmd.Key)
+		c.kill(cmd.Key, CommandCancel)
+	case CommandPause:
+		c.paused[cmd.Key] = true
+		c.kill(cmd.Key, CommandPause)
+	case CommandResume:
+		if c.paused[cmd.Key] {
+			delete(c.paused, cmd.Key)
+			c.retry[cmd.Key] = true
+		}
+	case CommandRetry:
+		delete(c.canceled, cmd.Key)
+		delete(c.paused, cmd.Key)
+		c.retry[cmd.Key] = true
+	}
+	c.notify()
+}
+
+// Abort stops every running process and makes waiting downloads give up.
+// Used when the progress view is closed before the run has finished.
+func (c *Control) Abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.aborted = true
+	for key := range c.running {
+		c.kill(key, CommandPause)
+	}
+	c.notify()
+}
+
+// TakeRetry reports and clears a pending retry or resume request for key.
+func (c *Control) TakeRetry(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.retry[key] {
+		return false
+	}
+	delete(c.retry, key)
+	return true
+}
+
+func (c *Control) kill(key string, reason CommandAction) {
+	if stop, ok := c.running[key]; ok {
+		c.stopped[key] = reason
+		stop()
+	}
+}
+
+func (c *Control) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// The methods below are nil-safe so Downloaders without a Control skip them.
+
+func (c *Control) track(key string, stop func()) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running[key] = stop
+	delete(c.stopped, key)
+	// A command may have arrived while the item was still queued.
+	switch {
+	case c.aborted:
+		c.kill(key, CommandPause)
+	case c.canceled[key]:
+		c.kill(key, CommandCancel)
+	case c.paused[key]:
+		c.kill(key, CommandPause)
+	}
+}
+
+// untrack forgets the process and returns why it was stopped, if it was.
+func (c *Control) untrack(key string) CommandAction {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.running, key)
+	reason := c.stopped[key]
+	delete(c.stopped, key)
+	return reason
+}
+
+// pending returns what should happen to an item that is about to start.
+func (c *Control) pending(key string) CommandAction {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.aborted:
+		return CommandPause
+	case c.canceled[key]:
+		return CommandCancel
+	case c.paused[key]:
+		return CommandPause
+	}
+	return ""
+}
+
+func (c *Control) isAborted() bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.aborted
+}
+
+// hasWaiting reports whether any of keys is paused or has a retry queued.
+func (c *Control) hasWaiting(keys []string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		if c.paused[key] || c.retry[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// wait returns a channel that is closed on the next state change.
+func (c *Control) wait() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.changed
+}
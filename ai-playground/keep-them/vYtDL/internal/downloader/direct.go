@@ -0,0 +1,279 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpBackend fetches plain media URLs without a site extractor. Partial
+// downloads are kept as .part files and resumed with a Range request.
+type httpBackend struct {
+	opts       Options
+	client     *http.Client
+	filePrefix string // put before the file name
+}
+
+func newHTTPBackend(opts Options) *httpBackend {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy := strings.TrimSpace(opts.Proxy); proxy != "" {
+		if u, err := url.Parse(proxy); err == nil {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+	timeout := 30 * time.Second
+	if secs, err := strconv.Atoi(strings.TrimSpace(opts.SocketTimeout)); err == nil && secs > 0 {
+		timeout = time.Duration(secs) * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	network := "tcp"
+	if opts.ForceIPv4 {
+		network = "tcp4"
+	}
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	transport.ResponseHeaderTimeout = timeout
+	return &httpBackend{opts: opts, client: &http.Client{Transport: transport}}
+}
+
+func (b *httpBackend) Name() string { return BackendHTTP }
+
+func (b *httpBackend) request(ctx context.Context, method, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ua := strings.TrimSpace(b.opts.UserAgent); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	return req, nil
+}
+
+// Probe issues a HEAD request and describes the file as a single format.
+func (b *httpBackend) Probe(ctx context.Context, rawURL string) (FormatList, error) {
+	req, err := b.request(ctx, http.MethodHead, rawURL)
+	if err != nil {
+		return FormatList{}, err
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return FormatList{}, &KindError{Kind: ErrorNetwork, Err: err}
+	}
+	resp.Body.Close()
+	if err := statusError(resp); err != nil {
+		return FormatList{}, err
+	}
+	name := fileName(rawURL, resp)
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	title := strings.TrimSuffix(name, path.Ext(name))
+	return FormatList{
+		VideoID: title,
+		Title:   title,
+		Formats: []Format{{ID: "direct", Ext: ext, VCodec: "unknown", ACodec: "unknown", Filesize: max(resp.ContentLength, 0)}},
+	}, nil
+}
+
+// ListPlaylist always fails: a media file is never a playlist.
+func (b *httpBackend) ListPlaylist(ctx context.Context, rawURL string) (PlaylistInfo, error) {
+	return PlaylistInfo{}, errors.New("direct media URLs are not playlists")
+}
+
+func (b *httpBackend) withFilePrefix(prefix string) Backend {
+	c := *b
+	c.filePrefix = prefix
+	return &c
+}
+
+// Download streams the file into outDir, resuming a matching .part file.
+func (b *httpBackend) Download(ctx context.Context, rawURL, outDir string, log io.Writer, progress func(Progress)) (Fetched, error) {
+	var fetched Fetched
+	req, err := b.request(ctx, http.MethodGet, rawURL)
+	if err != nil {
+		return fetched, err
+	}
+
+	// The name is only known from the response, so look for a .part file
+	// named after the URL first.
+	partPath := filepath.Join(outDir, sanitizeFilename(path.Base(req.URL.Path))+".part")
+	var offset int64
+	if st, err := os.Stat(partPath); err == nil {
+		offset = st.Size()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		fmt.Fprintf(log, "resuming %s from byte %d\n", partPath, offset)
+	}
+	fmt.Fprintf(log, "GET %s\n", rawURL)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fetched, ctx.Err()
+		}
+		return fetched, &KindError{Kind: ErrorNetwork, Err: err}
+	}
+	defer resp.Body.Close()
+	fmt.Fprintf(log, "HTTP %s, %d bytes\n", resp.Status, resp.ContentLength)
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// The .part file does not match the remote file; start over next try.
+		_ = os.Remove(partPath)
+		return fetched, &KindError{Kind: ErrorNetwork, Err: errors.New("discarded stale partial download")}
+	}
+	if err := statusError(resp); err != nil {
+		return fetched, err
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resp.StatusCode == http.StatusPartialContent {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	} else {
+		offset = 0
+	}
+
+	name := fileName(rawURL, resp)
+	title := strings.TrimSuffix(name, path.Ext(name))
+	info := VideoInfo{
+		ID:       title,
+		Title:    title,
+		Ext:      strings.TrimPrefix(path.Ext(name), "."),
+		FormatID: "direct",
+		Filename: filepath.Join(outDir, b.filePrefix+name),
+	}
+	progress(Progress{Status: "downloading", Info: &info})
+
+	f, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return fetched, &KindError{Kind: ErrorDisk, Err: err}
+	}
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	written, err := b.copy(f, resp.Body, offset, total, progress)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = &KindError{Kind: ErrorDisk, Err: closeErr}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return fetched, ctx.Err()
+		}
+		return fetched, err
+	}
+	if total >= 0 && written < total {
+		return fetched, &KindError{Kind: ErrorNetwork, Err: fmt.Errorf("connection closed after %d of %d bytes", written, total)}
+	}
+	if err := os.Rename(partPath, info.Filename); err != nil {
+		return fetched, &KindError{Kind: ErrorDisk, Err: err}
+	}
+	fetched.Info = info
+	return fetched, nil
+}
+
+// copy writes body to f, reporting progress about twice a second and
+// honouring LimitRate. It returns the total bytes now in the file.
+func (b *httpBackend) copy(f *os.File, body io.Reader, offset, total int64, progress func(Progress)) (int64, error) {
+	limit := ParseByteSize(b.opts.LimitRate)
+	start := time.Now()
+	lastReport := start
+	written := offset
+	buf := make([]byte, 64*1024)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				return written, &KindError{Kind: ErrorDisk, Err: err}
+			}
+			written += int64(n)
+		}
+		elapsed := time.Since(start)
+		if limit > 0 {
+			// Sleep until the average rate is back under the limit.
+			if want := time.Duration(float64(written-offset) / float64(limit) * float64(time.Second)); want > elapsed {
+				time.Sleep(want - elapsed)
+				elapsed = want
+			}
+		}
+		if time.Since(lastReport) >= 500*time.Millisecond || readErr == io.EOF {
+			lastReport = time.Now()
+			progress(httpProgress(written, offset, total, elapsed))
+		}
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, &KindError{Kind: ErrorNetwork, Err: readErr}
+		}
+	}
+}
+
+func httpProgress(written, offset, total int64, elapsed time.Duration) Progress {
+	p := Progress{Status: "downloading"}
+	if total > 0 {
+		p.TotalBytes = total
+		p.Percent = float64(written) / float64(total) * 100
+	}
+	if secs := elapsed.Seconds(); secs > 0 {
+		rate := float64(written-offset) / secs
+		p.Speed = humanRate(rate)
+		if total > 0 && rate > 0 {
+			eta := time.Duration(float64(total-written) / rate * float64(time.Second)).Round(time.Second)
+			p.ETA = fmt.Sprintf("%02d:%02d", int(eta.Minutes()), int(eta.Seconds())%60)
+		}
+	}
+	return p
+}
+
+// humanRate formats bytes per second the way yt-dlp does, e.g. "2.00MiB/s",
+// so ParseByteSize can read it back.
+func humanRate(rate float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for rate >= 1024 && i < len(units)-1 {
+		rate /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.2f%s/s", rate, units[i])
+}
+
+// statusError maps HTTP failures to error kinds.
+func statusError(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	err := fmt.Errorf("HTTP %s", resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &KindError{Kind: ErrorRateLimited, Err: err}
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		return &KindError{Kind: ErrorUnavailable, Err: err}
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return &KindError{Kind: ErrorPrivate, Err: err}
+	case resp.StatusCode >= 500:
+		return &KindError{Kind: ErrorNetwork, Err: err}
+	}
+	return &KindError{Kind: ErrorUnknown, Err: err}
+}
+
+// fileName picks the output name from Content-Disposition or the URL path.
+func fileName(rawURL string, resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := sanitizeFilename(path.Base(params["filename"])); name != "" && name != "." {
+			return name
+		}
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		if name := sanitizeFilename(path.Base(u.Path)); name != "" && name != "." && name != "/" {
+			return name
+		}
+	}
+	return "download"
+}
@@ -1,13 +1,16 @@
 package downloader
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,41 +25,84 @@ type ProgressUpdate struct {
 	Percent float64
 	Speed   string
 	ETA     string
-	Status  string // "downloading", "merging", "done", "error"
+	Status  string // "queued", "waiting", "starting", "downloading", "merging", "done", "error", "skipped", "paused", "retrying", "cooldown"
 	Error   string
+	// ErrorKind classifies Error on "error".
+	ErrorKind ErrorKind
+
+	// TotalBytes is the size yt-dlp reports for the current stream (0 if unknown).
+	TotalBytes int64
+	// OutputDir, Filename and Subtitles are filled in on "done".
+	OutputDir string
+	Filename  string
+	Subtitles []string
+	// ResumeAt is set on "waiting", "retrying" and "cooldown": when the item
+	// starts again.
+	ResumeAt time.Time
+	// Attempt is the number of the upcoming try on "retrying".
+	Attempt int
+	// HookError is set on "hook_failed", sent after the item's final "done"
+	// or "error" when a post-download hook failed.
+	HookError string
 }
 
 // VideoInfo holds metadata extracted from yt-dlp --dump-json.
 type VideoInfo struct {
-	ID            string `json:"id"`
-	Title         string `json:"title"`
-	Ext           string `json:"ext"`
-	PlaylistID    string `json:"playlist_id"`
-	PlaylistTitle string `json:"playlist_title"`
-	Filename      string // resolved output filename
+	ID            string  `json:"id"`
+	Title         string  `json:"title"`
+	Ext           string  `json:"ext"`
+	PlaylistID    string  `json:"playlist_id"`
+	PlaylistTitle string  `json:"playlist_title"`
+	FormatID      string  `json:"format_id"`
+	Resolution    string  `json:"resolution"`
+	VCodec        string  `json:"vcodec"`
+	ACodec        string  `json:"acodec"`
+	Duration      float64 `json:"duration"`
+	UploadDate    string  `json:"upload_date"` // YYYYMMDD
+	Thumbnail     string  `json:"thumbnail"`
+	Description   string  `json:"description"`
+	Filename      string  // resolved output filename
 }
 
 // Downloader wraps yt-dlp for video and playlist downloads.
 type Downloader struct {
 	opts     Options
 	progress chan<- ProgressUpdate
-}
+	bus      *Bus // replaces progress when set
+	control  *Control
+	backend  Backend // nil: chosen per URL from Options.Backend
+	logger   *slog.Logger
+	warn     func(string)
+	archived archive // Options.Archive, loaded per playlist run
 
-type playlistEntry struct {
-	ID         string `json:"id"`
-	Title      string `json:"title"`
-	URL        string `json:"url"`
-	WebpageURL string `json:"webpage_url"`
-}
+	schedule    *Schedule // Options.Window and WeekdayWindows; nil: always open
+	scheduleErr error     // why they did not parse; every run fails with it
 
-type playlistMetadata struct {
-	Title   string          `json:"title"`
-	Entries []playlistEntry `json:"entries"`
+	rateLimits *rateLimitStreak // shared with the Control's other Downloaders
 }
 
 // New creates a new Downloader. progress receives live updates (may be nil).
 func New(opts Options, progress chan<- ProgressUpdate) *Downloader {
-	return &Downloader{opts: opts, progress: progress}
+	d := &Downloader{opts: opts, progress: progress, rateLimits: &rateLimitStreak{}}
+	if d.schedule, d.scheduleErr = ParseSchedule(opts.Window, opts.WeekdayWindows); d.scheduleErr != nil {
+		d.scheduleErr = fmt.Errorf("download window: %w", d.scheduleErr)
+	}
+	return d
+}
+
+// SetBus publishes updates on b instead of the progress channel, so several
+// views and logs can follow the same run.
+func (d *Downloader) SetBus(b *Bus) {
+	d.bus = b
+}
+
+// SetControl lets a progress view cancel, pause, resume and retry items.
+// The rate-limit cooldown keeps growing across all Downloaders sharing c.
+func (d *Downloader) SetControl(c *Control) {
+	d.control = c
+	if c != nil {
+		d.rateLimits = &c.rateLimits
+	}
 }
 
 // ytdlpBin returns the yt-dlp binary path (prefers yt-dlp over youtube-dl).
@@ -72,133 +118,107 @@ func ytdlpBin() (string, error) {
 	return "", fmt.Errorf("neither yt-dlp nor youtube-dl found in PATH; please install yt-dlp")
 }
 
-// buildArgs constructs yt-dlp arguments from options.
-func (d *Downloader) buildArgs(url, outDir string) []string {
-	o := d.opts
-	args := []string{}
-	container := strings.TrimSpace(o.Format)
-
-	// Format selection
-	if o.Quality != "" && o.Quality != "bestvideo+bestaudio" {
-		// quality like "720", "1080" → select best video up to that height
-		height := strings.TrimSuffix(o.Quality, "p")
-		videoSel := fmt.Sprintf("bestvideo[height<=%s]", height)
-		bestSel := fmt.Sprintf("best[height<=%s]", height)
-		fmtStr := fmt.Sprintf("%s+bestaudio/%s", videoSel, bestSel)
-		args = append(args, "-f", fmtStr)
-	} else {
-		args = append(args, "-f", "bestvideo+bestaudio/best")
-	}
-
-	// Merge format
-	if container != "" {
-		args = append(args, "--merge-output-format", container)
-	}
-
-	// Output template
-	outTemplate := filepath.Join(outDir, "%(title)s.%(ext)s")
-	args = append(args, "-o", outTemplate)
-
-	// Subtitles
-	if o.WriteSubtitles {
-		args = append(args, "--write-subs")
-		if o.WriteAutoSubs {
-			args = append(args, "--write-auto-subs")
-		}
-		if len(o.SubtitleLangs) > 0 {
-			args = append(args, "--sub-langs", strings.Join(o.SubtitleLangs, ","))
-		}
-	}
-
-	// Time range (requires ffmpeg)
-	if o.StartTime != "" || o.EndTime != "" {
-		section := ""
-		if o.StartTime != "" {
-			section += "*" + o.StartTime + "-"
-		} else {
-			section += "*0-"
-		}
-		if o.EndTime != "" {
-			section += o.EndTime
-		} else {
-			section += "inf"
-		}
-		args = append(args, "--download-sections", section, "--force-keyframes-at-cuts")
-	}
-
-	// Playlist handling
-	if !o.IsPlaylist {
-		args = append(args, "--no-playlist")
-	}
-
-	if retries := strings.TrimSpace(o.Retries); retries != "" {
-		args = append(args, "--retries", retries, "--extractor-retries", retries)
-	}
-	if timeout := strings.TrimSpace(o.SocketTimeout); timeout != "" {
-		args = append(args, "--socket-timeout", timeout)
-	}
-	if o.ForceIPv4 {
-		args = append(args, "--force-ipv4")
-	}
-	if proxy := strings.TrimSpace(o.Proxy); proxy != "" {
-		args = append(args, "--proxy", proxy)
-	}
-	if cookies := strings.TrimSpace(o.CookiesFile); cookies != "" {
-		args = append(args, "--cookies", cookies)
-	}
-	if browser := strings.TrimSpace(o.CookiesFromBrowser); browser != "" {
-		args = append(args, "--cookies-from-browser", browser)
-	}
-	if ua := strings.TrimSpace(o.UserAgent); ua != "" {
-		args = append(args, "--user-agent", ua)
-	}
-	if extractorArgs := strings.TrimSpace(o.ExtractorArgs); extractorArgs != "" {
-		args = append(args, "--extractor-args", extractorArgs)
-	}
-
-	// Progress output in newline-delimited form for parsing
-	args = append(args, "--newline", "--progress")
-
-	// JSON metadata for post-processing
-	args = append(args, "--print-json")
-
-	args = append(args, url)
-	return args
-}
-
-// progressRe matches yt-dlp progress lines like:
-// [download]  42.3% of ~100.00MiB at  2.00MiB/s ETA 00:30
-var progressRe = regexp.MustCompile(`\[download\]\s+([\d.]+)%.*?at\s+(\S+)\s+ETA\s+(\S+)`)
-
 // DownloadResult holds the outcome of a single video download attempt.
 type DownloadResult struct {
+	Key        string // progress/state key of the item, as used in Command
 	VideoID    string
 	Title      string
 	URL        string
 	OutputDir  string
 	Filename   string
 	Subtitles  []string // paths to subtitle files
-	Success    bool
-	Error      string
-	StartedAt  time.Time
-	FinishedAt time.Time
+	FormatID   string   // format yt-dlp actually chose, e.g. "137+140"
+	Resolution string
+	VCodec     string
+	ACodec     string
+	// Duration, UploadDate, Thumbnail and Description are what the tool
+	// reported about the video; playlist state keeps them for feeds.
+	Duration    float64 // seconds
+	UploadDate  string  // YYYYMMDD
+	Thumbnail   string  // URL
+	Description string
+	Success     bool
+	Skipped     bool      // cancelled by the user; neither a success nor a failure
+	Error       string    // short human message
+	ErrorKind   ErrorKind // classification of Error; empty on success
+	Stderr      string    // raw yt-dlp stderr of a failed run
+	HookError   string    // failed --exec-after/--webhook; the download itself may have succeeded
+	LogFile     string    // complete tool output of every attempt, under OutputDir/.logs
+	StartedAt   time.Time
+	FinishedAt  time.Time
+
+	stopped CommandAction // set when a Control killed the process
 }
 
+// cancelledMessage is the Error of items cancelled through a Control.
+const cancelledMessage = "cancelled by user"
+
 // DownloadSingle downloads one video (non-playlist).
 func (d *Downloader) DownloadSingle(url string) DownloadResult {
 	url = normalizeURL(url)
-	return d.download(url, d.opts.OutputDir)
+	if d.scheduleErr != nil {
+		return DownloadResult{Key: url, URL: url, OutputDir: d.opts.OutputDir, Error: d.scheduleErr.Error()}
+	}
+	if !d.waitForWindow(url, "", url) {
+		return DownloadResult{Key: url, URL: url, OutputDir: d.opts.OutputDir, Skipped: true,
+			Error: "run stopped while waiting for the download window"}
+	}
+	for {
+		result := d.withRetries(url, "", url, d.triesLeft(0), func() DownloadResult {
+			return d.download(url, d.opts.OutputDir, url, "", "")
+		})
+		if result.stopped != CommandPause {
+			return d.finish(result, nil)
+		}
+		// Paused: wait for resume, retry, cancel or abort.
+		for {
+			changed := d.control.wait()
+			if d.control.TakeRetry(url) {
+				break
+			}
+			if d.control.isAborted() || d.control.pending(url) == CommandCancel {
+				result.stopped = CommandCancel
+				result.Skipped = true
+				result.Error = cancelledMessage
+				d.emit(ProgressUpdate{Key: url, VideoID: result.VideoID, Title: result.Title, Status: "skipped", Error: cancelledMessage})
+				return result
+			}
+			<-changed
+		}
+	}
 }
 
 // DownloadPlaylist downloads a full playlist, creating a sub-directory.
 func (d *Downloader) DownloadPlaylist(url string) []DownloadResult {
+	return d.DownloadPlaylistContext(context.Background(), url)
+}
+
+// DownloadPlaylistContext is DownloadPlaylist, stopping the listing when ctx
+// ends; nothing is downloaded then. Running downloads are stopped through the
+// Control instead, so they keep their partial files.
+func (d *Downloader) DownloadPlaylistContext(ctx context.Context, url string) []DownloadResult {
 	url = normalizeURL(url)
-	meta, err := d.fetchPlaylistMetadata(url)
-	title := sanitizeDirName(meta.Title)
-	if err != nil || title == "" {
-		title = sanitizeDirName(url)
+	if d.scheduleErr != nil {
+		return []DownloadResult{{URL: url, OutputDir: d.opts.OutputDir, Error: d.scheduleErr.Error()}}
 	}
-
+	meta, err := d.FetchPlaylistContext(ctx, url)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil && d.listsFromFeed(url) {
+		// Without a listing there is nothing new to compare; never fall back
+		// to handing the feed URL to the download tool.
+		return []DownloadResult{{URL: url, OutputDir: d.opts.OutputDir, Success: false,
+			Error: fmt.Sprintf("cannot read feed: %v", err)}}
+	}
+	if err != nil {
+		meta.Title = ""
+	}
+	if len(meta.Entries) == 0 && d.listsFromFeed(url) {
+		d.log().Info("feed has no entries", "feed", url)
+		return nil
+	}
+	title := playlistDirName(meta.Title, url)
 	playlistDir := filepath.Join(d.opts.OutputDir, title)
 	if err := os.MkdirAll(playlistDir, 0o755); err != nil {
 		return []DownloadResult{{
@@ -207,26 +227,31 @@ func (d *Downloader) DownloadPlaylist(url string) []DownloadResult {
 			Error:   fmt.Sprintf("cannot create playlist directory: %v", err),
 		}}
 	}
+	warnings, err := d.applyPlaylistConfig(playlistDir, url, true)
+	if err != nil {
+		return []DownloadResult{{URL: url, OutputDir: playlistDir, Success: false, Error: err.Error()}}
+	}
+	for _, w := range warnings {
+		d.warning(w)
+	}
+	prefixWidth, warning := d.indexPrefixWidth(url, title, len(meta.Entries))
+	if warning != "" {
+		d.warning(warning)
+	}
 
 	if len(meta.Entries) == 0 {
-		return []DownloadResult{d.download(url, playlistDir)}
+		// yt-dlp downloads every item in one process; key rows by video.
+		return []DownloadResult{d.finish(d.download(url, playlistDir, "", "", ""),
+			&HookPlaylist{URL: url, Title: meta.Title, Dir: playlistDir})}
 	}
 
-	stateEntries := make([]playliststate.EntryInput, 0, len(meta.Entries))
-	for _, entry := range meta.Entries {
-		stateEntries = append(stateEntries, playliststate.EntryInput{
-			ID:    strings.TrimSpace(entry.ID),
-			URL:   playlistEntryURL(entry),
-			Title: strings.TrimSpace(entry.Title),
-		})
-	}
 	stateMgr, err := playliststate.Open(
 		playliststate.StatePath(playlistDir),
 		url,
 		title,
 		playlistDir,
-		stateEntries,
-		d.opts.ResetPlaylistState,
+		stateInputs(meta),
+		d.mergeOptions(url),
 	)
 	if err != nil {
 		return []DownloadResult{{
@@ -237,244 +262,457 @@ func (d *Downloader) DownloadPlaylist(url string) []DownloadResult {
 		}}
 	}
 
-	results := make([]DownloadResult, 0, len(meta.Entries))
+	selected, err := d.entrySelection()
+	if err == nil {
+		d.archived, err = d.loadArchive()
+	}
+	if err != nil {
+		return []DownloadResult{{
+			URL:       url,
+			OutputDir: playlistDir,
+			Success:   false,
+			Error:     err.Error(),
+		}}
+	}
+
+	todo := make([]playliststate.EntryState, 0, len(meta.Entries))
+	positions := map[string]int{}
 	for _, entry := range stateMgr.Entries() {
-		key := playlistStateKey(entry.ID, entry.URL)
-		if entry.Status == playliststate.StatusSucceeded {
-			if d.progress != nil {
-				d.progress <- ProgressUpdate{
-					Key:     key,
-					VideoID: entry.ID,
-					Title:   entry.Title,
-					Status:  "skipped",
-				}
-			}
+		plan := d.planEntry(entry, selected)
+		key := plan.Key
+		if plan.Index > 0 {
+			positions[key] = plan.Index
+		}
+		if plan.Action != ActionSkip {
+			todo = append(todo, entry)
 			continue
 		}
-
-		entryURL := strings.TrimSpace(entry.URL)
-		if entryURL == "" {
-			result := DownloadResult{
+		switch plan.Reason {
+		case reasonNotSelected:
+			switch entry.Status {
+			case playliststate.StatusSucceeded, playliststate.StatusSkipped, playliststate.StatusUnavailable:
+			default:
+				d.saveState(key, playliststate.StatusSkipped, stateMgr.MarkSkipped(key))
+			}
+		case reasonSkipped:
+		default:
+			d.log().Info("skipping entry", "key", key, "reason", plan.Reason)
+			d.emit(ProgressUpdate{
+				Key:       key,
 				VideoID:   entry.ID,
 				Title:     entry.Title,
-				URL:       url,
-				OutputDir: playlistDir,
-				Success:   false,
-				Error:     "playlist entry is missing a downloadable URL",
+				Status:    "skipped",
+				Error:     plan.Reason,
+				ErrorKind: ErrorKind(entry.ErrorKind),
+			})
+		}
+	}
+
+	d.log().Info("playlist queued", "url", url, "entries", len(meta.Entries), "todo", len(todo), "state", playliststate.StatePath(playlistDir))
+
+	// Announce the whole queue up front so progress views know the total.
+	for _, entry := range todo {
+		d.emit(ProgressUpdate{
+			Key:       playlistStateKey(entry.ID, entry.URL),
+			VideoID:   entry.ID,
+			Title:     entry.Title,
+			Status:    "queued",
+			OutputDir: playlistDir,
+		})
+	}
+
+	results := make([]DownloadResult, 0, len(todo))
+	resultIndex := map[string]int{}
+	queue := append([]playliststate.EntryState(nil), todo...)
+	for {
+		for len(queue) > 0 {
+			entry := queue[0]
+			queue = queue[1:]
+			key := playlistStateKey(entry.ID, entry.URL)
+
+			if !d.waitForWindow(key, entry.ID, entry.Title) {
+				return results
+			}
+			switch d.control.pending(key) {
+			case CommandCancel:
+				d.saveState(key, playliststate.StatusSkipped, stateMgr.MarkSkipped(key))
+				d.emit(ProgressUpdate{Key: key, VideoID: entry.ID, Title: entry.Title, Status: "skipped", Error: cancelledMessage})
+				continue
+			case CommandPause:
+				d.emit(ProgressUpdate{Key: key, VideoID: entry.ID, Title: entry.Title, Status: "paused"})
+				continue
+			}
+
+			result := d.downloadEntry(stateMgr, entry, url, playlistDir, indexPrefix(prefixWidth, entry.PlaylistIndex))
+			switch result.stopped {
+			case CommandCancel:
+				d.saveState(key, playliststate.StatusSkipped, stateMgr.MarkSkipped(key))
+			case CommandPause:
+				d.saveState(key, playliststate.StatusPending, stateMgr.MarkPending(key, "paused before completion"))
+			default:
+				result = d.finish(result, &HookPlaylist{URL: url, Title: meta.Title, Dir: playlistDir,
+					Index: positions[key], Count: len(meta.Entries)})
+				if i, seen := resultIndex[key]; seen {
+					results[i] = result // a retry replaces the earlier outcome
+				} else {
+					resultIndex[key] = len(results)
+					results = append(results, result)
+				}
 			}
-			_ = stateMgr.MarkFinished(key, entry.Title, "", nil, false, result.Error)
-			results = append(results, result)
+			queue = append(queue, d.takeRetries(todo)...)
+		}
+
+		// The queue is drained; linger while the user still has paused items
+		// or retry requests for this playlist.
+		if d.control == nil {
+			break
+		}
+		changed := d.control.wait()
+		if queue = d.takeRetries(todo); len(queue) > 0 {
 			continue
 		}
+		keys := make([]string, 0, len(todo))
+		for _, entry := range todo {
+			keys = append(keys, playlistStateKey(entry.ID, entry.URL))
+		}
+		if d.control.isAborted() || !d.control.hasWaiting(keys) {
+			break
+		}
+		<-changed
+	}
+	return results
+}
 
+// takeRetries returns the entries of todo the user asked to retry or resume.
+func (d *Downloader) takeRetries(todo []playliststate.EntryState) []playliststate.EntryState {
+	if d.control == nil {
+		return nil
+	}
+	var again []playliststate.EntryState
+	for _, entry := range todo {
+		if d.control.TakeRetry(playlistStateKey(entry.ID, entry.URL)) {
+			again = append(again, entry)
+		}
+	}
+	return again
+}
+
+// retryKind reports whether a previously failed entry of this kind should be
+// attempted again. Without RetryKinds every failure is retried.
+func (d *Downloader) retryKind(kind ErrorKind) bool {
+	if len(d.opts.RetryKinds) == 0 {
+		return true
+	}
+	if kind == ErrorNone {
+		// Failures recorded before classification existed.
+		kind = ErrorUnknown
+	}
+	for _, k := range d.opts.RetryKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadEntry downloads one playlist entry and records the outcome in the
+// state file. Its files are named starting with filePrefix, if any.
+func (d *Downloader) downloadEntry(stateMgr *playliststate.Manager, entry playliststate.EntryState, playlistURL, playlistDir, filePrefix string) DownloadResult {
+	key := playlistStateKey(entry.ID, entry.URL)
+	entryURL := strings.TrimSpace(entry.URL)
+	if entryURL == "" {
+		result := DownloadResult{
+			Key:       key,
+			VideoID:   entry.ID,
+			Title:     entry.Title,
+			URL:       playlistURL,
+			OutputDir: playlistDir,
+			Success:   false,
+			Error:     "playlist entry is missing a downloadable URL",
+		}
+		d.saveState(key, playliststate.StatusFailed, stateMgr.MarkFinished(key, entry.Title, "", nil, false, result.Error, ""))
+		return result
+	}
+
+	result := d.withRetries(key, entry.ID, entry.Title, d.triesLeft(entry.Attempts), func() DownloadResult {
 		if err := stateMgr.MarkRunning(key); err != nil {
-			results = append(results, DownloadResult{
+			return DownloadResult{
+				Key:       key,
 				VideoID:   entry.ID,
 				Title:     entry.Title,
 				URL:       entryURL,
 				OutputDir: playlistDir,
 				Success:   false,
 				Error:     fmt.Sprintf("cannot update playlist state: %v", err),
-			})
-			continue
-		}
-
-		result := d.download(entryURL, playlistDir)
-		if strings.TrimSpace(result.Title) == "" {
-			result.Title = entry.Title
+				ErrorKind: ErrorDisk,
+			}
 		}
-		if strings.TrimSpace(result.VideoID) == "" {
-			result.VideoID = entry.ID
+		result := d.download(entryURL, playlistDir, key, entry.Title, filePrefix)
+		if !result.Success && d.coolsDown(result.ErrorKind) {
+			// The entry waits out the cooldown instead of failing.
+			d.saveState(key, playliststate.StatusPending, stateMgr.MarkDeferred(key, result.Error))
 		}
-		if err := stateMgr.MarkFinished(key, result.Title, result.Filename, result.Subtitles, result.Success, result.Error); err != nil {
-			result.Success = false
-			result.Error = fmt.Sprintf("download finished but failed to save playlist state: %v", err)
+		return result
+	})
+	if strings.TrimSpace(result.Title) == "" {
+		result.Title = entry.Title
+	}
+	if strings.TrimSpace(result.VideoID) == "" {
+		result.VideoID = entry.ID
+	}
+	if result.stopped != "" {
+		return result
+	}
+	var err error
+	status := playliststate.StatusSucceeded
+	if !result.Success && result.ErrorKind.Permanent() {
+		status = playliststate.StatusUnavailable
+		err = stateMgr.MarkUnavailable(key, result.Title, result.Error, string(result.ErrorKind))
+	} else {
+		if !result.Success {
+			status = playliststate.StatusFailed
+		} else {
+			stateMgr.SetMetadata(key, playliststate.Metadata{Duration: result.Duration, UploadDate: result.UploadDate,
+				Thumbnail: result.Thumbnail, Description: result.Description})
 		}
-		results = append(results, result)
+		err = stateMgr.MarkFinished(key, result.Title, result.Filename, result.Subtitles, result.Success, result.Error, string(result.ErrorKind))
 	}
-	return results
-}
-
-// download is the internal implementation that runs yt-dlp.
-func (d *Downloader) download(url, outDir string) DownloadResult {
-	bin, err := d.resolveYTDLPBin()
+	d.saveState(key, status, err)
 	if err != nil {
-		return DownloadResult{URL: url, Success: false, Error: err.Error()}
+		result.Success = false
+		result.Error = fmt.Sprintf("download finished but failed to save playlist state: %v", err)
 	}
-
-	if err := os.MkdirAll(outDir, 0o755); err != nil {
-		return DownloadResult{URL: url, Success: false, Error: fmt.Sprintf("cannot create output dir: %v", err)}
+	if result.Success {
+		d.addToArchive(result.VideoID, entryURL)
 	}
+	return result
+}
 
-	args := d.buildArgs(url, outDir)
-	cmd := exec.Command(bin, args...)
-	cmd.Dir = outDir
+// entrySelection returns a predicate over 1-based playlist index and state key,
+// or nil when neither Items nor SelectedEntries restricts the run.
+func (d *Downloader) entrySelection() (func(index int, key string) bool, error) {
+	items := strings.TrimSpace(d.opts.Items)
+	if items == "" && len(d.opts.SelectedEntries) == 0 {
+		return nil, nil
+	}
+	var set ItemSet
+	if items != "" {
+		var err error
+		if set, err = ParseItems(items); err != nil {
+			return nil, err
+		}
+	}
+	keys := make(map[string]bool, len(d.opts.SelectedEntries))
+	for _, key := range d.opts.SelectedEntries {
+		keys[key] = true
+	}
+	return func(index int, key string) bool {
+		if keys[key] {
+			return true
+		}
+		return items != "" && index > 0 && set.Contains(index)
+	}, nil
+}
 
-	stdout, err := cmd.StdoutPipe()
+// download is the internal implementation that hands one URL to a backend.
+// Progress updates use key and title when given; an empty key keys them by
+// video instead, for runs where one yt-dlp process fetches several videos.
+// Backends that support it start the file names with filePrefix.
+func (d *Downloader) download(url, outDir, key, title, filePrefix string) DownloadResult {
+	backend, err := d.backendFor(url)
 	if err != nil {
-		return DownloadResult{URL: url, Success: false, Error: err.Error()}
+		return DownloadResult{URL: url, Success: false, Error: err.Error(), ErrorKind: ErrorBinary}
 	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return DownloadResult{URL: url, Success: false, Error: err.Error()}
+	if p, ok := backend.(filePrefixer); ok && filePrefix != "" {
+		backend = p.withFilePrefix(filePrefix)
+	} else {
+		filePrefix = ""
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return DownloadResult{URL: url, Success: false, Error: fmt.Sprintf("cannot create output dir: %v", err), ErrorKind: ErrorDisk}
 	}
 
 	result := DownloadResult{
+		Key:       key,
 		URL:       url,
 		OutputDir: outDir,
 		StartedAt: time.Now(),
 	}
 
-	requestKey := url
-	if d.progress != nil {
-		d.progress <- ProgressUpdate{
-			Key:     requestKey,
-			Title:   url,
-			Status:  "starting",
-			Percent: 0,
+	var info VideoInfo
+	var totalBytes int64
+
+	// report fills in the identifying fields every update for this item shares.
+	report := func(u ProgressUpdate) {
+		u.VideoID = info.ID
+		u.Title = info.Title
+		if u.Title == "" {
+			u.Title = title
 		}
+		u.Key = key
+		if u.Key == "" {
+			u.Key = progressKey(url, info.ID, info.Title)
+		}
+		if u.Title == "" {
+			u.Title = url
+		}
+		u.OutputDir = outDir
+		u.TotalBytes = totalBytes
+		d.emit(u)
 	}
 
-	if err := cmd.Start(); err != nil {
-		result.Error = err.Error()
-		return result
+	report(ProgressUpdate{Status: "starting", Percent: 0})
+
+	controlKey := key
+	if controlKey == "" {
+		controlKey = url
+	}
+	var itemLog io.Writer = io.Discard
+	if logPath, f := d.openItemLog(outDir, key, url); f != nil {
+		defer f.Close()
+		result.LogFile = logPath
+		itemLog = f
+		logHeader(f, url, backend.Name())
 	}
+	d.log().Info("download started", "key", controlKey, "url", url, "backend", backend.Name(), "log", result.LogFile)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	d.control.track(controlKey, stop)
 
-	// Collect stderr for error messages
-	var stderrLines []string
-	go func() {
-		sc := bufio.NewScanner(stderr)
-		for sc.Scan() {
-			stderrLines = append(stderrLines, sc.Text())
+	fetched, err := backend.Download(ctx, url, outDir, itemLog, func(p Progress) {
+		if p.Info != nil {
+			info = *p.Info
 		}
-	}()
-
-	// Read stdout: yt-dlp emits progress lines AND JSON metadata lines
-	var lastJSON VideoInfo
-	sc := bufio.NewScanner(stdout)
-	// Increase buffer size for large JSON lines
-	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
-	for sc.Scan() {
-		line := sc.Text()
-
-		// Try to parse as JSON (video metadata from --print-json)
-		if strings.HasPrefix(line, "{") {
-			var info VideoInfo
-			if jsonErr := json.Unmarshal([]byte(line), &info); jsonErr == nil {
-				lastJSON = info
-				if d.progress != nil {
-					d.progress <- ProgressUpdate{
-						Key:     progressKey(requestKey, info.ID, info.Title),
-						VideoID: info.ID,
-						Title:   info.Title,
-						Status:  "merging",
-						Percent: 100,
-					}
-				}
-			}
-			continue
+		if p.TotalBytes > 0 {
+			totalBytes = p.TotalBytes
 		}
-
-		// Parse progress line
-		if m := progressRe.FindStringSubmatch(line); m != nil {
-			var pct float64
-			fmt.Sscanf(m[1], "%f", &pct)
-			if d.progress != nil {
-				d.progress <- ProgressUpdate{
-					Key:     progressKey(requestKey, lastJSON.ID, lastJSON.Title),
-					VideoID: lastJSON.ID,
-					Title:   lastJSON.Title,
-					Percent: pct,
-					Speed:   m[2],
-					ETA:     m[3],
-					Status:  "downloading",
-				}
-			}
+		report(ProgressUpdate{Status: p.Status, Percent: p.Percent, Speed: p.Speed, ETA: p.ETA})
+	})
+	result.FinishedAt = time.Now()
+	if fetched.Info.ID != "" || fetched.Info.Title != "" {
+		info = fetched.Info
+	}
+
+	if result.stopped = d.control.untrack(controlKey); result.stopped != "" {
+		fmt.Fprintf(itemLog, "=== stopped: %s\n", result.stopped)
+		d.log().Info("download stopped", "key", controlKey, "action", result.stopped)
+		result.Key = controlKey
+		if result.stopped == CommandCancel {
+			result.Skipped = true
+			result.Error = cancelledMessage
+			report(ProgressUpdate{Status: "skipped", Error: cancelledMessage})
+		} else {
+			report(ProgressUpdate{Status: "paused", Percent: 0})
 		}
+		return result
 	}
 
-	cmdErr := cmd.Wait()
-	result.FinishedAt = time.Now()
-
-	if cmdErr != nil {
+	if err != nil {
 		result.Success = false
-		errMsg := cmdErr.Error()
-		if len(stderrLines) > 0 {
-			errMsg = strings.Join(stderrLines, "; ")
-		}
-		result.Error = errMsg
-		if d.progress != nil {
-			d.progress <- ProgressUpdate{
-				Key:     progressKey(requestKey, lastJSON.ID, lastJSON.Title),
-				VideoID: lastJSON.ID,
-				Title:   lastJSON.Title,
-				Status:  "error",
-				Error:   errMsg,
-			}
+		result.Stderr = strings.Join(fetched.Stderr, "\n")
+		var kindErr *KindError
+		if errors.As(err, &kindErr) {
+			result.ErrorKind = kindErr.Kind
+			result.Error = fmt.Sprintf("%s: %v", kindErr.Kind.Message(), kindErr.Err)
+		} else {
+			result.ErrorKind = ClassifyError(result.Stderr)
+			result.Error = errorSummary(result.ErrorKind, fetched.Stderr, err.Error())
 		}
+		fmt.Fprintf(itemLog, "=== failed (%s): %v\n", result.ErrorKind, err)
+		d.log().Warn("download failed", "key", controlKey, "kind", result.ErrorKind, "err", result.Error,
+			"took", result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond))
+		report(ProgressUpdate{Status: "error", Error: result.Error, ErrorKind: result.ErrorKind})
 		return result
 	}
 
 	result.Success = true
-	result.VideoID = lastJSON.ID
-	result.Title = lastJSON.Title
-	if lastJSON.Title != "" {
+	result.VideoID = info.ID
+	result.Title = info.Title
+	result.FormatID = info.FormatID
+	result.Resolution = info.Resolution
+	result.VCodec = info.VCodec
+	result.ACodec = info.ACodec
+	result.Duration = info.Duration
+	result.UploadDate = info.UploadDate
+	result.Thumbnail = info.Thumbnail
+	result.Description = info.Description
+	result.Filename = info.Filename
+	if result.Filename == "" && info.Title != "" {
 		// Reconstruct expected filename
 		ext := strings.TrimSpace(d.opts.Format)
 		if ext == "" {
-			ext = strings.TrimSpace(lastJSON.Ext)
+			ext = strings.TrimSpace(info.Ext)
 		}
 		if ext != "" {
 			result.Filename = filepath.Join(outDir,
-				sanitizeFilename(lastJSON.Title)+"."+ext)
+				filePrefix+sanitizeFilename(info.Title)+"."+ext)
 		}
 	}
 
 	// Collect subtitle files
-	result.Subtitles = collectSubtitleFiles(outDir, lastJSON.Title)
-
-	if d.progress != nil {
-		d.progress <- ProgressUpdate{
-			Key:     progressKey(requestKey, lastJSON.ID, lastJSON.Title),
-			VideoID: lastJSON.ID,
-			Title:   lastJSON.Title,
-			Status:  "done",
-			Percent: 100,
-		}
-	}
+	result.Subtitles = collectSubtitleFiles(outDir, filePrefix, info.Title)
+	fmt.Fprintf(itemLog, "=== done: %s\n", result.Filename)
+	d.log().Info("download finished", "key", controlKey, "file", result.Filename,
+		"took", result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond))
+
+	report(ProgressUpdate{
+		Status:    "done",
+		Percent:   100,
+		Filename:  result.Filename,
+		Subtitles: result.Subtitles,
+	})
 
 	return result
 }
 
-// fetchPlaylistMetadata uses yt-dlp --dump-single-json to get playlist metadata.
-func (d *Downloader) fetchPlaylistMetadata(url string) (playlistMetadata, error) {
-	bin, err := d.resolveYTDLPBin()
-	if err != nil {
-		return playlistMetadata{}, err
-	}
-	out, err := exec.Command(bin, "--dump-single-json", "--flat-playlist", url).Output()
-	if err != nil {
-		return playlistMetadata{}, err
-	}
-	var meta playlistMetadata
-	if err := json.Unmarshal(out, &meta); err != nil {
-		return playlistMetadata{}, err
+// emit forwards an update to the bus or progress channel, if any.
+func (d *Downloader) emit(u ProgressUpdate) {
+	if d.bus != nil {
+		d.bus.Publish(u)
+	} else if d.progress != nil {
+		d.progress <- u
 	}
-	return meta, nil
 }
 
-// collectSubtitleFiles globs for subtitle files that match a video title in dir.
-func collectSubtitleFiles(dir, title string) []string {
+// collectSubtitleFiles globs for subtitle files that match a video title in
+// dir, named starting with prefix.
+func collectSubtitleFiles(dir, prefix, title string) []string {
 	if title == "" {
 		return nil
 	}
-	pattern := filepath.Join(dir, sanitizeFilename(title)+"*.vtt")
+	pattern := filepath.Join(dir, prefix+sanitizeFilename(title)+"*.vtt")
 	matches, _ := filepath.Glob(pattern)
-	pattern2 := filepath.Join(dir, sanitizeFilename(title)+"*.srt")
+	pattern2 := filepath.Join(dir, prefix+sanitizeFilename(title)+"*.srt")
 	m2, _ := filepath.Glob(pattern2)
 	return append(matches, m2...)
 }
 
+// indexPrefixWidth is how many digits Options.IndexPrefix pads the playlist
+// positions of rawURL to, or 0 without a prefix. Feeds get none, with a
+// warning saying why.
+func (d *Downloader) indexPrefixWidth(rawURL, title string, entries int) (int, string) {
+	switch {
+	case !d.opts.IndexPrefix:
+		return 0, ""
+	case d.mergeOptions(rawURL).Partial:
+		return 0, fmt.Sprintf("playlist %q: index prefix not used, positions in a feed change with every upload", title)
+	}
+	// YouTube playlists hold at most 5000 videos, so four digits keep the
+	// names in one folder the same width as it grows.
+	return max(4, len(strconv.Itoa(entries))), ""
+}
+
+// indexPrefix is the start of the file names of the entry at a playlist
+// position, or "" when width or the position is 0.
+func indexPrefix(width, index int) string {
+	if width == 0 || index <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%0*d - ", width, index)
+}
+
 // sanitizeDirName makes a string safe for use as a directory name.
 func sanitizeDirName(s string) string {
 	re := regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
@@ -486,6 +724,18 @@ func sanitizeDirName(s string) string {
 	return s
 }
 
+// PlaylistDir returns the directory DownloadPlaylist uses for a playlist title.
+func (d *Downloader) PlaylistDir(title, url string) string {
+	return filepath.Join(d.opts.OutputDir, playlistDirName(title, normalizeURL(url)))
+}
+
+func playlistDirName(title, url string) string {
+	if name := sanitizeDirName(title); name != "" {
+		return name
+	}
+	return sanitizeDirName(url)
+}
+
 // sanitizeFilename removes characters unsafe for filenames.
 func sanitizeFilename(s string) string {
 	return sanitizeDirName(s)
@@ -520,21 +770,6 @@ func playlistStateKey(id, url string) string {
 	return url
 }
 
-func playlistEntryURL(entry playlistEntry) string {
-	switch {
-	case entry.WebpageURL != "":
-		return entry.WebpageURL
-	case strings.HasPrefix(entry.URL, "http://"), strings.HasPrefix(entry.URL, "https://"):
-		return entry.URL
-	case entry.ID != "":
-		return "https://www.youtube.com/watch?v=" + entry.ID
-	case entry.URL != "":
-		return "https://www.youtube.com/watch?v=" + entry.URL
-	default:
-		return ""
-	}
-}
-
 func (d *Downloader) resolveYTDLPBin() (string, error) {
 	if path := strings.TrimSpace(d.opts.YTDLPBin); path != "" {
 		return path, nil
@@ -0,0 +1,150 @@
+package downloader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrorKind is a stable classification of why a download failed. The string
+// values are written to state and record files, so they must not change.
+type ErrorKind string
+
+const (
+	ErrorNone          ErrorKind = ""
+	ErrorPrivate       ErrorKind = "private"
+	ErrorUnavailable   ErrorKind = "unavailable" // deleted, removed or terminated
+	ErrorMembersOnly   ErrorKind = "members_only"
+	ErrorAgeRestricted ErrorKind = "age_restricted"
+	ErrorGeoBlocked    ErrorKind = "geo_blocked"
+	ErrorRateLimited   ErrorKind = "rate_limited" // HTTP 429
+	ErrorBotCheck      ErrorKind = "bot_check"    // "Sign in to confirm you're not a bot"
+	ErrorNetwork       ErrorKind = "network"
+	ErrorFFmpeg        ErrorKind = "ffmpeg"
+	ErrorFormat        ErrorKind = "format"
+	ErrorDisk          ErrorKind = "disk"
+	ErrorBinary        ErrorKind = "binary" // yt-dlp missing or not executable
+	ErrorUnknown       ErrorKind = "unknown"
+)
+
+// ErrorKinds lists every kind a failure may be classified as.
+var ErrorKinds = []ErrorKind{
+	ErrorPrivate, ErrorUnavailable, ErrorMembersOnly, ErrorAgeRestricted,
+	ErrorGeoBlocked, ErrorRateLimited, ErrorBotCheck, ErrorNetwork,
+	ErrorFFmpeg, ErrorFormat, ErrorDisk, ErrorBinary, ErrorUnknown,
+}
+
+var errorMessages = map[ErrorKind]string{
+	ErrorPrivate:       "video is private",
+	ErrorUnavailable:   "video is unavailable or was removed",
+	ErrorMembersOnly:   "video is for channel members only",
+	ErrorAgeRestricted: "video is age-restricted; cookies are required",
+	ErrorGeoBlocked:    "video is not available in your country",
+	ErrorRateLimited:   "rate limited by the site (HTTP 429)",
+	ErrorBotCheck:      "site asked to confirm you are not a bot; cookies are required",
+	ErrorNetwork:       "network error",
+	ErrorFFmpeg:        "ffmpeg is missing or failed",
+	ErrorFormat:        "requested format is not available",
+	ErrorDisk:          "cannot write output file",
+	ErrorBinary:        "yt-dlp could not be run",
+}
+
+// Message returns a short human description of the kind.
+func (k ErrorKind) Message() string {
+	if msg, ok := errorMessages[k]; ok {
+		return msg
+	}
+	return "download failed"
+}
+
+// errorPatterns are checked in order; the first match wins. More specific
+// patterns come before the generic ones they would otherwise fall under.
+var errorPatterns = []struct {
+	kind ErrorKind
+	re   *regexp.Regexp
+}{
+	{ErrorBotCheck, regexp.MustCompile(`(?i)confirm you.?re not a bot`)},
+	{ErrorRateLimited, regexp.MustCompile(`(?i)HTTP Error 429|Too Many Requests`)},
+	{ErrorPrivate, regexp.MustCompile(`(?i)private video|video is private`)},
+	{ErrorMembersOnly, regexp.MustCompile(`(?i)members[- ]only|join this channel`)},
+	{ErrorAgeRestricted, regexp.MustCompile(`(?i)age[- ]restricted|confirm your age|inappropriate for some users`)},
+	{ErrorGeoBlocked, regexp.MustCompile(`(?i)available in your country|geo[- ]?restrict|blocked it in your country`)},
+	{ErrorUnavailable, regexp.MustCompile(`(?i)video unavailable|has been removed|account .* terminated|no longer available|(video|playlist|channel|user) does not exist|HTTP Error 404|HTTP Error 410`)},
+	{ErrorFFmpeg, regexp.MustCompile(`(?i)ffmpeg|ffprobe|postprocessing`)},
+	{ErrorFormat, regexp.MustCompile(`(?i)requested format is not available|no video formats found`)},
+	{ErrorDisk, regexp.MustCompile(`(?i)no space left|permission denied|read-only file system|unable to open for writing`)},
+	{ErrorNetwork, regexp.MustCompile(`(?i)timed out|timeout|connection (reset|refused|aborted)|network is unreachable|name or service not known|temporary failure in name resolution|getaddrinfo failed|unable to download webpage|\[SSL: |ssl error|HTTP Error 5\d\d|IncompleteRead`)},
+}
+
+// ClassifyError maps yt-dlp's stderr to an ErrorKind. The ERROR lines are
+// classified first, so a WARNING such as "ffmpeg not found" does not hide
+// the failure that actually ended the download; the whole text is only
+// consulted when they match nothing. Text that matches no known pattern is
+// ErrorUnknown.
+func ClassifyError(stderr string) ErrorKind {
+	var errorLines []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "ERROR:") {
+			errorLines = append(errorLines, line)
+		}
+	}
+	if kind := classify(strings.Join(errorLines, "\n")); kind != ErrorUnknown {
+		return kind
+	}
+	return classify(stderr)
+}
+
+func classify(text string) ErrorKind {
+	if text == "" {
+		return ErrorUnknown
+	}
+	for _, p := range errorPatterns {
+		if p.re.MatchString(text) {
+			return p.kind
+		}
+	}
+	return ErrorUnknown
+}
+
+// errorSummary returns the short message shown for a failure: the kind's
+// description, or yt-dlp's own ERROR line when the kind is unknown.
+func errorSummary(kind ErrorKind, stderrLines []string, fallback string) string {
+	if kind != ErrorUnknown {
+		return kind.Message()
+	}
+	for i := len(stderrLines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(stderrLines[i]); strings.HasPrefix(line, "ERROR:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "ERROR:"))
+		}
+	}
+	if len(stderrLines) > 0 {
+		return strings.TrimSpace(stderrLines[len(stderrLines)-1])
+	}
+	return fallback
+}
+
+// ParseErrorKinds parses a comma-separated list such as "network,rate_limited".
+func ParseErrorKinds(spec string) ([]ErrorKind, error) {
+	var kinds []ErrorKind
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		kind := ErrorKind(part)
+		if !kind.known() {
+			return nil, fmt.Errorf("unknown error kind %q", part)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+func (k ErrorKind) known() bool {
+	for _, kind := range ErrorKinds {
+		if kind == k {
+			return true
+		}
+	}
+	return false
+}
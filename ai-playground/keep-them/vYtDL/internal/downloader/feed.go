@@ -0,0 +1,121 @@
+package downloader
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/innate/yt-dl/internal/feed"
+)
+
+// listsFromFeed reports whether a playlist URL is listed from a feed.
+func (d *Downloader) listsFromFeed(rawURL string) bool {
+	return d.opts.Feed || feed.IsFeedURL(rawURL)
+}
+
+// fetchFeed lists the entries of an Atom or RSS feed as a playlist.
+func (d *Downloader) fetchFeed(ctx context.Context, src string) (PlaylistInfo, error) {
+	var f feed.Feed
+	if path, ok := feed.LocalPath(src); ok {
+		var err error
+		if f, err = feed.Load(path); err != nil {
+			return PlaylistInfo{}, err
+		}
+	} else {
+		b := newHTTPBackend(d.opts)
+		req, err := b.request(ctx, http.MethodGet, src)
+		if err != nil {
+			return PlaylistInfo{}, err
+		}
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return PlaylistInfo{}, &KindError{Kind: ErrorNetwork, Err: err}
+		}
+		defer resp.Body.Close()
+		if err := statusError(resp); err != nil {
+			return PlaylistInfo{}, err
+		}
+		if f, err = feed.Parse(resp.Body); err != nil {
+			return PlaylistInfo{}, err
+		}
+	}
+	info := PlaylistInfo{Title: f.Title}
+	for _, e := range f.Entries {
+		info.Entries = append(info.Entries, PlaylistEntry{ID: e.ID, Title: e.Title, URL: e.URL})
+	}
+	d.log().Info("feed listed", "feed", src, "entries", len(info.Entries))
+	return info, nil
+}
+
+// archive is a download archive loaded for one run.
+type archive map[string]bool
+
+// loadArchive reads Options.Archive. A missing file is an empty archive.
+func (d *Downloader) loadArchive() (archive, error) {
+	path := strings.TrimSpace(d.opts.Archive)
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return archive{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read download archive: %w", err)
+	}
+	defer f.Close()
+	a := archive{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		// yt-dlp writes "<extractor> <id>"; the ID alone is enough to match.
+		fields := strings.Fields(sc.Text())
+		if len(fields) > 0 {
+			a[fields[len(fields)-1]] = true
+		}
+	}
+	return a, sc.Err()
+}
+
+// has reports whether the video ID is in the archive.
+func (a archive) has(id string) bool {
+	return id != "" && a[id]
+}
+
+// addToArchive appends a downloaded video to Options.Archive.
+func (d *Downloader) addToArchive(id, rawURL string) {
+	path := strings.TrimSpace(d.opts.Archive)
+	if path == "" || id == "" {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err == nil {
+		_, err = fmt.Fprintf(f, "%s %s\n", archiveExtractor(rawURL), id)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		d.log().Warn("download archive not updated", "id", id, "err", err)
+	}
+}
+
+// archiveExtractor names the yt-dlp extractor for a URL, so the archive stays
+// usable with yt-dlp's own --download-archive.
+func archiveExtractor(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "generic"
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch {
+	case host == "youtube.com", host == "m.youtube.com", host == "youtu.be":
+		return "youtube"
+	case host == "vimeo.com":
+		return "vimeo"
+	}
+	return "generic"
+}
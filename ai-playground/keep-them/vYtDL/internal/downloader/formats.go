@@ -0,0 +1,255 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Format describes one downloadable stream reported by yt-dlp.
+type Format struct {
+	ID             string  `json:"format_id"`
+	Ext            string  `json:"ext"`
+	Note           string  `json:"format_note,omitempty"`
+	Width          int     `json:"width,omitempty"`
+	Height         int     `json:"height,omitempty"`
+	FPS            float64 `json:"fps,omitempty"`
+	VCodec         string  `json:"vcodec,omitempty"`
+	ACodec         string  `json:"acodec,omitempty"`
+	TBR            float64 `json:"tbr,omitempty"`
+	Filesize       int64   `json:"filesize,omitempty"`
+	FilesizeApprox int64   `json:"filesize_approx,omitempty"`
+	DynamicRange   string  `json:"dynamic_range,omitempty"`
+}
+
+// HasVideo reports whether the format carries a video stream.
+func (f Format) HasVideo() bool {
+	return f.VCodec != "" && f.VCodec != "none"
+}
+
+// HasAudio reports whether the format carries an audio stream.
+func (f Format) HasAudio() bool {
+	return f.ACodec != "" && f.ACodec != "none"
+}
+
+// Kind returns "video+audio", "video", "audio" or "other".
+func (f Format) Kind() string {
+	switch {
+	case f.HasVideo() && f.HasAudio():
+		return "video+audio"
+	case f.HasVideo():
+		return "video"
+	case f.HasAudio():
+		return "audio"
+	default:
+		return "other"
+	}
+}
+
+// Resolution returns a WxH label, or "audio only" for audio streams.
+func (f Format) Resolution() string {
+	switch {
+	case f.Width > 0 && f.Height > 0:
+		return fmt.Sprintf("%dx%d", f.Width, f.Height)
+	case f.Height > 0:
+		return fmt.Sprintf("%dp", f.Height)
+	case f.HasAudio() && !f.HasVideo():
+		return "audio only"
+	default:
+		return ""
+	}
+}
+
+// Size returns the exact file size when known, otherwise yt-dlp's estimate.
+func (f Format) Size() int64 {
+	if f.Filesize > 0 {
+		return f.Filesize
+	}
+	return f.FilesizeApprox
+}
+
+// FormatList is the format table of a single video.
+type FormatList struct {
+	VideoID  string   `json:"id"`
+	Title    string   `json:"title"`
+	Duration float64  `json:"duration,omitempty"`
+	Formats  []Format `json:"formats"`
+}
+
+// FetchFormats asks the backend for the available formats of a single video.
+func (d *Downloader) FetchFormats(url string) (FormatList, error) {
+	return d.FetchFormatsContext(context.Background(), url)
+}
+
+// FetchFormatsContext is FetchFormats, stopping the probe when ctx ends.
+func (d *Downloader) FetchFormatsContext(ctx context.Context, url string) (FormatList, error) {
+	url = normalizeURL(url)
+	backend, err := d.backendFor(url)
+	if err != nil {
+		return FormatList{}, err
+	}
+	return backend.Probe(ctx, url)
+}
+
+// ParseFormatList decodes the formats section of yt-dlp JSON metadata.
+func ParseFormatList(data []byte) (FormatList, error) {
+	var list FormatList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return FormatList{}, fmt.Errorf("parse format metadata: %w", err)
+	}
+	// yt-dlp lists storyboards and other image-only tracks as formats.
+	kept := list.Formats[:0]
+	for _, f := range list.Formats {
+		if f.Kind() == "other" {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	list.Formats = kept
+	return list, nil
+}
+
+// FormatSelector combines a video and an audio format id into a yt-dlp -f value.
+// Either id may be empty when the other already carries both streams.
+func FormatSelector(videoID, audioID string) string {
+	videoID = strings.TrimSpace(videoID)
+	audioID = strings.TrimSpace(audioID)
+	switch {
+	case videoID != "" && audioID != "":
+		return videoID + "+" + audioID
+	case videoID != "":
+		return videoID
+	default:
+		return audioID
+	}
+}
+
+// codecPrefixes maps user-facing codec names to the prefixes yt-dlp reports in
+// the vcodec/acodec fields. Unknown names are matched literally.
+var codecPrefixes = map[string][]string{
+	"h264":   {"avc1"},
+	"avc":    {"avc1"},
+	"avc1":   {"avc1"},
+	"h265":   {"hvc1", "hev1"},
+	"hevc":   {"hvc1", "hev1"},
+	"vp9":    {"vp09", "vp9"},
+	"vp09":   {"vp09", "vp9"},
+	"av1":    {"av01"},
+	"av01":   {"av01"},
+	"aac":    {"mp4a"},
+	"m4a":    {"mp4a"},
+	"mp4a":   {"mp4a"},
+	"opus":   {"opus"},
+	"mp3":    {"mp3"},
+	"vorbis": {"vorbis"},
+}
+
+func codecMatchers(names []string) []string {
+	var prefixes []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		candidates, ok := codecPrefixes[name]
+		if !ok {
+			candidates = []string{name}
+		}
+		for _, prefix := range candidates {
+			if !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	return prefixes
+}
+
+// formatSelector builds the -f value from Quality and the codec, fps, HDR and
+// size constraints. Preferred codecs become ordered alternatives; everything
+// else becomes filters shared by all of them.
+func formatSelector(o Options) string {
+	var videoFilter, audioFilter, avoidAudio string
+	if o.Quality != "" && o.Quality != "bestvideo+bestaudio" {
+		videoFilter += fmt.Sprintf("[height<=%s]", strings.TrimSuffix(o.Quality, "p"))
+	}
+	if fps := strings.TrimSpace(o.MaxFPS); fps != "" {
+		videoFilter += fmt.Sprintf("[fps<=?%s]", fps)
+	}
+	if o.NoHDR {
+		// yt-dlp reports HDR10, HDR12, HLG and DV (Dolby Vision) besides
+		// SDR, so only SDR is let through. Formats without the field pass.
+		videoFilter += "[dynamic_range=?SDR]"
+	}
+	for _, prefix := range codecMatchers(o.AvoidVideoCodecs) {
+		videoFilter += fmt.Sprintf("[vcodec!^=%s]", prefix)
+	}
+	for _, prefix := range codecMatchers(o.AvoidAudioCodecs) {
+		avoidAudio += fmt.Sprintf("[acodec!^=%s]", prefix)
+	}
+	audioFilter += avoidAudio
+	if size := strings.TrimSpace(o.MaxFilesize); size != "" {
+		sizeFilter := fmt.Sprintf("[filesize<?%s][filesize_approx<?%s]", size, size)
+		videoFilter += sizeFilter
+		audioFilter += sizeFilter
+	}
+
+	preferVideo := codecMatchers(o.PreferVideoCodecs)
+	preferAudio := codecMatchers(o.PreferAudioCodecs)
+
+	var alternatives []string
+	if len(preferVideo) > 0 || len(preferAudio) > 0 {
+		videoPrefs := []string{""}
+		if len(preferVideo) > 0 {
+			videoPrefs = preferVideo
+		}
+		audioPrefs := []string{""}
+		if len(preferAudio) > 0 {
+			audioPrefs = preferAudio
+		}
+		for _, pv := range videoPrefs {
+			for _, pa := range audioPrefs {
+				v := "bestvideo" + videoFilter
+				if pv != "" {
+					v += fmt.Sprintf("[vcodec^=%s]", pv)
+				}
+				a := "bestaudio" + audioFilter
+				if pa != "" {
+					a += fmt.Sprintf("[acodec^=%s]", pa)
+				}
+				alternatives = append(alternatives, v+"+"+a)
+			}
+		}
+	}
+	alternatives = append(alternatives,
+		"bestvideo"+videoFilter+"+bestaudio"+audioFilter,
+		"best"+videoFilter+avoidAudio,
+	)
+	return strings.Join(alternatives, "/")
+}
+
+// ParseByteSize parses sizes as printed by yt-dlp, e.g. "100.00MiB", "~1.2GiB"
+// or "512KiB/s". It returns 0 for anything it cannot read.
+func ParseByteSize(s string) int64 {
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "~"), "/s")
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"B", 1},
+	}
+	for _, u := range units {
+		if num, ok := strings.CutSuffix(s, u.suffix); ok {
+			var v float64
+			if _, err := fmt.Sscanf(num, "%g", &v); err != nil {
+				return 0
+			}
+			return int64(v * u.factor)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// defaultHookTimeout bounds a hook when Options.HookTimeout is zero.
+const defaultHookTimeout = 30 * time.Second
+
+// HookPayload is the JSON document sent to --webhook and written to the
+// stdin of --exec-after for every finished item.
+type HookPayload struct {
+	Event      string        `json:"event"` // "download.succeeded" or "download.failed"
+	Key        string        `json:"key"`
+	VideoID    string        `json:"video_id"`
+	Title      string        `json:"title"`
+	URL        string        `json:"url"`
+	OutputDir  string        `json:"output_dir"`
+	Filename   string        `json:"filename,omitempty"`
+	Subtitles  []string      `json:"subtitles"`
+	FormatID   string        `json:"format_id,omitempty"`
+	Resolution string        `json:"resolution,omitempty"`
+	VCodec     string        `json:"vcodec,omitempty"`
+	ACodec     string        `json:"acodec,omitempty"`
+	Success    bool          `json:"success"`
+	Error      string        `json:"error,omitempty"`
+	ErrorKind  ErrorKind     `json:"error_kind,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	LogFile    string        `json:"log_file,omitempty"`
+	Playlist   *HookPlaylist `json:"playlist,omitempty"`
+}
+
+// HookPlaylist places an item within its playlist.
+type HookPlaylist struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Dir   string `json:"dir"`
+	Index int    `json:"index"` // 1-based position; 0 if the entry left the playlist
+	Count int    `json:"count"`
+}
+
+// NewHookPayload describes result for hooks. playlist is nil for single videos.
+func NewHookPayload(result DownloadResult, playlist *HookPlaylist) HookPayload {
+	event := "download.succeeded"
+	if !result.Success {
+		event = "download.failed"
+	}
+	subs := result.Subtitles
+	if subs == nil {
+		subs = []string{}
+	}
+	return HookPayload{
+		Event:      event,
+		Key:        result.Key,
+		VideoID:    result.VideoID,
+		Title:      result.Title,
+		URL:        result.URL,
+		OutputDir:  result.OutputDir,
+		Filename:   result.Filename,
+		Subtitles:  subs,
+		FormatID:   result.FormatID,
+		Resolution: result.Resolution,
+		VCodec:     result.VCodec,
+		ACodec:     result.ACodec,
+		Success:    result.Success,
+		Error:      result.Error,
+		ErrorKind:  result.ErrorKind,
+		StartedAt:  result.StartedAt,
+		FinishedAt: result.FinishedAt,
+		LogFile:    result.LogFile,
+		Playlist:   playlist,
+	}
+}
+
+// finish runs the post-download hooks for a finished item and records their
+// failures in HookError. Cancelled and paused items are not finished.
+func (d *Downloader) finish(result DownloadResult, playlist *HookPlaylist) DownloadResult {
+	if result.Skipped || result.stopped != "" {
+		return result
+	}
+	command := strings.TrimSpace(d.opts.ExecAfter)
+	webhook := strings.TrimSpace(d.opts.Webhook)
+	if command == "" && webhook == "" {
+		return result
+	}
+	payload, err := json.Marshal(NewHookPayload(result, playlist))
+	if err != nil {
+		result.HookError = err.Error()
+		return result
+	}
+
+	var failures []string
+	if command != "" {
+		if err := d.runExecHook(command, result, payload); err != nil {
+			failures = append(failures, "exec-after: "+err.Error())
+		}
+	}
+	if webhook != "" {
+		if err := d.postWebhook(webhook, payload); err != nil {
+			failures = append(failures, "webhook: "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		result.HookError = strings.Join(failures, "; ")
+		d.log().Warn("post-download hook failed", "key", result.Key, "err", result.HookError)
+		// A status of its own, so consumers counting "done" and "error" see
+		// each item finish once; views keep the item's final status.
+		d.emit(ProgressUpdate{Key: result.Key, VideoID: result.VideoID, Title: result.Title,
+			Status: "hook_failed", HookError: result.HookError})
+	}
+	return result
+}
+
+func (d *Downloader) hookTimeout() time.Duration {
+	if d.opts.HookTimeout > 0 {
+		return d.opts.HookTimeout
+	}
+	return defaultHookTimeout
+}
+
+// runExecHook runs command through the shell with the placeholders filled in
+// and the payload on stdin.
+func (d *Downloader) runExecHook(command string, result DownloadResult, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.hookTimeout())
+	defer cancel()
+
+	command = ExpandHookCommand(command, result)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = result.OutputDir
+	// Children of the shell may keep stderr open after it is killed.
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %s", d.hookTimeout())
+	case stderr.Len() > 0:
+		return fmt.Errorf("%v: %s", err, lastLine(stderr.String()))
+	}
+	return err
+}
+
+// postWebhook POSTs the payload and expects a 2xx answer.
+func (d *Downloader) postWebhook(url string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.hookTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %s", resp.Status)
+	}
+	return nil
+}
+
+// ExpandHookCommand replaces {filepath}, {dir}, {title}, {id} and {url} in
+// command with shell-quoted values from result.
+func ExpandHookCommand(command string, result DownloadResult) string {
+	return strings.NewReplacer(
+		"{filepath}", shellQuote(result.Filename),
+		"{dir}", shellQuote(result.OutputDir),
+		"{title}", shellQuote(result.Title),
+		"{id}", shellQuote(result.VideoID),
+		"{url}", shellQuote(result.URL),
+	).Replace(command)
+}
+
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,57 @@
+package downloader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ItemSet is a parsed --items selection such as "1-10,15,20-". Indexes are
+// 1-based playlist positions; open-ended ranges run to the end of the playlist.
+type ItemSet struct {
+	ranges [][2]int // inclusive; 0 as the upper bound means "to the end"
+}
+
+// ParseItems parses a comma-separated list of indexes and ranges.
+func ParseItems(spec string) (ItemSet, error) {
+	var set ItemSet
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, end := 1, 0
+		var err error
+		if lo = strings.TrimSpace(lo); lo != "" {
+			if start, err = strconv.Atoi(lo); err != nil || start < 1 {
+				return ItemSet{}, fmt.Errorf("invalid item %q: indexes start at 1", part)
+			}
+		} else if !isRange {
+			return ItemSet{}, fmt.Errorf("invalid item %q", part)
+		}
+		switch hi = strings.TrimSpace(hi); {
+		case !isRange:
+			end = start
+		case hi != "":
+			if end, err = strconv.Atoi(hi); err != nil || end < start {
+				return ItemSet{}, fmt.Errorf("invalid item range %q", part)
+			}
+		}
+		set.ranges = append(set.ranges, [2]int{start, end})
+	}
+	if len(set.ranges) == 0 {
+		return ItemSet{}, fmt.Errorf("empty item selection %q", spec)
+	}
+	return set, nil
+}
+
+// Contains reports whether the 1-based index is selected.
+func (s ItemSet) Contains(index int) bool {
+	for _, r := range s.ranges {
+		if index >= r[0] && (r[1] == 0 || index <= r[1]) {
+			return true
+		}
+	}
+	return false
+}
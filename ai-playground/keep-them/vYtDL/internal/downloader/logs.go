@@ -0,0 +1,113 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogDir is the directory, inside an item's output directory, that holds the
+// per-item tool logs.
+const LogDir = ".logs"
+
+// SetLogger sends the downloader's own decisions (resume skips, retries,
+// state saves) to l. Without one they are discarded.
+func (d *Downloader) SetLogger(l *slog.Logger) {
+	d.logger = l
+}
+
+// SetWarnings calls fn with messages meant for the user, such as options a
+// playlist's saved options replaced. They are logged either way.
+func (d *Downloader) SetWarnings(fn func(string)) {
+	d.warn = fn
+}
+
+func (d *Downloader) warning(msg string) {
+	d.log().Warn(msg)
+	if d.warn != nil {
+		d.warn(msg)
+	}
+}
+
+func (d *Downloader) log() *slog.Logger {
+	if d.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return d.logger
+}
+
+// saveState logs the outcome of a playlist state update.
+func (d *Downloader) saveState(key, status string, err error) {
+	if err != nil {
+		d.log().Warn("playlist state not saved", "key", key, "status", status, "err", err)
+		return
+	}
+	d.log().Debug("playlist state saved", "key", key, "status", status)
+}
+
+// openItemLog opens the log for one item in outDir/.logs for appending, so
+// every attempt across runs ends up in the same file.
+func (d *Downloader) openItemLog(outDir, key, rawURL string) (string, *os.File) {
+	dir := filepath.Join(outDir, LogDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		d.log().Warn("cannot create log directory", "dir", dir, "err", err)
+		return "", nil
+	}
+	logPath := filepath.Join(dir, itemLogName(key, rawURL)+".log")
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		d.log().Warn("cannot open item log", "path", logPath, "err", err)
+		return "", nil
+	}
+	return logPath, f
+}
+
+// itemLogName names an item's log after its video ID where the key or URL
+// carries one, e.g. "dQw4w9WgXcQ" for a watch URL.
+func itemLogName(key, rawURL string) string {
+	name := key
+	if name == "" {
+		name = rawURL
+	}
+	if u, err := url.Parse(name); err == nil && u.Host != "" {
+		switch {
+		case u.Query().Get("v") != "":
+			name = u.Query().Get("v")
+		case u.Query().Get("list") != "":
+			name = u.Query().Get("list")
+		case path.Base(u.Path) != "/" && path.Base(u.Path) != ".":
+			name = path.Base(u.Path)
+		default:
+			name = u.Host
+		}
+	}
+	name = strings.Trim(sanitizeFilename(name), ". ")
+	if name == "" {
+		return "item"
+	}
+	return name
+}
+
+// logHeader starts an attempt's section in an item log.
+func logHeader(w io.Writer, rawURL, backend string) {
+	fmt.Fprintf(w, "\n=== %s %s (%s)\n", time.Now().Format(time.RFC3339), rawURL, backend)
+}
+
+// lockedWriter serialises writes from the stdout and stderr readers.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) writeLine(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.w, line+"\n")
+}
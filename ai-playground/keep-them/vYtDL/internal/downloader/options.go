@@ -1,5 +1,7 @@
 package downloader
 
+import "time"
+
 // Options holds download configuration for a single video or playlist.
 type Options struct {
 	// URL is the target video or playlist URL.
@@ -11,6 +13,30 @@ type Options struct {
 	// Quality selects the video quality, e.g. "720", "1080". Empty = best.
 	Quality string
 
+	// FormatSelector is an explicit yt-dlp format selector such as "137+140".
+	// When set it takes precedence over Quality.
+	FormatSelector string
+
+	// PreferVideoCodecs / PreferAudioCodecs list codecs to try first, in order,
+	// e.g. ["h264"] or ["aac"]. Other codecs are still used as a fallback.
+	PreferVideoCodecs []string
+	PreferAudioCodecs []string
+
+	// AvoidVideoCodecs / AvoidAudioCodecs list codecs that must never be chosen,
+	// e.g. ["av1"].
+	AvoidVideoCodecs []string
+	AvoidAudioCodecs []string
+
+	// MaxFPS caps the frame rate of the chosen video stream, e.g. "30". Empty = no cap.
+	MaxFPS string
+
+	// NoHDR excludes HDR video streams.
+	NoHDR bool
+
+	// MaxFilesize caps the size of each chosen stream, e.g. "500M". Streams with
+	// unknown sizes are still allowed. Empty = no cap.
+	MaxFilesize string
+
 	// StartTime / EndTime define a time range (HH:MM:SS or seconds).
 	// Both empty means download the full video.
 	StartTime string
@@ -32,6 +58,29 @@ type Options struct {
 	// Each playlist gets its own sub-directory named after the playlist title.
 	IsPlaylist bool
 
+	// Items restricts a playlist run to 1-based positions, e.g. "1-10,15,20-".
+	// Entries left out are recorded as skipped in the playlist state.
+	Items string
+
+	// SelectedEntries restricts a playlist run to these entry keys (video IDs,
+	// or URLs for entries without an ID). Combined with Items as a union.
+	SelectedEntries []string
+
+	// IndexPrefix starts the names of downloaded playlist files with the
+	// entry's playlist position, e.g. "0007 - Title.mp4", so they sort in
+	// playlist order. Feed listings, whose positions change with every
+	// upload, and backends set with SetBackend are not prefixed.
+	IndexPrefix bool
+
+	// Feed lists playlists from an Atom or RSS feed (URL or local file)
+	// instead of asking the download tool. YouTube feed URLs are always read
+	// as feeds.
+	Feed bool
+
+	// Archive is a yt-dlp style download archive ("<extractor> <id>" lines).
+	// Playlist entries listed in it are skipped, and new downloads are added.
+	Archive string
+
 	// RecordFile is the path to the download-log file (.json or .csv).
 	RecordFile string
 
@@ -41,6 +90,16 @@ type Options struct {
 	// LogFormat selects "json" or "csv" for the record / mapping files.
 	LogFormat string
 
+	// Backend picks the download tool: "yt-dlp", "youtube-dl" or "http".
+	// Empty chooses per URL: http for direct media links, otherwise the
+	// tool YTDLPBin points at.
+	Backend string
+
+	// Capabilities of the installed yt-dlp, from DetectCapabilities. When set,
+	// flags it does not list are left out or replaced. Nil assumes a current
+	// yt-dlp.
+	Capabilities *Capabilities
+
 	// YTDLPBin optionally overrides the downloader binary path.
 	YTDLPBin string
 
@@ -68,23 +127,89 @@ type Options struct {
 	// ForceIPv4 forces yt-dlp to use IPv4.
 	ForceIPv4 bool
 
-	// ResetPlaylistState discards any saved playlist resume state before downloading.
+	// MaxRetries re-runs yt-dlp up to this many times within a run after a
+	// transient failure (network, rate limit, unknown). 0 disables retries.
+	MaxRetries int
+
+	// RetryDelay is the backoff before the first retry; it doubles for each
+	// further retry.
+	RetryDelay time.Duration
+
+	// RateLimitCooldown pauses the queue after HTTP 429 or a bot check; the
+	// pause doubles for every further rate limit in a row. 0 disables it, and
+	// rate limits are then retried like network errors.
+	RateLimitCooldown time.Duration
+
+	// MaxCooldowns ends the cooldowns once this many have followed each
+	// other without a successful download; the item then fails. A bot check
+	// gets one cooldown at most, since only cookies fix it. 0 = 3.
+	MaxCooldowns int
+
+	// MaxAttempts stops retrying a playlist entry once its recorded attempts,
+	// across runs, reach this number. 0 = unlimited.
+	MaxAttempts int
+
+	// RetryKinds limits which previously failed playlist entries are retried
+	// on resume. Empty retries every failure.
+	RetryKinds []ErrorKind
+
+	// LimitRate caps download bandwidth, e.g. "2M" or "500K". Empty = unlimited.
+	LimitRate string
+
+	// Window restricts when new downloads may start, e.g. "22:00-07:00".
+	// Running downloads are not interrupted when the window closes.
+	Window string
+
+	// WeekdayWindows overrides Window per day, keyed by mon … sun, with values
+	// like "09:00-17:00,22:00-07:00", "all" or "off".
+	WeekdayWindows map[string]string
+
+	// ExecAfter is a shell command run after each finished item, with
+	// {filepath}, {dir}, {title}, {id} and {url} replaced and the HookPayload
+	// JSON on stdin.
+	ExecAfter string
+
+	// Webhook receives the HookPayload as a JSON POST after each finished item.
+	Webhook string
+
+	// HookTimeout bounds each ExecAfter or Webhook call. 0 = 30s.
+	HookTimeout time.Duration
+
+	// ResetPlaylistState discards any saved playlist resume state before
+	// downloading. The playlist's saved options are replaced as with
+	// OverridePlaylistConfig.
 	ResetPlaylistState bool
+
+	// OverridePlaylistConfig replaces the options saved in a playlist's
+	// .vytdl.yaml with these. Without it a resumed playlist keeps the format,
+	// quality and subtitle options it was started with.
+	OverridePlaylistConfig bool
+
+	// PlaylistConfigKeys names the options, by their key in .vytdl.yaml,
+	// that the caller set on purpose. Only these are compared with a resumed
+	// playlist's saved options and replaced by OverridePlaylistConfig; the
+	// rest take their saved values silently. Nil counts every option as set.
+	PlaylistConfigKeys []string
 }
 
 // DefaultOptions returns sane defaults.
 func DefaultOptions() Options {
 	return Options{
-		Format:         "mp4",
-		Quality:        "bestvideo+bestaudio",
-		OutputDir:      ".",
-		SubtitleLangs:  []string{"en", "zh"},
-		WriteSubtitles: true,
-		WriteAutoSubs:  true,
-		LogFormat:      "json",
-		RecordFile:     "download_record",
-		MappingFile:    "subtitle_mapping",
-		Retries:        "10",
-		SocketTimeout:  "30",
+		Format:            "mp4",
+		Quality:           "bestvideo+bestaudio",
+		OutputDir:         ".",
+		SubtitleLangs:     []string{"en", "zh"},
+		WriteSubtitles:    true,
+		WriteAutoSubs:     true,
+		LogFormat:         "json",
+		RecordFile:        "download_record",
+		MappingFile:       "subtitle_mapping",
+		Retries:           "10",
+		SocketTimeout:     "30",
+		MaxRetries:        2,
+		RetryDelay:        10 * time.Second,
+		RateLimitCooldown: 5 * time.Minute,
+		MaxCooldowns:      defaultMaxCooldowns,
+		HookTimeout:       30 * time.Second,
 	}
 }
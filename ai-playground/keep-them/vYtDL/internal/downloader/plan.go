@@ -0,0 +1,216 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/innate/yt-dl/internal/playliststate"
+)
+
+// Actions a run takes for an entry.
+const (
+	ActionDownload = "download"
+	ActionRetry    = "retry"
+	ActionSkip     = "skip"
+)
+
+// Skip reasons that are not reported as "skipped" progress.
+const (
+	reasonNotSelected = "not selected"
+	reasonSkipped     = "skipped in an earlier run"
+)
+
+// Plan is what a download of one URL would do, as computed by Plan.
+type Plan struct {
+	URL       string `json:"url"`
+	Title     string `json:"title,omitempty"`
+	Playlist  bool   `json:"playlist"`
+	Dir       string `json:"dir"`
+	StateFile string `json:"state_file,omitempty"`
+	// Warnings name options the playlist's saved options would replace.
+	Warnings []string    `json:"warnings,omitempty"`
+	Entries  []PlanEntry `json:"entries"`
+}
+
+// PlanEntry is the planned action for one video.
+type PlanEntry struct {
+	Index    int      `json:"index,omitempty"` // 1-based playlist position; 0 if not in the playlist
+	Key      string   `json:"key"`
+	VideoID  string   `json:"video_id,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	URL      string   `json:"url"`
+	Status   string   `json:"status,omitempty"` // saved playlist state
+	Attempts int      `json:"attempts,omitempty"`
+	Action   string   `json:"action"`
+	Reason   string   `json:"reason,omitempty"`
+	Dir      string   `json:"dir"`
+	Command  []string `json:"command,omitempty"` // with secrets redacted
+}
+
+// Plan resolves rawURL the way DownloadSingle or DownloadPlaylist would and
+// reports the action for each video. It only lists the playlist: nothing is
+// downloaded and no state, record or directory is written.
+func (d *Downloader) Plan(ctx context.Context, rawURL string) (Plan, error) {
+	rawURL = normalizeURL(rawURL)
+	if d.scheduleErr != nil {
+		return Plan{}, d.scheduleErr
+	}
+	if !d.opts.IsPlaylist {
+		return Plan{URL: rawURL, Dir: d.opts.OutputDir, Entries: []PlanEntry{{
+			Key:     rawURL,
+			URL:     rawURL,
+			Action:  ActionDownload,
+			Dir:     d.opts.OutputDir,
+			Command: d.commandLine(rawURL, d.opts.OutputDir, ""),
+		}}}, nil
+	}
+
+	meta, err := d.FetchPlaylistContext(ctx, rawURL)
+	if err != nil {
+		return Plan{}, fmt.Errorf("list playlist: %w", err)
+	}
+	title := playlistDirName(meta.Title, rawURL)
+	plan := Plan{
+		URL:       rawURL,
+		Title:     meta.Title,
+		Playlist:  true,
+		Dir:       filepath.Join(d.opts.OutputDir, title),
+		StateFile: playliststate.StatePath(filepath.Join(d.opts.OutputDir, title)),
+	}
+	if len(meta.Entries) == 0 && d.listsFromFeed(rawURL) {
+		return plan, nil
+	}
+	if plan.Warnings, err = d.applyPlaylistConfig(plan.Dir, rawURL, false); err != nil {
+		return Plan{}, err
+	}
+	if len(meta.Entries) == 0 {
+		plan.Entries = []PlanEntry{{Key: rawURL, URL: rawURL, Action: ActionDownload, Dir: plan.Dir,
+			Reason:  "no flat listing; yt-dlp downloads the whole playlist",
+			Command: d.commandLine(rawURL, plan.Dir, "")}}
+		return plan, nil
+	}
+
+	selected, err := d.entrySelection()
+	if err != nil {
+		return Plan{}, err
+	}
+	if d.archived, err = d.loadArchive(); err != nil {
+		return Plan{}, err
+	}
+	prefixWidth, warning := d.indexPrefixWidth(rawURL, title, len(meta.Entries))
+	if warning != "" {
+		plan.Warnings = append(plan.Warnings, warning)
+	}
+	entries := playliststate.Preview(plan.StateFile, rawURL, title, plan.Dir, stateInputs(meta), d.mergeOptions(rawURL))
+	for _, entry := range entries {
+		p := d.planEntry(entry, selected)
+		p.Dir = plan.Dir
+		if p.Action != ActionSkip {
+			p.Command = d.commandLine(p.URL, plan.Dir, indexPrefix(prefixWidth, entry.PlaylistIndex))
+		}
+		plan.Entries = append(plan.Entries, p)
+	}
+	return plan, nil
+}
+
+func stateInputs(meta PlaylistInfo) []playliststate.EntryInput {
+	inputs := make([]playliststate.EntryInput, 0, len(meta.Entries))
+	for _, entry := range meta.Entries {
+		inputs = append(inputs, playliststate.EntryInput{ID: entry.ID, URL: entry.URL, Title: entry.Title})
+	}
+	return inputs
+}
+
+// mergeOptions is how a listing of rawURL updates the saved state. Feeds
+// only list recent videos, so older entries missing from them are not
+// treated as removed.
+func (d *Downloader) mergeOptions(rawURL string) playliststate.MergeOptions {
+	return playliststate.MergeOptions{
+		Reset:   d.opts.ResetPlaylistState,
+		Partial: d.listsFromFeed(rawURL),
+	}
+}
+
+// planEntry decides what a run does with a saved playlist entry.
+func (d *Downloader) planEntry(entry playliststate.EntryState, selected func(int, string) bool) PlanEntry {
+	index := entry.PlaylistIndex
+	p := PlanEntry{
+		Index:    index,
+		Key:      playlistStateKey(entry.ID, entry.URL),
+		VideoID:  entry.ID,
+		Title:    entry.Title,
+		URL:      strings.TrimSpace(entry.URL),
+		Status:   entry.Status,
+		Attempts: entry.Attempts,
+		Action:   ActionSkip,
+	}
+	switch {
+	case selected != nil && !selected(index, p.Key):
+		p.Reason = reasonNotSelected
+	case selected == nil && entry.Status == playliststate.StatusSkipped:
+		p.Reason = reasonSkipped
+	case entry.Status == playliststate.StatusSucceeded:
+		p.Reason = "already downloaded"
+	case d.archived.has(entry.ID):
+		p.Reason = "in download archive"
+	case entry.RemovedUpstream && selected == nil:
+		p.Reason = "removed from the playlist"
+	case entry.Status == playliststate.StatusUnavailable && selected == nil:
+		// Permanent failures are only retried when picked explicitly.
+		p.Reason = "unavailable: " + entry.Error
+	case entry.Status == playliststate.StatusFailed && d.opts.MaxAttempts > 0 && entry.Attempts >= d.opts.MaxAttempts:
+		p.Reason = fmt.Sprintf("gave up after %d attempts", entry.Attempts)
+	case entry.Status == playliststate.StatusFailed && !d.retryKind(ErrorKind(entry.ErrorKind)):
+		p.Reason = "not retried: " + entry.ErrorKind
+	case entry.Status == playliststate.StatusFailed, entry.Status == playliststate.StatusUnavailable:
+		p.Action = ActionRetry
+		p.Reason = entry.Error
+	default:
+		p.Action = ActionDownload
+	}
+	return p
+}
+
+// commandLine is the command the backend would run for rawURL, naming the
+// files starting with filePrefix, for display.
+func (d *Downloader) commandLine(rawURL, outDir, filePrefix string) []string {
+	backend, err := d.backendFor(rawURL)
+	if err != nil {
+		return nil
+	}
+	if p, ok := backend.(filePrefixer); ok && filePrefix != "" {
+		backend = p.withFilePrefix(filePrefix)
+	}
+	if cli, ok := backend.(*cliBackend); ok {
+		return append([]string{cli.bin}, RedactArgs(cli.buildArgs(rawURL, outDir))...)
+	}
+	return []string{backend.Name(), rawURL}
+}
+
+// secretParamRe matches key=value pairs whose value should not be shown.
+var secretParamRe = regexp.MustCompile(`(?i)([a-z_]*(?:token|password|secret|key)[a-z_]*=)[^;,&\s]+`)
+
+// RedactArgs hides proxy credentials and token-like extractor arguments.
+func RedactArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = arg
+		if i == 0 {
+			continue
+		}
+		switch args[i-1] {
+		case "--proxy":
+			if u, err := url.Parse(arg); err == nil && u.User != nil {
+				u.User = url.UserPassword("REDACTED", "REDACTED")
+				out[i] = u.String()
+			}
+		case "--extractor-args", "--add-header":
+			out[i] = secretParamRe.ReplaceAllString(arg, "${1}REDACTED")
+		}
+	}
+	return out
+}
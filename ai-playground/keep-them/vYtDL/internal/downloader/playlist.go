@@ -0,0 +1,67 @@
+package downloader
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// PlaylistEntry is one item of a playlist as listed by yt-dlp --flat-playlist.
+type PlaylistEntry struct {
+	ID       string
+	Title    string
+	URL      string
+	Duration float64 // seconds; 0 when unknown
+}
+
+// Key returns the playlist-state key of the entry, as used by SelectedEntries.
+func (e PlaylistEntry) Key() string {
+	return playlistStateKey(e.ID, e.URL)
+}
+
+// PlaylistInfo is the flat listing of a playlist, channel or collection.
+type PlaylistInfo struct {
+	Title   string
+	Entries []PlaylistEntry
+}
+
+// FetchPlaylist lists the entries of a playlist without downloading anything.
+func (d *Downloader) FetchPlaylist(rawURL string) (PlaylistInfo, error) {
+	return d.FetchPlaylistContext(context.Background(), rawURL)
+}
+
+// FetchPlaylistContext is FetchPlaylist, stopping the listing when ctx ends.
+func (d *Downloader) FetchPlaylistContext(ctx context.Context, rawURL string) (PlaylistInfo, error) {
+	rawURL = normalizeURL(rawURL)
+	if d.listsFromFeed(rawURL) {
+		return d.fetchFeed(ctx, rawURL)
+	}
+	backend, err := d.backendFor(rawURL)
+	if err != nil {
+		return PlaylistInfo{}, err
+	}
+	return backend.ListPlaylist(ctx, rawURL)
+}
+
+// LooksLikePlaylist guesses whether a URL points to a collection rather than a
+// single video. Watch URLs that merely carry a list= parameter count as single
+// videos, matching yt-dlp's --no-playlist behaviour.
+func LooksLikePlaylist(rawURL string) bool {
+	u, err := url.Parse(normalizeURL(rawURL))
+	if err != nil {
+		return false
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	switch {
+	case path == "/playlist":
+		return true
+	case path == "/watch", strings.HasPrefix(path, "/shorts/"), u.Host == "youtu.be":
+		return false
+	case strings.HasPrefix(path, "/channel/"),
+		strings.HasPrefix(path, "/c/"),
+		strings.HasPrefix(path, "/user/"),
+		strings.HasPrefix(path, "/@"):
+		return true
+	}
+	return u.Query().Get("list") != "" && u.Query().Get("v") == ""
+}
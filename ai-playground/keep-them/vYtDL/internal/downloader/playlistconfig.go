@@ -0,0 +1,160 @@
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/innate/yt-dl/internal/playliststate"
+)
+
+// configFields are the Config fields compared between runs, by their key in
+// the config file. set copies the field from src to dst.
+var configFields = []struct {
+	key   string
+	value func(playliststate.Config) string
+	set   func(dst *playliststate.Config, src playliststate.Config)
+}{
+	{"format", func(c playliststate.Config) string { return c.Format },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.Format = src.Format }},
+	{"quality", func(c playliststate.Config) string { return c.Quality },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.Quality = src.Quality }},
+	{"format_id", func(c playliststate.Config) string { return c.FormatID },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.FormatID = src.FormatID }},
+	{"prefer_vcodec", func(c playliststate.Config) string { return strings.Join(c.PreferVideoCodecs, ",") },
+		func(dst *playliststate.Config, src playliststate.Config) {
+			dst.PreferVideoCodecs = src.PreferVideoCodecs
+		}},
+	{"prefer_acodec", func(c playliststate.Config) string { return strings.Join(c.PreferAudioCodecs, ",") },
+		func(dst *playliststate.Config, src playliststate.Config) {
+			dst.PreferAudioCodecs = src.PreferAudioCodecs
+		}},
+	{"avoid_vcodec", func(c playliststate.Config) string { return strings.Join(c.AvoidVideoCodecs, ",") },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.AvoidVideoCodecs = src.AvoidVideoCodecs }},
+	{"avoid_acodec", func(c playliststate.Config) string { return strings.Join(c.AvoidAudioCodecs, ",") },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.AvoidAudioCodecs = src.AvoidAudioCodecs }},
+	{"max_fps", func(c playliststate.Config) string { return c.MaxFPS },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.MaxFPS = src.MaxFPS }},
+	{"no_hdr", func(c playliststate.Config) string { return strconv.FormatBool(c.NoHDR) },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.NoHDR = src.NoHDR }},
+	{"max_filesize", func(c playliststate.Config) string { return c.MaxFilesize },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.MaxFilesize = src.MaxFilesize }},
+	{"start", func(c playliststate.Config) string { return c.Start },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.Start = src.Start }},
+	{"end", func(c playliststate.Config) string { return c.End },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.End = src.End }},
+	{"subtitles", func(c playliststate.Config) string { return strconv.FormatBool(c.Subtitles) },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.Subtitles = src.Subtitles }},
+	{"auto_subtitles", func(c playliststate.Config) string { return strconv.FormatBool(c.AutoSubtitles) },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.AutoSubtitles = src.AutoSubtitles }},
+	{"sub_langs", func(c playliststate.Config) string { return strings.Join(c.SubLangs, ",") },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.SubLangs = src.SubLangs }},
+	{"index_prefix", func(c playliststate.Config) string { return strconv.FormatBool(c.IndexPrefix) },
+		func(dst *playliststate.Config, src playliststate.Config) { dst.IndexPrefix = src.IndexPrefix }},
+}
+
+func configFromOptions(playlistURL string, opts Options) playliststate.Config {
+	return playliststate.Config{
+		PlaylistURL:       playlistURL,
+		Format:            opts.Format,
+		Quality:           opts.Quality,
+		FormatID:          opts.FormatSelector,
+		PreferVideoCodecs: opts.PreferVideoCodecs,
+		PreferAudioCodecs: opts.PreferAudioCodecs,
+		AvoidVideoCodecs:  opts.AvoidVideoCodecs,
+		AvoidAudioCodecs:  opts.AvoidAudioCodecs,
+		MaxFPS:            opts.MaxFPS,
+		NoHDR:             opts.NoHDR,
+		MaxFilesize:       opts.MaxFilesize,
+		Start:             opts.StartTime,
+		End:               opts.EndTime,
+		Subtitles:         opts.WriteSubtitles,
+		AutoSubtitles:     opts.WriteAutoSubs,
+		SubLangs:          opts.SubtitleLangs,
+		IndexPrefix:       opts.IndexPrefix,
+	}
+}
+
+func optionsWithConfig(opts Options, cfg playliststate.Config) Options {
+	opts.Format = cfg.Format
+	opts.Quality = cfg.Quality
+	opts.FormatSelector = cfg.FormatID
+	opts.PreferVideoCodecs = slices.Clone(cfg.PreferVideoCodecs)
+	opts.PreferAudioCodecs = slices.Clone(cfg.PreferAudioCodecs)
+	opts.AvoidVideoCodecs = slices.Clone(cfg.AvoidVideoCodecs)
+	opts.AvoidAudioCodecs = slices.Clone(cfg.AvoidAudioCodecs)
+	opts.MaxFPS = cfg.MaxFPS
+	opts.NoHDR = cfg.NoHDR
+	opts.MaxFilesize = cfg.MaxFilesize
+	opts.StartTime = cfg.Start
+	opts.EndTime = cfg.End
+	opts.WriteSubtitles = cfg.Subtitles
+	opts.WriteAutoSubs = cfg.AutoSubtitles
+	opts.SubtitleLangs = slices.Clone(cfg.SubLangs)
+	opts.IndexPrefix = cfg.IndexPrefix
+	return opts
+}
+
+// applyPlaylistConfig makes a playlist run use the options saved in the
+// playlist directory, so a resume with other flags does not mix formats in
+// one folder. The first run saves its options there. With
+// OverridePlaylistConfig or ResetPlaylistState the options the caller set
+// (PlaylistConfigKeys) replace the saved ones and the rest are kept. It
+// returns a warning for every set option that differs. Unless save is set
+// nothing is written, for dry runs.
+func (d *Downloader) applyPlaylistConfig(playlistDir, playlistURL string, save bool) ([]string, error) {
+	path := playliststate.ConfigPath(playlistDir)
+	current := configFromOptions(playlistURL, d.opts)
+	saved, err := playliststate.LoadConfig(path)
+	if os.IsNotExist(err) {
+		if save {
+			d.saveConfig(path, current)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read playlist options: %w; fix or delete the file", err)
+	}
+
+	name := filepath.Base(playlistDir)
+	override := d.opts.OverridePlaylistConfig || d.opts.ResetPlaylistState
+	merged := saved
+	var warnings []string
+	for _, f := range configFields {
+		if d.opts.PlaylistConfigKeys != nil && !slices.Contains(d.opts.PlaylistConfigKeys, f.key) {
+			continue
+		}
+		was, now := f.value(saved), f.value(current)
+		if was == now {
+			continue
+		}
+		f.set(&merged, current)
+		if override {
+			warnings = append(warnings, fmt.Sprintf("playlist %q: %s changed from %q to %q", name, f.key, was, now))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("playlist %q was started with %s %q; keeping it instead of %q (use --override to change it)",
+				name, f.key, was, now))
+		}
+	}
+	if !override {
+		d.opts = optionsWithConfig(d.opts, saved)
+		return warnings, nil
+	}
+	d.opts = optionsWithConfig(d.opts, merged)
+	if save && len(warnings) > 0 {
+		if current.PlaylistURL != "" {
+			merged.PlaylistURL = current.PlaylistURL
+		}
+		d.saveConfig(path, merged)
+	}
+	return warnings, nil
+}
+
+func (d *Downloader) saveConfig(path string, cfg playliststate.Config) {
+	if err := playliststate.SaveConfig(path, cfg); err != nil {
+		d.log().Warn("playlist options not saved", "path", path, "err", err)
+	}
+}
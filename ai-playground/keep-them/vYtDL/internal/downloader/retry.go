@@ -0,0 +1,200 @@
+package downloader
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	// maxRetryDelay caps the exponential backoff between attempts.
+	maxRetryDelay = 5 * time.Minute
+	// maxCooldown caps the pause after repeated rate limiting.
+	maxCooldown = time.Hour
+	// defaultMaxCooldowns is Options.MaxCooldowns when unset.
+	defaultMaxCooldowns = 3
+)
+
+// Permanent reports whether a failure of this kind will not go away by
+// trying again later, e.g. a private or deleted video.
+func (k ErrorKind) Permanent() bool {
+	switch k {
+	case ErrorPrivate, ErrorUnavailable, ErrorMembersOnly, ErrorGeoBlocked:
+		return true
+	}
+	return false
+}
+
+// RateLimited reports whether the site is throttling us as a whole rather
+// than refusing this one video.
+func (k ErrorKind) RateLimited() bool {
+	return k == ErrorRateLimited || k == ErrorBotCheck
+}
+
+// Retryable reports whether the same download may succeed when re-run within
+// the current run. Failures that need the user to change something (cookies,
+// ffmpeg, format, disk space) are not retried.
+func (k ErrorKind) Retryable() bool {
+	switch k {
+	case ErrorNetwork, ErrorRateLimited, ErrorUnknown:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns the backoff before retry n (1-based): RetryDelay doubled
+// for every earlier retry, capped, with ±20% jitter so parallel runs spread out.
+func (d *Downloader) retryDelay(n int) time.Duration {
+	base := d.opts.RetryDelay
+	if base <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < n && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryDelay)
+	jitter := time.Duration((rand.Float64()*0.4 - 0.2) * float64(delay))
+	return delay + jitter
+}
+
+// cooldownDelay returns the pause after the n-th rate limit in a row.
+func (d *Downloader) cooldownDelay(n int) time.Duration {
+	delay := d.opts.RateLimitCooldown
+	for i := 1; i < n && delay < maxCooldown; i++ {
+		delay *= 2
+	}
+	return min(delay, maxCooldown)
+}
+
+// coolsDown reports whether a failure of this kind pauses the queue instead
+// of failing: the site is throttling us, cooldowns are on and the streak of
+// cooldowns has not reached its limit.
+func (d *Downloader) coolsDown(kind ErrorKind) bool {
+	if !kind.RateLimited() || d.opts.RateLimitCooldown <= 0 {
+		return false
+	}
+	limit := d.opts.MaxCooldowns
+	if limit <= 0 {
+		limit = defaultMaxCooldowns
+	}
+	if kind == ErrorBotCheck {
+		limit = 1
+	}
+	return d.rateLimits.count() < limit
+}
+
+// withRetries runs attempt until it succeeds, is stopped through the Control,
+// fails with a kind that is not worth retrying, or maxTries is used up.
+// Between attempts the item reports "retrying" with the time of the next try.
+// Rate limiting instead pauses the queue for a cooldown that grows while the
+// site keeps refusing; cooldowns do not use up tries. After MaxCooldowns in a
+// row the failure is handled like any other.
+func (d *Downloader) withRetries(key, videoID, title string, maxTries int, attempt func() DownloadResult) DownloadResult {
+	for try := 1; ; try++ {
+		result := attempt()
+		if result.Success {
+			d.rateLimits.reset()
+		}
+		if result.stopped == "" && d.coolsDown(result.ErrorKind) {
+			n := d.rateLimits.hit()
+			until := time.Now().Add(d.cooldownDelay(n))
+			d.log().Warn("rate limited, pausing queue", "key", key, "kind", result.ErrorKind, "until", until, "in_a_row", n)
+			if result.Title != "" {
+				title = result.Title
+			}
+			d.emit(ProgressUpdate{
+				Key:       key,
+				VideoID:   videoID,
+				Title:     title,
+				Status:    "cooldown",
+				Error:     result.Error,
+				ErrorKind: result.ErrorKind,
+				ResumeAt:  until,
+			})
+			if !d.sleepUntil(key, until) {
+				return d.stoppedWhileWaiting(key, result)
+			}
+			try--
+			continue
+		}
+		if result.stopped == "" && result.ErrorKind.RateLimited() && d.opts.RateLimitCooldown > 0 {
+			d.log().Warn("rate limited after too many cooldowns, giving up", "key", key, "kind", result.ErrorKind,
+				"in_a_row", d.rateLimits.count())
+		}
+		if result.Success || result.stopped != "" || !result.ErrorKind.Retryable() || try >= maxTries {
+			return result
+		}
+		next := time.Now().Add(d.retryDelay(try))
+		d.log().Info("retrying", "key", key, "kind", result.ErrorKind, "attempt", try+1, "at", next)
+		if result.Title != "" {
+			title = result.Title
+		}
+		d.emit(ProgressUpdate{
+			Key:       key,
+			VideoID:   videoID,
+			Title:     title,
+			Status:    "retrying",
+			Error:     result.Error,
+			ErrorKind: result.ErrorKind,
+			Attempt:   try + 1,
+			ResumeAt:  next,
+		})
+		if !d.sleepUntil(key, next) {
+			return d.stoppedWhileWaiting(key, result)
+		}
+	}
+}
+
+// sleepUntil waits for t, waking early when the Control aborts the run or
+// has a command for key. It returns false in those cases.
+func (d *Downloader) sleepUntil(key string, t time.Time) bool {
+	for {
+		wait := time.Until(t)
+		if wait <= 0 {
+			return true
+		}
+		timer := time.NewTimer(wait)
+		var changed <-chan struct{}
+		if d.control != nil {
+			changed = d.control.wait()
+		}
+		select {
+		case <-timer.C:
+		case <-changed:
+			timer.Stop()
+		}
+		if d.control.isAborted() || d.control.pending(key) != "" {
+			return false
+		}
+	}
+}
+
+// stoppedWhileWaiting turns the last failed attempt into a stopped result
+// when a command or abort arrived during the backoff.
+func (d *Downloader) stoppedWhileWaiting(key string, result DownloadResult) DownloadResult {
+	result.Key = key
+	result.stopped = d.control.pending(key)
+	update := ProgressUpdate{Key: key, VideoID: result.VideoID, Title: result.Title}
+	switch result.stopped {
+	case CommandCancel:
+		result.Skipped = true
+		result.Error = cancelledMessage
+		update.Status, update.Error = "skipped", cancelledMessage
+	case CommandPause:
+		update.Status = "paused"
+	default:
+		return result
+	}
+	d.emit(update)
+	return result
+}
+
+// triesLeft returns how many attempts an entry may still use in this run,
+// given the attempts already recorded for it.
+func (d *Downloader) triesLeft(attempts int) int {
+	tries := d.opts.MaxRetries + 1
+	if d.opts.MaxAttempts > 0 {
+		tries = min(tries, d.opts.MaxAttempts-attempts)
+	}
+	return max(tries, 1)
+}
@@ -0,0 +1,186 @@
+package downloader
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// window is a daily time range as offsets from midnight. End before Start
+// means the window runs past midnight into the next day.
+type window struct {
+	start, end time.Duration
+}
+
+// Schedule decides when new downloads may start. Days without their own rule
+// use the default windows; an empty Schedule is always open.
+type Schedule struct {
+	def  []window
+	days map[time.Weekday][]window
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseSchedule builds a Schedule from a default window spec such as
+// "22:00-07:00" and optional per-weekday specs keyed by mon, tue, … sun.
+// A spec is a comma-separated list of HH:MM-HH:MM ranges, "all" or "off".
+// It returns nil when neither argument restricts anything.
+func ParseSchedule(def string, weekdays map[string]string) (*Schedule, error) {
+	if strings.TrimSpace(def) == "" && len(weekdays) == 0 {
+		return nil, nil
+	}
+	s := &Schedule{days: map[time.Weekday][]window{}}
+	var err error
+	if strings.TrimSpace(def) == "" {
+		s.def = []window{{0, 24 * time.Hour}}
+	} else if s.def, err = parseWindows(def); err != nil {
+		return nil, err
+	}
+	for name, spec := range weekdays {
+		short := strings.ToLower(strings.TrimSpace(name))
+		if len(short) > 3 {
+			short = short[:3] // accept "monday" as well as "mon"
+		}
+		day, ok := weekdayNames[short]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q in download windows", name)
+		}
+		if s.days[day], err = parseWindows(spec); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return s, nil
+}
+
+func parseWindows(spec string) ([]window, error) {
+	switch strings.ToLower(strings.TrimSpace(spec)) {
+	case "all", "always":
+		return []window{{0, 24 * time.Hour}}, nil
+	case "off", "none", "never":
+		return []window{}, nil
+	}
+	var windows []window
+	for _, part := range strings.Split(spec, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid window %q: want HH:MM-HH:MM", part)
+		}
+		start, err := parseClock(from)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(to)
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("invalid window %q: start equals end", part)
+		}
+		windows = append(windows, window{start, end})
+	}
+	return windows, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	hh, mm, ok := strings.Cut(strings.TrimSpace(s), ":")
+	h, errH := strconv.Atoi(hh)
+	m, errM := strconv.Atoi(mm)
+	if !ok || errH != nil || errM != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q: want HH:MM", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func (s *Schedule) windowsFor(day time.Weekday) []window {
+	if w, ok := s.days[day]; ok {
+		return w
+	}
+	return s.def
+}
+
+// Open reports whether a download may start at t.
+func (s *Schedule) Open(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	for _, w := range s.windowsFor(t.Weekday()) {
+		if w.start < w.end && offset >= w.start && offset < w.end {
+			return true
+		}
+		if w.end < w.start && offset >= w.start {
+			return true
+		}
+	}
+	// Overnight windows that started yesterday.
+	for _, w := range s.windowsFor(midnight.AddDate(0, 0, -1).Weekday()) {
+		if w.end < w.start && offset < w.end {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the earliest minute at or after t when the schedule is
+// open, or the zero time if it never opens within a week.
+func (s *Schedule) NextOpen(t time.Time) time.Time {
+	if s.Open(t) {
+		return t
+	}
+	next := t.Truncate(time.Minute)
+	for i := 0; i < 8*24*60; i++ {
+		next = next.Add(time.Minute)
+		if s.Open(next) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// waitForWindow blocks until the schedule allows a new download to start,
+// reporting a "waiting" status for the item meanwhile. A cancel or pause for
+// the item ends the wait early so the caller can act on it. It returns false
+// when the run was aborted while waiting or the schedule never opens.
+func (d *Downloader) waitForWindow(key, videoID, title string) bool {
+	sched := d.schedule
+	if sched == nil {
+		return true
+	}
+	for {
+		now := time.Now()
+		if sched.Open(now) {
+			return true
+		}
+		opensAt := sched.NextOpen(now)
+		if opensAt.IsZero() {
+			d.emit(ProgressUpdate{Key: key, VideoID: videoID, Title: title, Status: "error",
+				Error: "download window never opens; check --window and the weekday rules"})
+			return false
+		}
+		d.log().Debug("outside download window", "key", key, "opens", opensAt)
+		d.emit(ProgressUpdate{Key: key, VideoID: videoID, Title: title, Status: "waiting", ResumeAt: opensAt})
+
+		// Re-check at least every minute so clock changes are picked up.
+		timer := time.NewTimer(min(time.Until(opensAt), time.Minute))
+		var changed <-chan struct{}
+		if d.control != nil {
+			changed = d.control.wait()
+		}
+		select {
+		case <-timer.C:
+		case <-changed:
+			timer.Stop()
+		}
+		if d.control.isAborted() {
+			return false
+		}
+		if d.control.pending(key) != "" {
+			return true
+		}
+	}
+}
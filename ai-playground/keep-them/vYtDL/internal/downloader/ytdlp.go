@@ -0,0 +1,348 @@
+package downloader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// cliBackend runs yt-dlp, or youtube-dl for the flags the two share.
+type cliBackend struct {
+	bin        string
+	opts       Options
+	youtubeDL  bool   // assume youtube-dl's flag set unless Options.Capabilities says otherwise
+	filePrefix string // put before the title in the output template
+}
+
+func newCLIBackend(bin string, opts Options, youtubeDL bool) *cliBackend {
+	return &cliBackend{bin: bin, opts: opts, youtubeDL: youtubeDL}
+}
+
+func (b *cliBackend) withFilePrefix(prefix string) Backend {
+	c := *b
+	c.filePrefix = prefix
+	return &c
+}
+
+func (b *cliBackend) Name() string {
+	if b.youtubeDL {
+		return BackendYoutubeDL
+	}
+	return BackendYTDLP
+}
+
+type playlistEntry struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	URL        string  `json:"url"`
+	WebpageURL string  `json:"webpage_url"`
+	Duration   float64 `json:"duration"`
+}
+
+type playlistMetadata struct {
+	Title   string          `json:"title"`
+	Entries []playlistEntry `json:"entries"`
+}
+
+// ListPlaylist uses --dump-single-json --flat-playlist to list entries.
+func (b *cliBackend) ListPlaylist(ctx context.Context, url string) (PlaylistInfo, error) {
+	out, err := exec.CommandContext(ctx, b.bin, "--dump-single-json", "--flat-playlist", url).Output()
+	if err != nil {
+		return PlaylistInfo{}, err
+	}
+	var meta playlistMetadata
+	if err := json.Unmarshal(out, &meta); err != nil {
+		return PlaylistInfo{}, err
+	}
+	info := PlaylistInfo{Title: strings.TrimSpace(meta.Title)}
+	for _, entry := range meta.Entries {
+		info.Entries = append(info.Entries, PlaylistEntry{
+			ID:       strings.TrimSpace(entry.ID),
+			Title:    strings.TrimSpace(entry.Title),
+			URL:      playlistEntryURL(entry),
+			Duration: entry.Duration,
+		})
+	}
+	return info, nil
+}
+
+// Probe asks the tool for the metadata and formats of a single video.
+func (b *cliBackend) Probe(ctx context.Context, url string) (FormatList, error) {
+	args := append([]string{"--dump-single-json", "--no-playlist"}, b.networkArgs()...)
+	args = append(args, url)
+	out, err := exec.CommandContext(ctx, b.bin, args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return FormatList{}, fmt.Errorf("list formats: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return FormatList{}, fmt.Errorf("list formats: %w", err)
+	}
+	return ParseFormatList(out)
+}
+
+// Download runs the tool and turns its stdout into progress reports: progress
+// lines become "downloading", the --print-json line becomes "merging".
+func (b *cliBackend) Download(ctx context.Context, url, outDir string, log io.Writer, progress func(Progress)) (Fetched, error) {
+	var fetched Fetched
+	if err := b.unsupported(); err != nil {
+		return fetched, &KindError{Kind: ErrorFormat, Err: err}
+	}
+
+	args := b.buildArgs(url, outDir)
+	out := &lockedWriter{w: log}
+	// Item logs are plain files; keep proxy passwords and tokens out of them.
+	out.writeLine("$ " + b.bin + " " + strings.Join(RedactArgs(args), " "))
+	cmd := exec.CommandContext(ctx, b.bin, args...)
+	cmd.Dir = outDir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fetched, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fetched, err
+	}
+	if err := cmd.Start(); err != nil {
+		if ctx.Err() != nil {
+			return fetched, ctx.Err()
+		}
+		return fetched, &KindError{Kind: ErrorBinary, Err: err}
+	}
+
+	// Collect stderr for error messages
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			out.writeLine(sc.Text())
+			fetched.Stderr = append(fetched.Stderr, sc.Text())
+		}
+	}()
+
+	var totalBytes int64
+	sc := bufio.NewScanner(stdout)
+	// Increase buffer size for large JSON lines
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		out.writeLine(line)
+
+		// Try to parse as JSON (video metadata from --print-json)
+		if strings.HasPrefix(line, "{") {
+			var info VideoInfo
+			if jsonErr := json.Unmarshal([]byte(line), &info); jsonErr == nil {
+				fetched.Info = info
+				progress(Progress{Status: "merging", Percent: 100, TotalBytes: totalBytes, Info: &info})
+			}
+			continue
+		}
+
+		// Parse progress line
+		if m := progressRe.FindStringSubmatch(line); m != nil {
+			var pct float64
+			fmt.Sscanf(m[1], "%f", &pct)
+			if size := ParseByteSize(m[2]); size > 0 {
+				totalBytes = size
+			}
+			progress(Progress{
+				Status:     "downloading",
+				Percent:    pct,
+				Speed:      m[3],
+				ETA:        m[4],
+				TotalBytes: totalBytes,
+			})
+		}
+	}
+
+	// Wait closes the pipes, so stderr must be read to the end first.
+	<-stderrDone
+	return fetched, cmd.Wait()
+}
+
+// ytdlpOnlyFlags are assumed missing from youtube-dl when no Capabilities
+// were detected.
+var ytdlpOnlyFlags = map[string]bool{
+	"--download-sections": true, "--force-keyframes-at-cuts": true,
+	"--write-subs": true, "--write-auto-subs": true, "--sub-langs": true,
+	"--extractor-retries": true, "--progress": true,
+	"--cookies-from-browser": true, "--extractor-args": true,
+}
+
+// supports reports whether the installed tool has flag, from the detected
+// Capabilities when there are any.
+func (b *cliBackend) supports(flag string) bool {
+	if caps := b.opts.Capabilities; caps != nil && len(caps.Flags) > 0 {
+		return caps.Supports(flag)
+	}
+	return !(b.youtubeDL && ytdlpOnlyFlags[flag])
+}
+
+// unsupported reports requested options the installed tool cannot honour.
+func (b *cliBackend) unsupported() error {
+	switch {
+	case strings.TrimSpace(b.opts.CookiesFromBrowser) != "" && !b.supports("--cookies-from-browser"):
+		return fmt.Errorf("%s does not support --cookies-from-browser; export a cookies file instead", b.Name())
+	case strings.TrimSpace(b.opts.ExtractorArgs) != "" && !b.supports("--extractor-args"):
+		return fmt.Errorf("%s does not support --extractor-args", b.Name())
+	}
+	return nil
+}
+
+// buildArgs constructs yt-dlp arguments from options.
+func (b *cliBackend) buildArgs(url, outDir string) []string {
+	o := b.opts
+	args := []string{}
+	container := strings.TrimSpace(o.Format)
+
+	// Format selection
+	if selector := strings.TrimSpace(o.FormatSelector); selector != "" {
+		args = append(args, "-f", selector)
+	} else {
+		args = append(args, "-f", formatSelector(o))
+	}
+
+	// Merge format
+	if container != "" {
+		args = append(args, "--merge-output-format", container)
+	}
+
+	// Output template
+	// "%" starts a field in the template.
+	prefix := strings.ReplaceAll(b.filePrefix, "%", "%%")
+	outTemplate := filepath.Join(outDir, prefix+"%(title)s.%(ext)s")
+	args = append(args, "-o", outTemplate)
+
+	// Subtitles
+	if o.WriteSubtitles {
+		if b.supports("--write-subs") {
+			args = append(args, "--write-subs")
+			if o.WriteAutoSubs {
+				args = append(args, "--write-auto-subs")
+			}
+			if len(o.SubtitleLangs) > 0 {
+				args = append(args, "--sub-langs", strings.Join(o.SubtitleLangs, ","))
+			}
+		} else {
+			// Older spelling, also understood by youtube-dl.
+			args = append(args, "--write-sub")
+			if o.WriteAutoSubs {
+				args = append(args, "--write-auto-sub")
+			}
+			if len(o.SubtitleLangs) > 0 {
+				args = append(args, "--sub-lang", strings.Join(o.SubtitleLangs, ","))
+			}
+		}
+	}
+
+	// Time range (requires ffmpeg)
+	if o.StartTime != "" || o.EndTime != "" {
+		if !b.supports("--download-sections") {
+			// No --download-sections; let ffmpeg cut while downloading.
+			cut := ""
+			if o.StartTime != "" {
+				cut += "-ss " + o.StartTime
+			}
+			if o.EndTime != "" {
+				cut = strings.TrimSpace(cut + " -to " + o.EndTime)
+			}
+			args = append(args, "--external-downloader", "ffmpeg", "--external-downloader-args", cut)
+		} else {
+			section := ""
+			if o.StartTime != "" {
+				section += "*" + o.StartTime + "-"
+			} else {
+				section += "*0-"
+			}
+			if o.EndTime != "" {
+				section += o.EndTime
+			} else {
+				section += "inf"
+			}
+			args = append(args, "--download-sections", section, "--force-keyframes-at-cuts")
+		}
+	}
+
+	// Playlist handling
+	if !o.IsPlaylist {
+		args = append(args, "--no-playlist")
+	}
+
+	args = append(args, b.networkArgs()...)
+
+	if rate := strings.TrimSpace(o.LimitRate); rate != "" {
+		args = append(args, "--limit-rate", rate)
+	}
+
+	// Progress output in newline-delimited form for parsing
+	args = append(args, "--newline")
+	if b.supports("--progress") {
+		args = append(args, "--progress")
+	}
+
+	// JSON metadata for post-processing
+	args = append(args, "--print-json")
+
+	args = append(args, url)
+	return args
+}
+
+// networkArgs returns the connection, retry and authentication flags shared by
+// every yt-dlp invocation.
+func (b *cliBackend) networkArgs() []string {
+	o := b.opts
+	args := []string{}
+	if retries := strings.TrimSpace(o.Retries); retries != "" {
+		args = append(args, "--retries", retries)
+		if b.supports("--extractor-retries") {
+			args = append(args, "--extractor-retries", retries)
+		}
+	}
+	if timeout := strings.TrimSpace(o.SocketTimeout); timeout != "" {
+		args = append(args, "--socket-timeout", timeout)
+	}
+	if o.ForceIPv4 {
+		args = append(args, "--force-ipv4")
+	}
+	if proxy := strings.TrimSpace(o.Proxy); proxy != "" {
+		args = append(args, "--proxy", proxy)
+	}
+	if cookies := strings.TrimSpace(o.CookiesFile); cookies != "" {
+		args = append(args, "--cookies", cookies)
+	}
+	if ua := strings.TrimSpace(o.UserAgent); ua != "" {
+		args = append(args, "--user-agent", ua)
+	}
+	if browser := strings.TrimSpace(o.CookiesFromBrowser); browser != "" && b.supports("--cookies-from-browser") {
+		args = append(args, "--cookies-from-browser", browser)
+	}
+	if extractorArgs := strings.TrimSpace(o.ExtractorArgs); extractorArgs != "" && b.supports("--extractor-args") {
+		args = append(args, "--extractor-args", extractorArgs)
+	}
+	return args
+}
+
+// progressRe matches yt-dlp progress lines like:
+// [download]  42.3% of ~100.00MiB at  2.00MiB/s ETA 00:30
+var progressRe = regexp.MustCompile(`\[download\]\s+([\d.]+)%(?:\s+of\s+~?\s*(\S+))?.*?at\s+(\S+)\s+ETA\s+(\S+)`)
+
+func playlistEntryURL(entry playlistEntry) string {
+	switch {
+	case entry.WebpageURL != "":
+		return entry.WebpageURL
+	case strings.HasPrefix(entry.URL, "http://"), strings.HasPrefix(entry.URL, "https://"):
+		return entry.URL
+	case entry.ID != "":
+		return "https://www.youtube.com/watch?v=" + entry.ID
+	case entry.URL != "":
+		return "https://www.youtube.com/watch?v=" + entry.URL
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,232 @@
+// Package feed reads Atom and RSS feeds as playlist listings. YouTube
+// publishes an Atom feed of the latest uploads of every channel and playlist;
+// polling it is far cheaper than listing a whole channel with yt-dlp.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Feed is a parsed Atom or RSS feed.
+type Feed struct {
+	Title   string
+	Link    string
+	Entries []Entry // in feed order, usually newest first
+}
+
+// Entry is one item of a feed.
+type Entry struct {
+	// ID is the video ID: yt:videoId for YouTube feeds, the v= parameter of
+	// a YouTube link, or else the entry's id or guid.
+	ID        string
+	Title     string
+	URL       string
+	Published time.Time // zero when the feed does not say
+}
+
+// YouTubeFeedURL is where YouTube serves channel and playlist feeds.
+const YouTubeFeedURL = "https://www.youtube.com/feeds/videos.xml"
+
+// URLFor returns the feed for a channel or playlist page: YouTube channel
+// URLs with a channel ID and playlist URLs map to YouTube's Atom feed, and
+// anything else, including other feed URLs and local files, is returned as
+// is. Handle URLs (/@name) cannot be mapped without a network lookup.
+func URLFor(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || !isYouTubeHost(u.Host) || strings.HasPrefix(u.Path, "/feeds/") {
+		return rawURL, nil
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	switch {
+	case strings.HasPrefix(path, "/channel/"):
+		id := strings.SplitN(strings.TrimPrefix(path, "/channel/"), "/", 2)[0]
+		return YouTubeFeedURL + "?channel_id=" + url.QueryEscape(id), nil
+	case u.Query().Get("list") != "":
+		return YouTubeFeedURL + "?playlist_id=" + url.QueryEscape(u.Query().Get("list")), nil
+	case strings.HasPrefix(path, "/user/"):
+		name := strings.SplitN(strings.TrimPrefix(path, "/user/"), "/", 2)[0]
+		return YouTubeFeedURL + "?user=" + url.QueryEscape(name), nil
+	}
+	return "", fmt.Errorf("no feed for %s: use the channel's /channel/UC… URL or its feed URL", rawURL)
+}
+
+// IsFeedURL reports whether rawURL is a YouTube feed, which is always listed
+// from the feed rather than by the download tool.
+func IsFeedURL(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	return err == nil && isYouTubeHost(u.Host) && strings.HasPrefix(u.Path, "/feeds/")
+}
+
+// LocalPath returns the file a feed source names: a file:// URL or a plain
+// path. ok is false for http and https URLs.
+func LocalPath(src string) (path string, ok bool) {
+	if u, err := url.Parse(src); err == nil {
+		switch u.Scheme {
+		case "http", "https":
+			return "", false
+		case "file":
+			return filepath.FromSlash(u.Path), true
+		}
+	}
+	return src, true
+}
+
+// Load parses the feed in a local file.
+func Load(path string) (Feed, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Feed{}, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// document covers both formats; only one of Entries and Channel is filled.
+type document struct {
+	XMLName xml.Name
+	Title   string     `xml:"title"`
+	Links   []link     `xml:"link"`
+	Entries []atomItem `xml:"entry"`
+	Channel *struct {
+		Title string    `xml:"title"`
+		Link  string    `xml:"link"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type link struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomItem struct {
+	ID        string `xml:"id"`
+	VideoID   string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+	Title     string `xml:"title"`
+	Links     []link `xml:"link"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+type rssItem struct {
+	Title     string `xml:"title"`
+	Link      string `xml:"link"`
+	GUID      string `xml:"guid"`
+	PubDate   string `xml:"pubDate"`
+	Enclosure struct {
+		URL string `xml:"url,attr"`
+	} `xml:"enclosure"`
+}
+
+// Parse reads an Atom or RSS 2.0 feed. Entries without a link are dropped.
+func Parse(r io.Reader) (Feed, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Feed{}, err
+	}
+	var doc document
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	if err := dec.Decode(&doc); err != nil {
+		return Feed{}, fmt.Errorf("parse feed: %w", err)
+	}
+
+	var f Feed
+	switch {
+	case doc.XMLName.Local == "feed":
+		f = Feed{Title: strings.TrimSpace(doc.Title), Link: alternate(doc.Links)}
+		for _, item := range doc.Entries {
+			e := Entry{
+				ID:        strings.TrimSpace(item.VideoID),
+				Title:     strings.TrimSpace(item.Title),
+				URL:       alternate(item.Links),
+				Published: parseTime(item.Published, item.Updated),
+			}
+			if e.ID == "" {
+				e.ID = entryID(e.URL, item.ID)
+			}
+			f.add(e)
+		}
+	case doc.XMLName.Local == "rss" && doc.Channel != nil:
+		f = Feed{Title: strings.TrimSpace(doc.Channel.Title), Link: strings.TrimSpace(doc.Channel.Link)}
+		for _, item := range doc.Channel.Items {
+			link := strings.TrimSpace(item.Link)
+			if link == "" {
+				link = strings.TrimSpace(item.Enclosure.URL)
+			}
+			f.add(Entry{
+				ID:        entryID(link, item.GUID),
+				Title:     strings.TrimSpace(item.Title),
+				URL:       link,
+				Published: parseTime(item.PubDate),
+			})
+		}
+	default:
+		return Feed{}, errors.New("parse feed: not an Atom or RSS document")
+	}
+	return f, nil
+}
+
+func (f *Feed) add(e Entry) {
+	if e.URL != "" {
+		f.Entries = append(f.Entries, e)
+	}
+}
+
+// alternate picks the page link of an Atom element.
+func alternate(links []link) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	return ""
+}
+
+// entryID prefers the YouTube video ID in link, so entries match the IDs
+// yt-dlp reports, and falls back to the feed's own identifier.
+func entryID(link, fallback string) string {
+	if u, err := url.Parse(link); err == nil && isYouTubeHost(u.Host) {
+		if v := u.Query().Get("v"); v != "" {
+			return v
+		}
+		if u.Host == "youtu.be" || strings.HasPrefix(u.Path, "/shorts/") {
+			return strings.TrimPrefix(strings.TrimPrefix(u.Path, "/shorts"), "/")
+		}
+	}
+	fallback = strings.TrimSpace(fallback)
+	if id, ok := strings.CutPrefix(fallback, "yt:video:"); ok {
+		return id
+	}
+	return fallback
+}
+
+var timeLayouts = []string{time.RFC3339, time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"}
+
+func parseTime(values ...string) time.Time {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func isYouTubeHost(host string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	return host == "youtube.com" || host == "youtu.be"
+}
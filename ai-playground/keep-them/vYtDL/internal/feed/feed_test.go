@@ -0,0 +1,105 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const youtubeAtom = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns:media="http://search.yahoo.com/mrss/" xmlns="http://www.w3.org/2005/Atom">
+ <link rel="self" href="http://www.youtube.com/feeds/videos.xml?channel_id=UCabc"/>
+ <id>yt:channel:UCabc</id>
+ <yt:channelId>UCabc</yt:channelId>
+ <title>Lecture Hall</title>
+ <link rel="alternate" href="https://www.youtube.com/channel/UCabc"/>
+ <entry>
+  <id>yt:video:new1</id>
+  <yt:videoId>new1</yt:videoId>
+  <title>Week 2</title>
+  <link rel="alternate" href="https://www.youtube.com/watch?v=new1"/>
+  <published>2025-10-02T12:00:00+00:00</published>
+  <media:group><media:title>Week 2 (media)</media:title></media:group>
+ </entry>
+ <entry>
+  <id>yt:video:old1</id>
+  <title>Week 1</title>
+  <link rel="alternate" href="https://www.youtube.com/watch?v=old1"/>
+  <published>2025-09-25T12:00:00+00:00</published>
+ </entry>
+</feed>`
+
+const rss = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+ <title>Talks</title>
+ <link>https://example.com/talks</link>
+ <item><title>Short</title><link>https://youtu.be/s1</link><guid>ignored</guid><pubDate>Thu, 02 Oct 2025 12:00:00 +0000</pubDate></item>
+ <item><title>Episode</title><guid isPermaLink="false">ep-7</guid><enclosure url="https://cdn.example.com/ep7.mp3" type="audio/mpeg"/></item>
+ <item><title>No link</title></item>
+</channel></rss>`
+
+func TestParseYouTubeAtom(t *testing.T) {
+	f, err := Parse(strings.NewReader(youtubeAtom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Lecture Hall" || f.Link != "https://www.youtube.com/channel/UCabc" || len(f.Entries) != 2 {
+		t.Fatalf("feed = %+v", f)
+	}
+	want := []Entry{
+		{ID: "new1", Title: "Week 2", URL: "https://www.youtube.com/watch?v=new1", Published: time.Date(2025, 10, 2, 12, 0, 0, 0, time.UTC)},
+		{ID: "old1", Title: "Week 1", URL: "https://www.youtube.com/watch?v=old1", Published: time.Date(2025, 9, 25, 12, 0, 0, 0, time.UTC)},
+	}
+	for i, e := range f.Entries {
+		if e.ID != want[i].ID || e.Title != want[i].Title || e.URL != want[i].URL || !e.Published.Equal(want[i].Published) {
+			t.Errorf("entry %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestParseRSS(t *testing.T) {
+	f, err := Parse(strings.NewReader(rss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Talks" || len(f.Entries) != 2 {
+		t.Fatalf("feed = %+v", f)
+	}
+	if e := f.Entries[0]; e.ID != "s1" || e.Published.IsZero() {
+		t.Errorf("youtu.be entry = %+v", e)
+	}
+	if e := f.Entries[1]; e.ID != "ep-7" || e.URL != "https://cdn.example.com/ep7.mp3" {
+		t.Errorf("enclosure entry = %+v", e)
+	}
+
+	if _, err := Parse(strings.NewReader(`<html><body>not a feed</body></html>`)); err == nil {
+		t.Error("parsed an HTML page as a feed")
+	}
+}
+
+func TestURLFor(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://www.youtube.com/channel/UCabc/videos":              "https://www.youtube.com/feeds/videos.xml?channel_id=UCabc",
+		"https://www.youtube.com/playlist?list=PLxyz":               "https://www.youtube.com/feeds/videos.xml?playlist_id=PLxyz",
+		"https://www.youtube.com/user/someone":                      "https://www.youtube.com/feeds/videos.xml?user=someone",
+		"https://www.youtube.com/feeds/videos.xml?channel_id=UCabc": "https://www.youtube.com/feeds/videos.xml?channel_id=UCabc",
+		"https://example.com/podcast.rss":                           "https://example.com/podcast.rss",
+		"testdata/channel.xml":                                      "testdata/channel.xml",
+	} {
+		if got, err := URLFor(in); err != nil || got != want {
+			t.Errorf("URLFor(%s) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	if _, err := URLFor("https://www.youtube.com/@handle"); err == nil {
+		t.Error("URLFor mapped a handle URL")
+	}
+	if !IsFeedURL("https://www.youtube.com/feeds/videos.xml?channel_id=UCabc") || IsFeedURL("https://www.youtube.com/channel/UCabc") {
+		t.Error("IsFeedURL misclassified")
+	}
+	if p, ok := LocalPath("file:///tmp/feed.xml"); !ok || p != "/tmp/feed.xml" {
+		t.Errorf("LocalPath(file://) = %s, %v", p, ok)
+	}
+	if _, ok := LocalPath("https://example.com/feed"); ok {
+		t.Error("LocalPath accepted an https URL")
+	}
+}
@@ -0,0 +1,318 @@
+// Package inbox turns URL files dropped into a directory into download jobs:
+// it parses .url, .webloc and .txt files, reads their option sidecars and
+// files them under done/ or failed/ once processed.
+package inbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+// Directories inside the inbox that processed files are moved to.
+const (
+	DoneDir   = "done"
+	FailedDir = "failed"
+)
+
+// Extensions lists the URL file types the inbox picks up.
+var Extensions = []string{".url", ".webloc", ".txt"}
+
+// IsURLFile reports whether name is a URL file the inbox should process.
+// Hidden and temporary files are left alone.
+func IsURLFile(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~") {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFile returns the URLs in a URL file.
+func ParseFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".url":
+		urls = parseInternetShortcut(data)
+	case ".webloc":
+		if urls, err = parseWebloc(data); err != nil {
+			return nil, err
+		}
+	default:
+		urls = parseText(data)
+	}
+	for _, u := range urls {
+		if !isWebURL(u) {
+			return nil, fmt.Errorf("%q is not an http or https URL", u)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("no URL found")
+	}
+	return urls, nil
+}
+
+// parseInternetShortcut reads the URL= line of a Windows .url file.
+func parseInternetShortcut(data []byte) []string {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "URL") {
+			return []string{strings.TrimSpace(value)}
+		}
+	}
+	return nil
+}
+
+// parseWebloc reads the URL from a macOS .webloc property list. Binary
+// plists are not supported; Finder writes XML ones when a link is dragged
+// out of a browser.
+func parseWebloc(data []byte) ([]string, error) {
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		return nil, errors.New("binary .webloc files are not supported; save it as XML")
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	var key string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var text string
+		switch start.Name.Local {
+		case "key":
+			if err := dec.DecodeElement(&text, &start); err != nil {
+				return nil, err
+			}
+			key = strings.TrimSpace(text)
+		case "string":
+			if err := dec.DecodeElement(&text, &start); err != nil {
+				return nil, err
+			}
+			if key == "URL" {
+				return []string{strings.TrimSpace(text)}, nil
+			}
+		}
+	}
+}
+
+// parseText reads one URL per line, skipping blank lines and # comments.
+func parseText(data []byte) []string {
+	var urls []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\uFEFF"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Sidecar holds per-file options from a JSON file next to the URL file with
+// the same name, e.g. talk.json for talk.url. Empty fields keep the watch
+// command's defaults.
+type Sidecar struct {
+	// Output is a directory for this file's downloads, relative to the
+	// default output directory unless absolute.
+	Output   string   `json:"output,omitempty"`
+	Format   string   `json:"format,omitempty"`
+	Quality  string   `json:"quality,omitempty"`
+	FormatID string   `json:"format_id,omitempty"`
+	Playlist *bool    `json:"playlist,omitempty"` // nil guesses from the URL
+	Items    string   `json:"items,omitempty"`
+	SubLangs []string `json:"sub_langs,omitempty"`
+	NoSubs   bool     `json:"no_subs,omitempty"`
+	Start    string   `json:"start,omitempty"`
+	End      string   `json:"end,omitempty"`
+	// Override replaces the options saved in a playlist directory.
+	Override    bool `json:"override,omitempty"`
+	IndexPrefix bool `json:"index_prefix,omitempty"`
+
+	// Path is the sidecar file; empty when there is none.
+	Path string `json:"-"`
+}
+
+// SidecarPath is where the sidecar of a URL file would be.
+func SidecarPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".json"
+}
+
+// LoadSidecar reads the sidecar of the URL file at path. A missing sidecar is
+// not an error; unknown fields are, so a typo does not go unnoticed.
+func LoadSidecar(path string) (Sidecar, error) {
+	var sc Sidecar
+	sidecar := SidecarPath(path)
+	data, err := os.ReadFile(sidecar)
+	if errors.Is(err, os.ErrNotExist) {
+		return sc, nil
+	}
+	if err != nil {
+		return sc, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sc); err != nil {
+		return sc, fmt.Errorf("%s: %w", filepath.Base(sidecar), err)
+	}
+	if err := sc.Validate(); err != nil {
+		return sc, fmt.Errorf("%s: %w", filepath.Base(sidecar), err)
+	}
+	sc.Path = sidecar
+	return sc, nil
+}
+
+// Validate checks the fields that Apply does not check itself.
+func (sc Sidecar) Validate() error {
+	if sc.Items != "" {
+		if _, err := ytdl.ParseItems(sc.Items); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	return nil
+}
+
+// Apply returns opts with the sidecar's settings on top.
+func (sc Sidecar) Apply(opts ytdl.Options) ytdl.Options {
+	if sc.Output != "" {
+		if filepath.IsAbs(sc.Output) {
+			opts.OutputDir = sc.Output
+		} else {
+			opts.OutputDir = filepath.Join(opts.OutputDir, sc.Output)
+		}
+	}
+	if sc.Format != "" {
+		opts.Format = sc.Format
+	}
+	if sc.Quality != "" {
+		opts.Quality = sc.Quality
+	}
+	if sc.FormatID != "" {
+		opts.FormatSelector = sc.FormatID
+	}
+	if sc.Items != "" {
+		opts.Items = sc.Items
+	}
+	if len(sc.SubLangs) > 0 {
+		opts.SubtitleLangs = sc.SubLangs
+	}
+	if sc.NoSubs {
+		opts.WriteSubtitles = false
+		opts.WriteAutoSubs = false
+	}
+	if sc.Start != "" {
+		opts.StartTime = sc.Start
+	}
+	if sc.End != "" {
+		opts.EndTime = sc.End
+	}
+	if sc.Override {
+		opts.OverridePlaylistConfig = true
+	}
+	if sc.IndexPrefix {
+		opts.IndexPrefix = true
+	}
+	return opts
+}
+
+// Jobs turns urls into download jobs, guessing playlists from the URL unless
+// the sidecar says otherwise.
+func (sc Sidecar) Jobs(urls []string) []ytdl.Job {
+	jobs := make([]ytdl.Job, 0, len(urls))
+	for _, u := range urls {
+		playlist := ytdl.LooksLikePlaylist(u)
+		if sc.Playlist != nil {
+			playlist = *sc.Playlist
+		}
+		jobs = append(jobs, ytdl.Job{URL: u, Playlist: playlist})
+	}
+	return jobs
+}
+
+// Report is written next to a processed file as <name>.record.json.
+type Report struct {
+	Source      string        `json:"source"`
+	ProcessedAt time.Time     `json:"processed_at"`
+	URLs        []string      `json:"urls,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	Records     []ytdl.Record `json:"records"`
+}
+
+// Finish moves the URL file at path, and its sidecar, to done/ or failed/
+// in inbox and writes the report beside it. It returns the new path.
+func Finish(inbox, path string, ok bool, report Report) (string, error) {
+	dir := filepath.Join(inbox, FailedDir)
+	if ok {
+		dir = filepath.Join(inbox, DoneDir)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// A file dropped again under the same name must not overwrite the first.
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+		stem += "-" + time.Now().Format("20060102-150405")
+	}
+
+	dest := filepath.Join(dir, stem+ext)
+	if err := os.Rename(path, dest); err != nil {
+		return "", err
+	}
+	if sidecar := SidecarPath(path); fileExists(sidecar) {
+		if err := os.Rename(sidecar, filepath.Join(dir, stem+".json")); err != nil {
+			return dest, err
+		}
+	}
+
+	report.Source = name
+	if report.Records == nil {
+		report.Records = []ytdl.Record{}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return dest, err
+	}
+	return dest, os.WriteFile(filepath.Join(dir, stem+".record.json"), append(data, '\n'), 0o644)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,200 @@
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	cases := []struct {
+		name, content string
+		want          []string
+	}{
+		{"talk.url", "[InternetShortcut]\r\nURL=https://www.youtube.com/watch?v=abc\r\n", []string{"https://www.youtube.com/watch?v=abc"}},
+		{"talk.webloc", `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0"><dict><key>URL</key><string>https://youtu.be/xyz</string></dict></plist>`, []string{"https://youtu.be/xyz"}},
+		{"list.txt", "\uFEFF# queue\nhttps://a.example/1\n\n  https://a.example/2  \n", []string{"https://a.example/1", "https://a.example/2"}},
+	}
+	for _, c := range cases {
+		path := filepath.Join(dir, c.name)
+		write(t, path, c.content)
+		got, err := ParseFile(path)
+		if err != nil || !slices.Equal(got, c.want) {
+			t.Errorf("ParseFile(%s) = %v, %v; want %v", c.name, got, err, c.want)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"empty.txt":  "# nothing yet\n",
+		"bad.txt":    "not a url\n",
+		"ftp.url":    "[InternetShortcut]\nURL=ftp://example.com/file\n",
+		"bin.webloc": "bplist00\x00",
+	} {
+		path := filepath.Join(dir, name)
+		write(t, path, content)
+		if urls, err := ParseFile(path); err == nil {
+			t.Errorf("ParseFile(%s) = %v, want an error", name, urls)
+		}
+	}
+}
+
+func TestIsURLFile(t *testing.T) {
+	t.Parallel()
+	for name, want := range map[string]bool{
+		"a.url": true, "B.TXT": true, "c.webloc": true,
+		"a.json": false, ".hidden.txt": false, "~lock.txt": false, "video.mp4": false,
+	} {
+		if got := IsURLFile(name); got != want {
+			t.Errorf("IsURLFile(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestSidecar(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "course.txt")
+	write(t, path, "https://www.youtube.com/playlist?list=PL1\nhttps://www.youtube.com/watch?v=v1\n")
+
+	// No sidecar keeps the defaults and guesses playlists from the URL.
+	sc, err := LoadSidecar(path)
+	if err != nil || sc.Path != "" {
+		t.Fatalf("LoadSidecar without file = %+v, %v", sc, err)
+	}
+	urls, _ := ParseFile(path)
+	if jobs := sc.Jobs(urls); !jobs[0].Playlist || jobs[1].Playlist {
+		t.Errorf("guessed jobs = %+v", jobs)
+	}
+
+	write(t, SidecarPath(path), `{"output": "courses", "quality": "720", "playlist": false, "items": "1-3", "no_subs": true}`)
+	sc, err = LoadSidecar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := sc.Apply(ytdl.Options{OutputDir: "/media", Quality: "1080", Format: "mp4", WriteSubtitles: true, WriteAutoSubs: true})
+	if opts.OutputDir != filepath.Join("/media", "courses") || opts.Quality != "720" || opts.Format != "mp4" ||
+		opts.Items != "1-3" || opts.WriteSubtitles || opts.WriteAutoSubs {
+		t.Errorf("Apply = %+v", opts)
+	}
+	if jobs := sc.Jobs(urls); jobs[0].Playlist {
+		t.Errorf("sidecar playlist=false ignored: %+v", jobs)
+	}
+
+	for _, bad := range []string{`{"qualty": "720"}`, `{"items": "3-1"}`, `{`} {
+		write(t, SidecarPath(path), bad)
+		if _, err := LoadSidecar(path); err == nil {
+			t.Errorf("LoadSidecar(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestFinishMovesFileSidecarAndReport(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "talk.url")
+	for round := 0; round < 2; round++ {
+		write(t, path, "[InternetShortcut]\nURL=https://youtu.be/abc\n")
+		write(t, SidecarPath(path), `{"quality": "720"}`)
+		report := Report{ProcessedAt: time.Now(), URLs: []string{"https://youtu.be/abc"},
+			Records: []ytdl.Record{{URL: "https://youtu.be/abc", Title: "Talk", Success: true}}}
+		dest, err := Finish(dir, path, true, report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Dir(dest) != filepath.Join(dir, DoneDir) {
+			t.Fatalf("moved to %s", dest)
+		}
+		if round == 1 && filepath.Base(dest) == "talk.url" {
+			t.Fatal("second file overwrote the first")
+		}
+		stem := dest[:len(dest)-len(".url")]
+		if _, err := os.Stat(stem + ".json"); err != nil {
+			t.Errorf("sidecar not moved: %v", err)
+		}
+		data, err := os.ReadFile(stem + ".record.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Report
+		if err := json.Unmarshal(data, &got); err != nil || got.Source != "talk.url" || len(got.Records) != 1 {
+			t.Errorf("report = %+v, %v", got, err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file still in the inbox: %v", err)
+	}
+
+	write(t, path, "nothing")
+	dest, err := Finish(dir, path, false, Report{Error: "no URL found"})
+	if err != nil || filepath.Dir(dest) != filepath.Join(dir, FailedDir) {
+		t.Fatalf("Finish failed = %s, %v", dest, err)
+	}
+}
+
+func TestWatcherPollsForSettledFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, DoneDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write(t, filepath.Join(dir, DoneDir, "old.txt"), "https://a.example/old\n")
+	write(t, filepath.Join(dir, "notes.json"), "{}")
+	write(t, filepath.Join(dir, "a.txt"), "https://a.example/1\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	w := &Watcher{Dir: dir, Poll: true, Interval: 10 * time.Millisecond, Settle: 30 * time.Millisecond}
+	var handled []string
+	err := w.Watch(ctx, func(path string) {
+		handled = append(handled, filepath.Base(path))
+		switch len(handled) {
+		case 1:
+			// Left in place: must not come back until it changes.
+			write(t, filepath.Join(dir, "b.url"), "[InternetShortcut]\nURL=https://a.example/2\n")
+		case 2:
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.txt", "b.url"}; !slices.Equal(handled, want) {
+		t.Fatalf("handled = %v, want %v", handled, want)
+	}
+}
+
+func TestScannerWaitsForFilesToSettle(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	write(t, path, "https://a.example/")
+	s := newScanner(time.Second)
+	now := time.Now()
+	if ready, _ := s.scan(dir, now); len(ready) != 0 {
+		t.Fatalf("new file ready at once: %v", ready)
+	}
+	write(t, path, "https://a.example/1\nhttps://a.example/2\n")
+	if ready, _ := s.scan(dir, now.Add(2*time.Second)); len(ready) != 0 {
+		t.Fatalf("changed file ready at once: %v", ready)
+	}
+	if ready, _ := s.scan(dir, now.Add(4*time.Second)); len(ready) != 1 {
+		t.Fatalf("settled file not ready: %v", ready)
+	}
+}
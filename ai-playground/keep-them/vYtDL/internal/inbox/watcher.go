@@ -0,0 +1,173 @@
+package inbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher hands URL files in a directory to a handler once they have stopped
+// changing. Files are handled one at a time, oldest name first.
+type Watcher struct {
+	Dir string
+	// Poll rescans the directory every Interval instead of using file system
+	// notifications. Watch also falls back to polling when notifications are
+	// unavailable, e.g. on some network shares.
+	Poll     bool
+	Interval time.Duration
+	// Settle is how long a file's size and modification time must stay the
+	// same before it is handled, so half-written files are left alone.
+	Settle time.Duration
+	// OnFallback, if set, is told why notifications could not be used.
+	OnFallback func(error)
+}
+
+// Watch calls handle for each ready URL file until ctx ends. The handler is
+// expected to move the file away; a file it leaves in place is not handled
+// again until it changes.
+func (w *Watcher) Watch(ctx context.Context, handle func(path string)) error {
+	if err := os.MkdirAll(w.Dir, 0o755); err != nil {
+		return err
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	var events chan fsnotify.Event
+	var errs chan error
+	if !w.Poll {
+		fw, err := fsnotify.NewWatcher()
+		if err == nil {
+			if err = fw.Add(w.Dir); err != nil {
+				fw.Close()
+			}
+		}
+		if err != nil {
+			if w.OnFallback != nil {
+				w.OnFallback(err)
+			}
+		} else {
+			defer fw.Close()
+			events, errs = fw.Events, fw.Errors
+			// Notifications wake the loop at once; the ticker only has to
+			// notice when a changed file has settled.
+			interval = max(w.Settle/2, 100*time.Millisecond)
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s := newScanner(w.Settle)
+	for {
+		ready, err := s.scan(w.Dir, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, path := range ready {
+			if ctx.Err() != nil {
+				return nil
+			}
+			handle(path)
+			s.handled(path)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-events:
+		case <-errs:
+			// An overflowed event queue only means a rescan is due.
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pending lists the URL files in dir, for processing what is already there
+// without waiting for them to settle.
+func Pending(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && IsURLFile(e.Name()) {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// fileState is what the scanner last saw of a file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // when size and modTime were first seen
+}
+
+// scanner tracks URL files between directory scans.
+type scanner struct {
+	settle time.Duration
+	seen   map[string]fileState
+	done   map[string]time.Time // handled path → its modTime at the time
+}
+
+func newScanner(settle time.Duration) *scanner {
+	return &scanner{settle: settle, seen: map[string]fileState{}, done: map[string]time.Time{}}
+}
+
+// scan returns the files that have not changed for the settle time.
+func (s *scanner) scan(dir string, now time.Time) ([]string, error) {
+	paths, err := Pending(dir)
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[string]bool, len(paths))
+	var ready []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // moved away between listing and stat
+		}
+		present[path] = true
+		if mod, ok := s.done[path]; ok && mod.Equal(info.ModTime()) {
+			continue
+		}
+		delete(s.done, path)
+		prev, ok := s.seen[path]
+		if !ok || prev.size != info.Size() || !prev.modTime.Equal(info.ModTime()) {
+			s.seen[path] = fileState{size: info.Size(), modTime: info.ModTime(), since: now}
+			if s.settle > 0 {
+				continue
+			}
+		} else if now.Sub(prev.since) < s.settle {
+			continue
+		}
+		ready = append(ready, path)
+	}
+	for path := range s.seen {
+		if !present[path] {
+			delete(s.seen, path)
+		}
+	}
+	for path := range s.done {
+		if !present[path] {
+			delete(s.done, path)
+		}
+	}
+	return ready, nil
+}
+
+// handled notes a file the handler is finished with. If it is still there,
+// it is skipped until it is modified.
+func (s *scanner) handled(path string) {
+	delete(s.seen, path)
+	if info, err := os.Stat(path); err == nil {
+		s.done[path] = info.ModTime()
+	}
+}
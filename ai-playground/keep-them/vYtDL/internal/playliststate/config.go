@@ -0,0 +1,74 @@
+package playliststate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the set of options that decide what a playlist's files look like.
+// It is saved next to the state on the first run so later runs produce
+// matching files.
+type Config struct {
+	PlaylistURL       string   `yaml:"playlist_url,omitempty"`
+	Format            string   `yaml:"format,omitempty"`
+	Quality           string   `yaml:"quality,omitempty"`
+	FormatID          string   `yaml:"format_id,omitempty"`
+	PreferVideoCodecs []string `yaml:"prefer_vcodec,omitempty"`
+	PreferAudioCodecs []string `yaml:"prefer_acodec,omitempty"`
+	AvoidVideoCodecs  []string `yaml:"avoid_vcodec,omitempty"`
+	AvoidAudioCodecs  []string `yaml:"avoid_acodec,omitempty"`
+	MaxFPS            string   `yaml:"max_fps,omitempty"`
+	NoHDR             bool     `yaml:"no_hdr,omitempty"`
+	MaxFilesize       string   `yaml:"max_filesize,omitempty"`
+	Start             string   `yaml:"start,omitempty"`
+	End               string   `yaml:"end,omitempty"`
+	Subtitles         bool     `yaml:"subtitles"`
+	AutoSubtitles     bool     `yaml:"auto_subtitles"`
+	SubLangs          []string `yaml:"sub_langs,omitempty"`
+	IndexPrefix       bool     `yaml:"index_prefix,omitempty"`
+}
+
+const configHeader = `# Options this playlist was first downloaded with. yt-dl applies them on
+# every later run so the folder stays consistent; edit them here, or run
+# with --override to replace them with the options on the command line.
+`
+
+func ConfigPath(playlistDir string) string {
+	return filepath.Join(playlistDir, ".vytdl.yaml")
+}
+
+// LoadConfig reads a playlist config. Unknown keys are errors, so a typo in a
+// hand-edited file does not go unnoticed. A missing file returns an error
+// satisfying os.IsNotExist.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return cfg, nil
+}
+
+// SaveConfig writes a playlist config, replacing any earlier one.
+func SaveConfig(path string, cfg Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append([]byte(configHeader), data...), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
@@ -13,6 +13,10 @@ const (
 	StatusRunning   = "running"
 	StatusSucceeded = "succeeded"
 	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+	// StatusUnavailable marks entries that failed permanently (private,
+	// deleted, geo-blocked); resumes leave them alone.
+	StatusUnavailable = "unavailable"
 )
 
 type EntryInput struct {
@@ -27,11 +31,65 @@ type EntryState struct {
 	Title          string    `json:"title"`
 	Status         string    `json:"status"`
 	Error          string    `json:"error,omitempty"`
+	ErrorKind      string    `json:"error_kind,omitempty"`
 	Attempts       int       `json:"attempts"`
 	Filename       string    `json:"filename,omitempty"`
 	Subtitles      []string  `json:"subtitles,omitempty"`
 	LastStartedAt  time.Time `json:"last_started_at,omitempty"`
 	LastFinishedAt time.Time `json:"last_finished_at,omitempty"`
+	Metadata
+
+	// PlaylistIndex is the 1-based position in the latest listing; 0 once
+	// the entry is no longer listed.
+	PlaylistIndex int       `json:"playlist_index,omitempty"`
+	FirstSeen     time.Time `json:"first_seen,omitempty"`
+	LastSeen      time.Time `json:"last_seen,omitempty"`
+	// RemovedUpstream marks entries missing from the latest full listing.
+	RemovedUpstream bool `json:"removed_upstream,omitempty"`
+	// ListedAs is "private" or "deleted" when the latest listing shows a
+	// placeholder instead of the video.
+	ListedAs string `json:"listed_as,omitempty"`
+	// TitleHistory records every title change the listings showed, oldest
+	// first.
+	TitleHistory []TitleChange `json:"title_history,omitempty"`
+}
+
+// TitleChange is one rename of an entry's video.
+type TitleChange struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// Placeholder kinds in EntryState.ListedAs.
+const (
+	ListedPrivate = "private"
+	ListedDeleted = "deleted"
+)
+
+// placeholderTitles are what playlist listings show in place of videos that
+// can no longer be watched.
+var placeholderTitles = map[string]string{
+	"[Private video]":     ListedPrivate,
+	"[Deleted video]":     ListedDeleted,
+	"[Unavailable video]": ListedDeleted,
+}
+
+// MergeOptions controls how a new listing is merged into the saved state.
+type MergeOptions struct {
+	// Reset discards the saved state.
+	Reset bool
+	// Partial marks a listing that only shows recent entries, such as a
+	// feed; saved entries missing from it are not flagged as removed.
+	Partial bool
+}
+
+// Metadata is what the download tool reported about a finished video.
+type Metadata struct {
+	Duration    float64 `json:"duration,omitempty"`    // seconds
+	UploadDate  string  `json:"upload_date,omitempty"` // YYYYMMDD
+	Thumbnail   string  `json:"thumbnail,omitempty"`
+	Description string  `json:"description,omitempty"`
 }
 
 type State struct {
@@ -47,7 +105,21 @@ type Manager struct {
 	state State
 }
 
-func Open(path, playlistURL, playlistTitle, playlistDir string, entries []EntryInput, reset bool) (*Manager, error) {
+func Open(path, playlistURL, playlistTitle, playlistDir string, entries []EntryInput, opts MergeOptions) (*Manager, error) {
+	m := merge(path, playlistURL, playlistTitle, playlistDir, entries, opts)
+	if err := m.Save(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Preview returns the entries Open would start from, without writing the
+// state file.
+func Preview(path, playlistURL, playlistTitle, playlistDir string, entries []EntryInput, opts MergeOptions) []EntryState {
+	return merge(path, playlistURL, playlistTitle, playlistDir, entries, opts).Entries()
+}
+
+func merge(path, playlistURL, playlistTitle, playlistDir string, entries []EntryInput, opts MergeOptions) *Manager {
 	m := &Manager{
 		path: path,
 		state: State{
@@ -57,7 +129,7 @@ func Open(path, playlistURL, playlistTitle, playlistDir string, entries []EntryI
 		},
 	}
 
-	if !reset {
+	if !opts.Reset {
 		if data, err := os.ReadFile(path); err == nil {
 			_ = json.Unmarshal(data, &m.state)
 		}
@@ -67,11 +139,8 @@ func Open(path, playlistURL, playlistTitle, playlistDir string, entries []EntryI
 	m.state.PlaylistTitle = playlistTitle
 	m.state.PlaylistDir = playlistDir
 	m.normalizeInterruptedRuns()
-	m.mergeEntries(entries)
-	if err := m.Save(); err != nil {
-		return nil, err
-	}
-	return m, nil
+	m.mergeEntries(entries, opts.Partial, time.Now())
+	return m
 }
 
 func (m *Manager) Path() string {
@@ -89,6 +158,7 @@ func (m *Manager) MarkRunning(key string) error {
 		}
 		m.state.Entries[i].Status = StatusRunning
 		m.state.Entries[i].Error = ""
+		m.state.Entries[i].ErrorKind = ""
 		m.state.Entries[i].Attempts++
 		m.state.Entries[i].LastStartedAt = time.Now()
 		break
@@ -96,7 +166,7 @@ func (m *Manager) MarkRunning(key string) error {
 	return m.Save()
 }
 
-func (m *Manager) MarkFinished(key, title, filename string, subtitles []string, success bool, errText string) error {
+func (m *Manager) MarkFinished(key, title, filename string, subtitles []string, success bool, errText, errKind string) error {
 	for i := range m.state.Entries {
 		if stateKey(m.state.Entries[i].ID, m.state.Entries[i].URL) != key {
 			continue
@@ -110,15 +180,110 @@ func (m *Manager) MarkFinished(key, title, filename string, subtitles []string,
 		if success {
 			m.state.Entries[i].Status = StatusSucceeded
 			m.state.Entries[i].Error = ""
+			m.state.Entries[i].ErrorKind = ""
 		} else {
 			m.state.Entries[i].Status = StatusFailed
 			m.state.Entries[i].Error = errText
+			m.state.Entries[i].ErrorKind = errKind
+		}
+		break
+	}
+	return m.Save()
+}
+
+// MarkUnavailable records a failure that retrying will not fix.
+func (m *Manager) MarkUnavailable(key, title, errText, errKind string) error {
+	for i := range m.state.Entries {
+		if stateKey(m.state.Entries[i].ID, m.state.Entries[i].URL) != key {
+			continue
+		}
+		if strings.TrimSpace(title) != "" {
+			m.state.Entries[i].Title = title
+		}
+		m.state.Entries[i].Status = StatusUnavailable
+		m.state.Entries[i].Error = errText
+		m.state.Entries[i].ErrorKind = errKind
+		m.state.Entries[i].LastFinishedAt = time.Now()
+		break
+	}
+	return m.Save()
+}
+
+// SetMetadata stores what is known about an entry's video; empty fields keep
+// the saved values. It is written with the next Mark call.
+func (m *Manager) SetMetadata(key string, meta Metadata) {
+	for i := range m.state.Entries {
+		if stateKey(m.state.Entries[i].ID, m.state.Entries[i].URL) != key {
+			continue
+		}
+		e := &m.state.Entries[i].Metadata
+		if meta.Duration > 0 {
+			e.Duration = meta.Duration
+		}
+		if meta.UploadDate != "" {
+			e.UploadDate = meta.UploadDate
+		}
+		if meta.Thumbnail != "" {
+			e.Thumbnail = meta.Thumbnail
+		}
+		if meta.Description != "" {
+			e.Description = meta.Description
+		}
+		break
+	}
+}
+
+// MarkPending puts an entry back in the queue without counting a failure,
+// e.g. after the user paused it mid-download.
+func (m *Manager) MarkPending(key, note string) error {
+	for i := range m.state.Entries {
+		if stateKey(m.state.Entries[i].ID, m.state.Entries[i].URL) != key {
+			continue
+		}
+		m.state.Entries[i].Status = StatusPending
+		m.state.Entries[i].Error = note
+		m.state.Entries[i].ErrorKind = ""
+		break
+	}
+	return m.Save()
+}
+
+// MarkDeferred puts a running entry back to pending and gives back the
+// attempt it used, for failures caused by the site throttling the whole run.
+func (m *Manager) MarkDeferred(key, note string) error {
+	for i := range m.state.Entries {
+		if stateKey(m.state.Entries[i].ID, m.state.Entries[i].URL) != key {
+			continue
+		}
+		m.state.Entries[i].Status = StatusPending
+		m.state.Entries[i].Error = note
+		m.state.Entries[i].ErrorKind = ""
+		if m.state.Entries[i].Attempts > 0 {
+			m.state.Entries[i].Attempts--
 		}
 		break
 	}
 	return m.Save()
 }
 
+// MarkSkipped records that the entry was deliberately left out of a run.
+// Succeeded and unavailable entries keep their status.
+func (m *Manager) MarkSkipped(key string) error {
+	for i := range m.state.Entries {
+		if stateKey(m.state.Entries[i].ID, m.state.Entries[i].URL) != key {
+			continue
+		}
+		if m.state.Entries[i].Status == StatusSucceeded || m.state.Entries[i].Status == StatusUnavailable {
+			return nil
+		}
+		m.state.Entries[i].Status = StatusSkipped
+		m.state.Entries[i].Error = ""
+		m.state.Entries[i].ErrorKind = ""
+		break
+	}
+	return m.Save()
+}
+
 func (m *Manager) Save() error {
 	m.state.UpdatedAt = time.Now()
 	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
@@ -141,6 +306,22 @@ func (m *Manager) Save() error {
 	return os.Rename(tempPath, m.path)
 }
 
+// Load reads a saved state file without modifying it.
+func Load(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// Key returns the identifier used to address an entry in Mark* calls.
+func (e EntryState) Key() string {
+	return stateKey(e.ID, e.URL)
+}
+
 func StatePath(playlistDir string) string {
 	return filepath.Join(playlistDir, ".playlist_state.json")
 }
@@ -162,22 +343,30 @@ func (m *Manager) normalizeInterruptedRuns() {
 	}
 }
 
-func (m *Manager) mergeEntries(entries []EntryInput) {
-	index := map[string]EntryState{}
-	for _, entry := range m.state.Entries {
-		index[stateKey(entry.ID, entry.URL)] = entry
+// mergeEntries lays the saved entries over a new listing: listed entries in
+// listing order, then saved ones the listing no longer has, in their saved
+// order. Unless the listing is partial those are flagged as removed upstream.
+func (m *Manager) mergeEntries(entries []EntryInput, partial bool, now time.Time) {
+	index := map[string]int{}
+	for i, entry := range m.state.Entries {
+		index[stateKey(entry.ID, entry.URL)] = i
 	}
+	listed := map[int]bool{}
 
-	merged := make([]EntryState, 0, len(entries))
-	for _, entry := range entries {
+	merged := make([]EntryState, 0, len(entries)+len(m.state.Entries))
+	for pos, entry := range entries {
 		key := stateKey(entry.ID, entry.URL)
-		stateEntry, ok := index[key]
-		if !ok {
+		var stateEntry EntryState
+		if i, ok := index[key]; ok && !listed[i] {
+			stateEntry = m.state.Entries[i]
+			listed[i] = true
+		} else {
 			stateEntry = EntryState{
-				ID:     entry.ID,
-				URL:    entry.URL,
-				Title:  entry.Title,
-				Status: StatusPending,
+				ID:        entry.ID,
+				URL:       entry.URL,
+				Title:     entry.Title,
+				Status:    StatusPending,
+				FirstSeen: now,
 			}
 		}
 		if strings.TrimSpace(entry.ID) != "" {
@@ -186,22 +375,46 @@ func (m *Manager) mergeEntries(entries []EntryInput) {
 		if strings.TrimSpace(entry.URL) != "" {
 			stateEntry.URL = entry.URL
 		}
-		if strings.TrimSpace(entry.Title) != "" {
-			stateEntry.Title = entry.Title
+		stateEntry.ListedAs = placeholderTitles[strings.TrimSpace(entry.Title)]
+		if title := strings.TrimSpace(entry.Title); title != "" && stateEntry.ListedAs == "" {
+			stateEntry.setTitle(entry.Title, now)
 		}
 		if strings.TrimSpace(stateEntry.Status) == "" {
 			stateEntry.Status = StatusPending
 		}
+		if stateEntry.FirstSeen.IsZero() {
+			stateEntry.FirstSeen = now
+		}
+		stateEntry.LastSeen = now
+		stateEntry.PlaylistIndex = pos + 1
+		stateEntry.RemovedUpstream = false
 		merged = append(merged, stateEntry)
-		delete(index, key)
 	}
 
-	for _, leftover := range index {
+	for i, leftover := range m.state.Entries {
+		if listed[i] {
+			continue
+		}
+		leftover.PlaylistIndex = 0
+		if !partial {
+			leftover.RemovedUpstream = true
+		}
 		merged = append(merged, leftover)
 	}
 	m.state.Entries = merged
 }
 
+// setTitle renames the entry, recording the change when it had a title.
+func (e *EntryState) setTitle(title string, now time.Time) {
+	if e.Title == title {
+		return
+	}
+	if strings.TrimSpace(e.Title) != "" {
+		e.TitleHistory = append(e.TitleHistory, TitleChange{From: e.Title, To: title, At: now})
+	}
+	e.Title = title
+}
+
 func stateKey(id, url string) string {
 	if strings.TrimSpace(id) != "" {
 		return id
@@ -0,0 +1,85 @@
+package podcast
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Handler serves the feed of the playlist in dir at /feed.xml and its
+// episodes below MediaPrefix, with range requests so players can seek. The
+// state is read on every request, so new downloads show up without a
+// restart. Without opts.BaseURL, episode links point back at the host and
+// path the feed was requested from, which also works behind
+// http.StripPrefix.
+func Handler(dir string, opts Options) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, path.Join(requestPath(r), "feed.xml"), http.StatusFound)
+	})
+	mux.HandleFunc("GET /feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		o := opts
+		if o.BaseURL == "" {
+			o.BaseURL = requestBase(r)
+		}
+		data, err := Build(dir, o)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		_, _ = w.Write(data)
+	})
+	mux.HandleFunc("GET "+MediaPrefix+"{file...}", func(w http.ResponseWriter, r *http.Request) {
+		files, err := episodes(dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		file, ok := files[r.PathValue("file")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", mediaType(file))
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+	return mux
+}
+
+// requestPath is the directory of the request as the client sent it, before
+// any prefix was stripped.
+func requestPath(r *http.Request) string {
+	p := r.URL.Path
+	if r.RequestURI != "" {
+		p, _, _ = strings.Cut(r.RequestURI, "?")
+	}
+	if strings.HasSuffix(p, "/") {
+		return p
+	}
+	return path.Dir(p) + "/"
+}
+
+// requestBase is the URL the feed was requested under, minus "feed.xml".
+func requestBase(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + strings.TrimSuffix(requestPath(r), "/")
+}
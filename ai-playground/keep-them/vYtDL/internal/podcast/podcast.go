@@ -0,0 +1,296 @@
+// Package podcast publishes a downloaded playlist directory as a podcast:
+// an RSS 2.0 feed with iTunes tags built from the playlist state, and an HTTP
+// handler serving that feed and the media files it points to.
+package podcast
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/innate/yt-dl/internal/playliststate"
+)
+
+// MediaPrefix is the URL path, below the base URL, media files are served at.
+const MediaPrefix = "/media/"
+
+// Options describes the channel of a generated feed. Empty fields fall back
+// to the playlist state.
+type Options struct {
+	// BaseURL is where the feed is published, e.g. http://nas.local:8080.
+	// Enclosure URLs are BaseURL + MediaPrefix + the file's path in the
+	// playlist directory.
+	BaseURL     string
+	Title       string
+	Description string
+	Author      string
+	Image       string // channel artwork URL
+	Language    string // e.g. "en"
+	Explicit    bool
+}
+
+// Build renders the feed for the playlist in dir.
+func Build(dir string, opts Options) ([]byte, error) {
+	state, err := playliststate.Load(playliststate.StatePath(dir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s has no playlist state; download a playlist into it first", dir)
+		}
+		return nil, err
+	}
+	return BuildState(dir, state, opts)
+}
+
+// BuildState renders the feed for a loaded state. Only entries that finished
+// and whose file is still in dir become episodes.
+func BuildState(dir string, state playliststate.State, opts Options) ([]byte, error) {
+	base := strings.TrimSuffix(strings.TrimSpace(opts.BaseURL), "/")
+	if _, err := url.ParseRequestURI(base); base == "" || err != nil {
+		return nil, fmt.Errorf("a base URL such as http://host:8080 is needed for the episode links")
+	}
+
+	ch := channel{
+		Title:       firstNonEmpty(opts.Title, state.PlaylistTitle, filepath.Base(dir)),
+		Link:        firstNonEmpty(state.PlaylistURL, base+"/"),
+		Self:        atomLink{Href: base + "/feed.xml", Rel: "self", Type: "application/rss+xml"},
+		Description: firstNonEmpty(opts.Description, "Downloaded from "+state.PlaylistURL),
+		Language:    opts.Language,
+		Generator:   "yt-dl",
+		Author:      opts.Author,
+		Explicit:    yesNo(opts.Explicit),
+		Type:        "episodic",
+	}
+	if opts.Image != "" {
+		ch.Image = &itunesImage{Href: opts.Image}
+	}
+
+	var newest time.Time
+	for i, entry := range state.Entries {
+		if entry.Status != playliststate.StatusSucceeded || entry.Filename == "" {
+			continue
+		}
+		rel, size, ok := mediaFile(dir, entry.Filename)
+		if !ok {
+			continue
+		}
+		published := entry.LastFinishedAt
+		if t, err := time.Parse("20060102", entry.UploadDate); err == nil {
+			published = t
+		}
+		if published.After(newest) {
+			newest = published
+		}
+		it := item{
+			Title:       entry.Title,
+			Description: entry.Description,
+			Link:        entry.URL,
+			GUID:        guid{IsPermaLink: "false", Value: firstNonEmpty(entry.ID, entry.URL)},
+			Enclosure:   enclosure{URL: base + MediaPrefix + escapePath(rel), Length: size, Type: mediaType(rel)},
+			Duration:    formatDuration(entry.Duration),
+			Episode:     i + 1,
+			Explicit:    ch.Explicit,
+		}
+		if !published.IsZero() {
+			it.PubDate = published.UTC().Format(time.RFC1123Z)
+		}
+		if entry.Thumbnail != "" {
+			it.Image = &itunesImage{Href: entry.Thumbnail}
+		}
+		ch.Items = append(ch.Items, it)
+	}
+	if ch.Image == nil {
+		// Podcast apps want channel artwork; borrow the first thumbnail.
+		for _, it := range ch.Items {
+			if it.Image != nil {
+				ch.Image = &itunesImage{Href: it.Image.Href}
+				break
+			}
+		}
+	}
+	if !newest.IsZero() {
+		ch.LastBuildDate = newest.UTC().Format(time.RFC1123Z)
+	}
+
+	out, err := xml.MarshalIndent(rss{Version: "2.0", ITunes: itunesNS, Atom: atomNS, Channel: ch}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
+
+// Write renders the feed for the playlist in dir to w.
+func Write(w io.Writer, dir string, opts Options) error {
+	data, err := Build(dir, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+const (
+	itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	atomNS   = "http://www.w3.org/2005/Atom"
+)
+
+type rss struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	ITunes  string   `xml:"xmlns:itunes,attr"`
+	Atom    string   `xml:"xmlns:atom,attr"`
+	Channel channel  `xml:"channel"`
+}
+
+type channel struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link"`
+	Self          atomLink     `xml:"atom:link"`
+	Description   string       `xml:"description"`
+	Language      string       `xml:"language,omitempty"`
+	Generator     string       `xml:"generator"`
+	LastBuildDate string       `xml:"lastBuildDate,omitempty"`
+	Author        string       `xml:"itunes:author,omitempty"`
+	Explicit      string       `xml:"itunes:explicit"`
+	Type          string       `xml:"itunes:type"`
+	Image         *itunesImage `xml:"itunes:image"`
+	Items         []item       `xml:"item"`
+}
+
+type item struct {
+	Title       string       `xml:"title"`
+	Description string       `xml:"description,omitempty"`
+	Link        string       `xml:"link,omitempty"`
+	GUID        guid         `xml:"guid"`
+	PubDate     string       `xml:"pubDate,omitempty"`
+	Enclosure   enclosure    `xml:"enclosure"`
+	Duration    string       `xml:"itunes:duration,omitempty"`
+	Episode     int          `xml:"itunes:episode,omitempty"`
+	Explicit    string       `xml:"itunes:explicit"`
+	Image       *itunesImage `xml:"itunes:image"`
+}
+
+type guid struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// mediaFile finds the file a state entry names and returns its path relative
+// to dir. State paths are relative to where the download ran, so a file that
+// is not found there is looked up by name in dir. Files outside dir are never
+// published.
+func mediaFile(dir, filename string) (rel string, size int64, ok bool) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", 0, false
+	}
+	for _, path := range []string{filename, filepath.Join(dir, filepath.Base(filename))} {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absDir, absPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return filepath.ToSlash(rel), info.Size(), true
+	}
+	return "", 0, false
+}
+
+// mediaTypes covers containers mime.TypeByExtension often does not know.
+var mediaTypes = map[string]string{
+	".mp4": "video/mp4", ".m4v": "video/x-m4v", ".mov": "video/quicktime",
+	".mkv": "video/x-matroska", ".webm": "video/webm",
+	".mp3": "audio/mpeg", ".m4a": "audio/mp4", ".aac": "audio/aac",
+	".opus": "audio/ogg", ".ogg": "audio/ogg", ".flac": "audio/flac", ".wav": "audio/wav",
+}
+
+func mediaType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if t, ok := mediaTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// formatDuration renders seconds as HH:MM:SS for itunes:duration.
+func formatDuration(seconds float64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	s := int(seconds + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+func escapePath(rel string) string {
+	parts := strings.Split(rel, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// episodes maps the media paths a feed for dir links to, relative to dir, to
+// their files. The handler serves only these.
+func episodes(dir string) (map[string]string, error) {
+	state, err := playliststate.Load(playliststate.StatePath(dir))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, entry := range state.Entries {
+		if entry.Status != playliststate.StatusSucceeded || entry.Filename == "" {
+			continue
+		}
+		if rel, _, ok := mediaFile(dir, entry.Filename); ok {
+			files[rel] = filepath.Join(dir, filepath.FromSlash(rel))
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,137 @@
+package podcast
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/innate/yt-dl/internal/playliststate"
+)
+
+// lectures writes a playlist directory with two downloaded entries, one
+// whose file is gone and one that failed.
+func lectures(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "Lectures")
+	entries := []playliststate.EntryInput{{ID: "l1", Title: "Week 1"}, {ID: "l2", Title: "Week 2 & Q/A"}, {ID: "gone", Title: "Gone"}, {ID: "bad", Title: "Bad"}}
+	mgr, err := playliststate.Open(playliststate.StatePath(dir), "https://www.youtube.com/playlist?list=PLx", "Lectures", dir, entries, playliststate.MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish := func(id, file string, meta playliststate.Metadata) {
+		path := filepath.Join(dir, file)
+		if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_ = mgr.MarkRunning(id)
+		mgr.SetMetadata(id, meta)
+		_ = mgr.MarkFinished(id, "", path, nil, true, "", "")
+	}
+	finish("l1", "Week 1.m4a", playliststate.Metadata{Duration: 3725, UploadDate: "20250901", Thumbnail: "https://i.ytimg.com/vi/l1/hq.jpg", Description: "Intro"})
+	finish("l2", "Week 2 & Q_A.mp4", playliststate.Metadata{})
+	finish("gone", "Gone.mp4", playliststate.Metadata{})
+	if err := os.Remove(filepath.Join(dir, "Gone.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	_ = mgr.MarkRunning("bad")
+	_ = mgr.MarkFinished("bad", "", "", nil, false, "boom", "network")
+	return dir
+}
+
+type feedDoc struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Image struct {
+			Href string `xml:"href,attr"`
+		} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		Items []struct {
+			Title     string `xml:"title"`
+			GUID      string `xml:"guid"`
+			PubDate   string `xml:"pubDate"`
+			Duration  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+			Enclosure struct {
+				URL    string `xml:"url,attr"`
+				Length int64  `xml:"length,attr"`
+				Type   string `xml:"type,attr"`
+			} `xml:"enclosure"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+func TestBuildListsDownloadedEpisodes(t *testing.T) {
+	dir := lectures(t)
+	data, err := Build(dir, Options{BaseURL: "http://nas.local:8080/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc feedDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("feed is not valid XML: %v\n%s", err, data)
+	}
+	ch := doc.Channel
+	if ch.Title != "Lectures" || len(ch.Items) != 2 || ch.Image.Href != "https://i.ytimg.com/vi/l1/hq.jpg" {
+		t.Fatalf("channel = %+v", ch)
+	}
+	first, second := ch.Items[0], ch.Items[1]
+	if first.GUID != "l1" || first.Duration != "01:02:05" || first.PubDate != "Mon, 01 Sep 2025 00:00:00 +0000" ||
+		first.Enclosure.URL != "http://nas.local:8080/media/Week%201.m4a" || first.Enclosure.Length != 10 || first.Enclosure.Type != "audio/mp4" {
+		t.Errorf("first episode = %+v", first)
+	}
+	if second.Title != "Week 2 & Q/A" || second.Enclosure.URL != "http://nas.local:8080/media/Week%202%20&%20Q_A.mp4" || second.Enclosure.Type != "video/mp4" {
+		t.Errorf("second episode = %+v", second)
+	}
+
+	if _, err := Build(dir, Options{}); err == nil {
+		t.Error("built a feed without a base URL")
+	}
+	if _, err := Build(t.TempDir(), Options{BaseURL: "http://x"}); err == nil {
+		t.Error("built a feed for a directory without state")
+	}
+}
+
+func TestHandlerServesFeedAndRanges(t *testing.T) {
+	dir := lectures(t)
+	mux := http.NewServeMux()
+	mux.Handle("/podcast/", http.StripPrefix("/podcast", Handler(dir, Options{})))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/podcast/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), srv.URL+"/podcast/media/Week%201.m4a") {
+		t.Fatalf("feed %d:\n%s", resp.StatusCode, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/podcast/media/Week%201.m4a", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "2345" || resp.Header.Get("Content-Type") != "audio/mp4" {
+		t.Fatalf("range = %d %q %s", resp.StatusCode, body, resp.Header.Get("Content-Type"))
+	}
+
+	// Only episodes are served: not the state file, not other paths.
+	for _, path := range []string{"/podcast/media/.playlist_state.json", "/podcast/media/../Lectures/Week%201.m4a", "/podcast/media/Gone.mp4"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("%s served", path)
+		}
+	}
+}
@@ -19,8 +19,16 @@ type DownloadRecord struct {
 	URL        string    `json:"url"         csv:"url"`
 	OutputDir  string    `json:"output_dir"  csv:"output_dir"`
 	Filename   string    `json:"filename"    csv:"filename"`
+	FormatID   string    `json:"format_id,omitempty"  csv:"format_id"`
+	Resolution string    `json:"resolution,omitempty" csv:"resolution"`
+	VCodec     string    `json:"vcodec,omitempty"     csv:"vcodec"`
+	ACodec     string    `json:"acodec,omitempty"     csv:"acodec"`
 	Success    bool      `json:"success"     csv:"success"`
 	Error      string    `json:"error"       csv:"error"`
+	ErrorKind  string    `json:"error_kind,omitempty" csv:"error_kind"`
+	Stderr     string    `json:"stderr,omitempty"     csv:"stderr"`
+	HookError  string    `json:"hook_error,omitempty" csv:"hook_error"`
+	LogFile    string    `json:"log_file,omitempty"   csv:"log_file"`
 	StartedAt  time.Time `json:"started_at"  csv:"started_at"`
 	FinishedAt time.Time `json:"finished_at" csv:"finished_at"`
 	Duration   string    `json:"duration"    csv:"duration"`
@@ -43,8 +51,16 @@ func FromResult(r downloader.DownloadResult) DownloadRecord {
 		URL:        r.URL,
 		OutputDir:  r.OutputDir,
 		Filename:   r.Filename,
+		FormatID:   r.FormatID,
+		Resolution: r.Resolution,
+		VCodec:     r.VCodec,
+		ACodec:     r.ACodec,
 		Success:    r.Success,
 		Error:      r.Error,
+		ErrorKind:  string(r.ErrorKind),
+		Stderr:     r.Stderr,
+		HookError:  r.HookError,
+		LogFile:    r.LogFile,
 		StartedAt:  r.StartedAt,
 		FinishedAt: r.FinishedAt,
 		Duration:   dur,
@@ -149,6 +165,8 @@ func writeJSON(path string, v any) error {
 var recordCSVHeader = []string{
 	"video_id", "title", "url", "output_dir", "filename",
 	"success", "error", "started_at", "finished_at", "duration",
+	"format_id", "resolution", "vcodec", "acodec",
+	"error_kind", "stderr", "hook_error", "log_file",
 }
 
 func writeCSVRecords(path string, records []DownloadRecord) error {
@@ -167,6 +185,8 @@ func writeCSVRecords(path string, records []DownloadRecord) error {
 			r.StartedAt.Format(time.RFC3339),
 			r.FinishedAt.Format(time.RFC3339),
 			r.Duration,
+			r.FormatID, r.Resolution, r.VCodec, r.ACodec,
+			r.ErrorKind, r.Stderr, r.HookError, r.LogFile,
 		}
 		_ = w.Write(row)
 	}
@@ -238,6 +258,22 @@ func readCSVRecords(path string) []DownloadRecord {
 		}
 		startedAt, _ := time.Parse(time.RFC3339, row[7])
 		finishedAt, _ := time.Parse(time.RFC3339, row[8])
+		// Format columns were added later; older files stop at duration.
+		format := make([]string, 4)
+		if len(row) >= 14 {
+			copy(format, row[10:14])
+		}
+		var errorKind, stderr string
+		if len(row) >= 16 {
+			errorKind, stderr = row[14], row[15]
+		}
+		var hookError, logFile string
+		if len(row) >= 17 {
+			hookError = row[16]
+		}
+		if len(row) >= 18 {
+			logFile = row[17]
+		}
 		records = append(records, DownloadRecord{
 			VideoID:    row[0],
 			Title:      row[1],
@@ -249,6 +285,14 @@ func readCSVRecords(path string) []DownloadRecord {
 			StartedAt:  startedAt,
 			FinishedAt: finishedAt,
 			Duration:   row[9],
+			FormatID:   format[0],
+			Resolution: format[1],
+			VCodec:     format[2],
+			ACodec:     format[3],
+			ErrorKind:  errorKind,
+			Stderr:     stderr,
+			HookError:  hookError,
+			LogFile:    logFile,
 		})
 	}
 	return records
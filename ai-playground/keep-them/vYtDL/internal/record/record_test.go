@@ -119,6 +119,34 @@ func TestManagerLoadsExistingJSONOnRerun(t *testing.T) {
 	}
 }
 
+func TestManagerCSVKeepsErrorKindOnRerun(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	failed := sampleResult(dir)
+	failed.Success = false
+	failed.Error = downloader.ErrorPrivate.Message()
+	failed.ErrorKind = downloader.ErrorPrivate
+	failed.Stderr = "ERROR: [youtube] abc123: Private video\nSign in if you've been granted access"
+	failed.HookError = "webhook: HTTP 502 Bad Gateway"
+	failed.LogFile = filepath.Join(dir, ".logs", "abc123.log")
+
+	first := NewManager("csv", "downloads", "mapping", dir)
+	first.Add(failed)
+	if err := first.Flush(); err != nil {
+		t.Fatalf("flush csv: %v", err)
+	}
+
+	second := NewManager("csv", "downloads", "mapping", dir)
+	if len(second.records) != 1 {
+		t.Fatalf("expected 1 reloaded record, got %#v", second.records)
+	}
+	got := second.records[0]
+	if got.ErrorKind != "private" || got.Stderr != failed.Stderr || got.Error != failed.Error || got.HookError != failed.HookError || got.LogFile != failed.LogFile {
+		t.Fatalf("error fields not preserved: %#v", got)
+	}
+}
+
 func sampleResult(dir string) downloader.DownloadResult {
 	start := time.Date(2026, 3, 18, 10, 0, 0, 0, time.UTC)
 	end := start.Add(45 * time.Second)
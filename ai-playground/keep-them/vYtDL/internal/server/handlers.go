@@ -0,0 +1,555 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/innate/yt-dl/internal/playliststate"
+	"github.com/innate/yt-dl/internal/podcast"
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+// filesPrefix is the URL path files in the output directory are served at.
+const filesPrefix = "/files/"
+
+// podcastPrefix is the URL path playlist podcast feeds are served under.
+const podcastPrefix = "/podcast/"
+
+// scanDepth is how many directory levels below the output directory are
+// searched for playlists and record files.
+const scanDepth = 3
+
+// Handler returns the API and the web UI:
+//
+//	GET    /api/jobs                  all jobs
+//	POST   /api/jobs                  queue a Request
+//	DELETE /api/jobs/{id}             cancel a queued or running job
+//	GET    /api/items                 latest state of every download
+//	POST   /api/commands              {"action": "cancel", "key": "…"} for the running job
+//	GET    /api/events                server-sent "jobs" and "progress" events
+//	GET    /api/playlists             playlists in the output directory
+//	GET    /api/playlist?dir=…        one playlist's state
+//	POST   /api/playlist/retry        {"dir": "…", "keys": […]}; no keys retries failed entries
+//	GET    /api/records               record and subtitle mapping files
+//	GET    /files/{path…}             a file in the output directory
+//	GET    /podcast/{dir…}/feed.xml   a playlist as a podcast feed, its media under /podcast/{dir…}/media/
+//
+// There is no authentication. Requests addressed to a host outside
+// Config.AllowedHosts are refused, and so are requests that change something
+// when they come from another site's page: POST bodies must be JSON, which a
+// page can only send to another origin after a CORS preflight this server
+// never answers, and a foreign Origin header is rejected.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Jobs())
+	})
+	mux.HandleFunc("POST /api/jobs", s.handleSubmit)
+	mux.HandleFunc("DELETE /api/jobs/{id}", s.handleCancel)
+	mux.HandleFunc("GET /api/items", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Items())
+	})
+	mux.HandleFunc("POST /api/commands", s.handleCommand)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	mux.HandleFunc("GET /api/playlists", s.handlePlaylists)
+	mux.HandleFunc("GET /api/playlist", s.handlePlaylist)
+	mux.HandleFunc("POST /api/playlist/retry", s.handleRetry)
+	mux.HandleFunc("GET /api/records", s.handleRecords)
+	mux.HandleFunc("GET "+filesPrefix+"{path...}", s.handleFile)
+	mux.HandleFunc("GET "+podcastPrefix+"{path...}", s.handlePodcast)
+	mux.Handle("GET /", http.FileServerFS(uiFS))
+	return s.guard(mux)
+}
+
+// guard refuses requests for other hosts and cross-site requests that would
+// change something.
+func (s *Server) guard(next http.Handler) http.Handler {
+	allowed := map[string]bool{}
+	for _, host := range s.cfg.AllowedHosts {
+		allowed[strings.ToLower(strings.Trim(host, "[]"))] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(allowed) > 0 && !allowed[hostName(r.Host)] {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not served here", r.Host))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if origin := r.Header.Get("Origin"); origin != "" {
+				if u, err := url.Parse(origin); err != nil || !strings.EqualFold(u.Host, r.Host) {
+					writeError(w, http.StatusForbidden, fmt.Errorf("cross-site request from %s refused", origin))
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostName is the lower-case host of a Host header, without port or brackets.
+func hostName(hostport string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	return strings.ToLower(strings.Trim(host, "[]"))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// errNotJSON is returned by decode for bodies not sent as JSON. Requiring
+// the type keeps other sites' pages from posting "simple" form or text
+// requests.
+var errNotJSON = errors.New("request body must be sent as Content-Type: application/json")
+
+// decode reads a JSON body, rejecting unknown fields like inbox sidecars do.
+func decode(w http.ResponseWriter, r *http.Request, v any) error {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		return errNotJSON
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// decodeStatus is the response status for an error from decode.
+func decodeStatus(err error) int {
+	if errors.Is(err, errNotJSON) {
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := decode(w, r, &req); err != nil {
+		writeError(w, decodeStatus(err), err)
+		return
+	}
+	job, err := s.Submit(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || !s.Cancel(id) {
+		writeError(w, http.StatusNotFound, errors.New("no queued or running job with that ID"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCommand(w http.ResponseWriter, r *http.Request) {
+	var cmd struct {
+		Action string `json:"action"`
+		Key    string `json:"key"`
+	}
+	if err := decode(w, r, &cmd); err != nil {
+		writeError(w, decodeStatus(err), err)
+		return
+	}
+	action := ytdl.CommandAction(cmd.Action)
+	switch action {
+	case ytdl.CommandCancel, ytdl.CommandRetry, ytdl.CommandPause, ytdl.CommandResume:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown action %q: use cancel, retry, pause or resume", cmd.Action))
+		return
+	}
+	if cmd.Key == "" {
+		writeError(w, http.StatusBadRequest, errors.New("no key"))
+		return
+	}
+	if !s.Send(ytdl.Command{Action: action, Key: cmd.Key}) {
+		writeError(w, http.StatusConflict, errors.New("no job is running"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEvents streams "jobs" events with the full job list whenever a job
+// changes and "progress" events with an Item whenever a download moves. A
+// new stream starts with the current state.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	// Subscribe before taking the snapshot so nothing falls in between.
+	sub := s.bus.Subscribe(0, ytdl.CoalesceProgress)
+	defer sub.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	send := func(event string, v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	}
+	// Take the change channel before each job snapshot so a change between
+	// the two is not missed.
+	changed := s.jobsChanged()
+	send("jobs", s.Jobs())
+	for _, it := range s.Items() {
+		send("progress", it)
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case u, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if it, ok := s.item(u.Key); ok {
+				send("progress", it)
+			}
+		case <-changed:
+			changed = s.jobsChanged()
+			send("jobs", s.Jobs())
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// PlaylistSummary describes one playlist directory below the output
+// directory.
+type PlaylistSummary struct {
+	Dir       string         `json:"dir"` // relative to the output directory, with slashes
+	Title     string         `json:"title"`
+	URL       string         `json:"url"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Entries   int            `json:"entries"`
+	Counts    map[string]int `json:"counts"` // entries by status
+	Feed      string         `json:"feed"`   // podcast feed URL
+}
+
+func (s *Server) handlePlaylists(w http.ResponseWriter, r *http.Request) {
+	dirs, err := s.playlistDirs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	summaries := []PlaylistSummary{}
+	for _, dir := range dirs {
+		state, err := playliststate.Load(playliststate.StatePath(filepath.Join(s.root, filepath.FromSlash(dir))))
+		if err != nil {
+			s.cfg.Logger.Warn("playlist state unreadable", "dir", dir, "err", err)
+			continue
+		}
+		sum := PlaylistSummary{Dir: dir, Title: state.PlaylistTitle, URL: state.PlaylistURL,
+			UpdatedAt: state.UpdatedAt, Entries: len(state.Entries), Counts: map[string]int{},
+			Feed: podcastLink(dir) + "feed.xml"}
+		for _, e := range state.Entries {
+			sum.Counts[e.Status]++
+		}
+		summaries = append(summaries, sum)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].UpdatedAt.After(summaries[j].UpdatedAt) })
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// PlaylistEntry is an entry of a playlist's state with links to its files.
+type PlaylistEntry struct {
+	playliststate.EntryState
+	Key       string   `json:"key"`
+	File      string   `json:"file,omitempty"`
+	SubFiles  []string `json:"subtitle_files,omitempty"`
+	Retryable bool     `json:"retryable"`
+}
+
+// PlaylistDetail is a playlist's full state.
+type PlaylistDetail struct {
+	Dir       string          `json:"dir"`
+	Title     string          `json:"title"`
+	URL       string          `json:"url"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Entries   []PlaylistEntry `json:"entries"`
+}
+
+func (s *Server) handlePlaylist(w http.ResponseWriter, r *http.Request) {
+	dir, state, err := s.loadPlaylist(r.URL.Query().Get("dir"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	detail := PlaylistDetail{Dir: dir, Title: state.PlaylistTitle, URL: state.PlaylistURL,
+		UpdatedAt: state.UpdatedAt, Entries: []PlaylistEntry{}}
+	abs := filepath.Join(s.root, filepath.FromSlash(dir))
+	for _, e := range state.Entries {
+		entry := PlaylistEntry{EntryState: e, Key: e.Key(), Retryable: retryable(e)}
+		if e.Status == playliststate.StatusSucceeded {
+			entry.File = s.entryFile(abs, e.Filename)
+			for _, sub := range e.Subtitles {
+				if link := s.entryFile(abs, sub); link != "" {
+					entry.SubFiles = append(entry.SubFiles, link)
+				}
+			}
+		}
+		detail.Entries = append(detail.Entries, entry)
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (s *Server) handleRetry(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Dir  string   `json:"dir"`
+		Keys []string `json:"keys,omitempty"`
+	}
+	if err := decode(w, r, &body); err != nil {
+		writeError(w, decodeStatus(err), err)
+		return
+	}
+	dir, state, err := s.loadPlaylist(body.Dir)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	keys := body.Keys
+	if len(keys) == 0 {
+		for _, e := range state.Entries {
+			if retryable(e) {
+				keys = append(keys, e.Key())
+			}
+		}
+	}
+	if len(keys) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no failed entries to retry"))
+		return
+	}
+
+	// The playlist directory is named after the playlist inside the job's
+	// output directory, so the job writes to its parent.
+	parent := path.Dir(dir)
+	if parent == "." {
+		parent = ""
+	}
+	playlist := true
+	req := Request{URLs: []string{state.PlaylistURL}, SelectedEntries: keys}
+	req.Output = filepath.FromSlash(parent)
+	req.Playlist = &playlist
+	job, err := s.Submit(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// retryable reports whether an entry is retried by default.
+func retryable(e playliststate.EntryState) bool {
+	return e.Status == playliststate.StatusFailed || e.Status == playliststate.StatusUnavailable
+}
+
+// RecordFile is a download record or subtitle mapping file.
+type RecordFile struct {
+	Kind      string    `json:"kind"` // "record" or "mapping"
+	File      string    `json:"file"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (s *Server) handleRecords(w http.ResponseWriter, r *http.Request) {
+	names := map[string]string{}
+	for _, ext := range []string{".json", ".csv"} {
+		names[filepath.Base(s.cfg.Defaults.RecordFile)+ext] = "record"
+		names[filepath.Base(s.cfg.Defaults.MappingFile)+ext] = "mapping"
+	}
+	files := []RecordFile{}
+	err := s.walk(func(rel string, d fs.DirEntry) {
+		kind, ok := names[d.Name()]
+		if !ok || d.IsDir() {
+			return
+		}
+		info, err := d.Info()
+		if err != nil {
+			return
+		}
+		files = append(files, RecordFile{Kind: kind, File: filesPrefix + escapePath(rel),
+			Size: info.Size(), UpdatedAt: info.ModTime()})
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, files)
+}
+
+// handleFile serves a file from the output directory. Hidden files and
+// directories, such as the state files and tool logs, are not served.
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	rel := r.PathValue("path")
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || strings.HasPrefix(part, ".") {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	root, err := os.OpenRoot(s.root)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer root.Close()
+	f, err := root.Open(filepath.FromSlash(rel))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	if r.URL.Query().Has("download") {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", info.Name()))
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// playlistDirs lists the directories below the output directory that hold a
+// playlist state, relative and with slashes.
+func (s *Server) playlistDirs() ([]string, error) {
+	var dirs []string
+	err := s.walk(func(rel string, d fs.DirEntry) {
+		if !d.IsDir() {
+			return
+		}
+		if _, err := os.Stat(playliststate.StatePath(filepath.Join(s.root, filepath.FromSlash(rel)))); err == nil {
+			dirs = append(dirs, rel)
+		}
+	})
+	return dirs, err
+}
+
+// walk calls fn for the files and directories up to scanDepth levels below
+// the output directory, skipping hidden ones. rel uses slashes.
+func (s *Server) walk(fn func(rel string, d fs.DirEntry)) error {
+	return fs.WalkDir(os.DirFS(s.root), ".", func(rel string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if rel == "." {
+				return err
+			}
+			return nil
+		}
+		if rel == "." {
+			if d.IsDir() {
+				fn(rel, d)
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		fn(rel, d)
+		if d.IsDir() && strings.Count(rel, "/")+1 >= scanDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+}
+
+// loadPlaylist reads the state of a playlist directory named by the API.
+func (s *Server) loadPlaylist(dir string) (string, playliststate.State, error) {
+	dir = path.Clean("/" + dir)[1:]
+	if dir == "" {
+		dir = "."
+	}
+	state, err := playliststate.Load(playliststate.StatePath(filepath.Join(s.root, filepath.FromSlash(dir))))
+	if err != nil {
+		return dir, state, fmt.Errorf("no playlist state in %q", dir)
+	}
+	return dir, state, nil
+}
+
+// handlePodcast serves a playlist directory's podcast feed and media. The
+// playlist is the longest leading part of the path that holds a playlist
+// state; the rest is handed to podcast.Handler.
+func (s *Server) handlePodcast(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(path.Clean("/" + r.PathValue("path"))[1:], "/")
+	for n := min(len(parts), scanDepth); n >= 0; n-- {
+		dir := path.Join(parts[:n]...)
+		if dir == "" {
+			dir = "."
+		}
+		abs := filepath.Join(s.root, filepath.FromSlash(dir))
+		if _, err := os.Stat(playliststate.StatePath(abs)); err != nil {
+			continue
+		}
+		rest := "/" + path.Join(parts[n:]...)
+		if rest == "/" && !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, podcastLink(dir), http.StatusFound)
+			return
+		}
+		// Like http.StripPrefix, but the escaped path is dropped: the feed
+		// takes its base URL from the request URI, not from r.URL.
+		r2 := r.Clone(r.Context())
+		r2.URL.Path, r2.URL.RawPath = rest, ""
+		podcast.Handler(abs, podcast.Options{}).ServeHTTP(w, r2)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// podcastLink is the URL a playlist directory's podcast feed is served under,
+// with a trailing slash.
+func podcastLink(dir string) string {
+	if dir == "." {
+		return podcastPrefix
+	}
+	return podcastPrefix + (&url.URL{Path: dir}).EscapedPath() + "/"
+}
+
+// entryFile links a file named in a playlist state. State paths are relative
+// to where the download ran, so a file not found there is looked up by name
+// in the playlist directory.
+func (s *Server) entryFile(playlistDir, filename string) string {
+	if filename == "" {
+		return ""
+	}
+	for _, p := range []string{filename, filepath.Join(playlistDir, filepath.Base(filename))} {
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+			return s.fileLink(p)
+		}
+	}
+	return ""
+}
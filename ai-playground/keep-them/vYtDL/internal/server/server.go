@@ -0,0 +1,433 @@
+// Package server runs downloads submitted over HTTP: a JSON API for adding
+// URLs, following progress as server-sent events, browsing playlist state and
+// retrying failed entries, plus a small web UI built on that API.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innate/yt-dl/internal/inbox"
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+// Job statuses.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Config configures a Server.
+type Config struct {
+	// Defaults are the options of every job; a request's settings go on top.
+	// Defaults.OutputDir is also the directory the API browses and serves
+	// files from.
+	Defaults ytdl.Options
+	Logger   *slog.Logger
+	// ClientOptions are added to every job's client, e.g. ytdl.WithBackend.
+	ClientOptions []ytdl.Option
+	// AllowedHosts are the host names requests may be addressed to, without
+	// ports. Other Host headers are refused, so a web page cannot reach the
+	// API through DNS rebinding. Empty accepts any host.
+	AllowedHosts []string
+}
+
+// Request is the body of POST /api/jobs: the URLs and the same per-download
+// settings an inbox sidecar file accepts.
+type Request struct {
+	URLs []string `json:"urls"`
+	inbox.Sidecar
+	// SelectedEntries restricts playlist URLs to these entry keys.
+	SelectedEntries []string `json:"selected_entries,omitempty"`
+}
+
+// validate rejects requests that cannot run. Output must stay inside the
+// server's output directory, since it is also what the API serves.
+func (req Request) validate() error {
+	if len(req.URLs) == 0 {
+		return errors.New("no URLs")
+	}
+	for _, raw := range req.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("not an http(s) URL: %q", raw)
+		}
+	}
+	if out := req.Output; out != "" {
+		if filepath.IsAbs(out) || !filepath.IsLocal(out) {
+			return fmt.Errorf("output %q must be a directory inside the output directory", out)
+		}
+	}
+	return req.Sidecar.Validate()
+}
+
+func (req Request) jobs() []ytdl.Job {
+	jobs := req.Sidecar.Jobs(req.URLs)
+	for i := range jobs {
+		if jobs[i].Playlist {
+			jobs[i].SelectedEntries = req.SelectedEntries
+		}
+	}
+	return jobs
+}
+
+// Job is one submitted request and how it went.
+type Job struct {
+	ID         int        `json:"id"`
+	Request    Request    `json:"request"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Warnings   []string   `json:"warnings,omitempty"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	Skipped    int        `json:"skipped"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Item is the latest state of one download, keyed like ytdl.Progress. The
+// JSON field names follow the ndjson output mode.
+type Item struct {
+	Key        string     `json:"key"`
+	JobID      int        `json:"job_id"`
+	VideoID    string     `json:"video_id,omitempty"`
+	Title      string     `json:"title,omitempty"`
+	Status     string     `json:"status"`
+	Percent    float64    `json:"percent,omitempty"`
+	Speed      string     `json:"speed,omitempty"`
+	ETA        string     `json:"eta,omitempty"`
+	TotalBytes int64      `json:"total_bytes,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ErrorKind  string     `json:"error_kind,omitempty"`
+	HookError  string     `json:"hook_error,omitempty"`
+	ResumeAt   *time.Time `json:"resume_at,omitempty"`
+	Attempt    int        `json:"attempt,omitempty"`
+	// File and Subtitles are paths below /files/, set on "done" when the
+	// files are inside the output directory.
+	File      string    `json:"file,omitempty"`
+	Subtitles []string  `json:"subtitles,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Server queues jobs and runs them one at a time.
+type Server struct {
+	cfg  Config
+	root string // absolute Defaults.OutputDir
+	bus  *ytdl.Bus
+
+	mu      sync.Mutex
+	jobs    []*Job
+	queue   []*Job
+	wake    chan struct{}
+	items   map[string]*Item
+	order   []string
+	active  *ytdl.Client
+	cancel  map[int]context.CancelFunc // running job → its cancel
+	changed chan struct{}              // closed and replaced when jobs change
+}
+
+// New returns a server; call Run to start processing jobs.
+func New(cfg Config) (*Server, error) {
+	if cfg.Defaults.OutputDir == "" {
+		cfg.Defaults.OutputDir = "."
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.DiscardHandler)
+	}
+	root, err := filepath.Abs(cfg.Defaults.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		cfg:     cfg,
+		root:    root,
+		bus:     ytdl.NewBus(),
+		wake:    make(chan struct{}, 1),
+		items:   map[string]*Item{},
+		cancel:  map[int]context.CancelFunc{},
+		changed: make(chan struct{}),
+	}, nil
+}
+
+// Run processes queued jobs until ctx ends, then stops the running job and
+// ends all event streams.
+func (s *Server) Run(ctx context.Context) {
+	defer s.bus.Close()
+	for {
+		job := s.next()
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+				continue
+			}
+		}
+		s.run(ctx, job)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Submit queues a request and returns its job.
+func (s *Server) Submit(req Request) (Job, error) {
+	if err := req.validate(); err != nil {
+		return Job{}, err
+	}
+	s.mu.Lock()
+	job := &Job{ID: len(s.jobs) + 1, Request: req, Status: JobQueued, CreatedAt: time.Now()}
+	s.jobs = append(s.jobs, job)
+	s.queue = append(s.queue, job)
+	queued := *job
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	s.cfg.Logger.Info("job queued", "job", queued.ID, "urls", len(req.URLs))
+	return queued, nil
+}
+
+// Cancel removes a queued job or stops a running one. It reports false for
+// unknown and finished jobs.
+func (s *Server) Cancel(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.cancel[id]; ok {
+		cancel()
+		return true
+	}
+	for i, job := range s.queue {
+		if job.ID == id {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			now := time.Now()
+			job.Status, job.FinishedAt = JobCancelled, &now
+			s.notifyLocked()
+			return true
+		}
+	}
+	return false
+}
+
+// Send passes a command to the running job. It reports false when no job is
+// running.
+func (s *Server) Send(cmd ytdl.Command) bool {
+	s.mu.Lock()
+	client := s.active
+	s.mu.Unlock()
+	if client == nil {
+		return false
+	}
+	client.Send(cmd)
+	return true
+}
+
+// Jobs returns all jobs, oldest first.
+func (s *Server) Jobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, len(s.jobs))
+	for i, job := range s.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// Items returns the latest state of every download seen, in the order they
+// first appeared.
+func (s *Server) Items() []Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]Item, 0, len(s.order))
+	for _, key := range s.order {
+		items = append(items, *s.items[key])
+	}
+	return items
+}
+
+func (s *Server) item(key string) (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.items[key]
+	if !ok {
+		return Item{}, false
+	}
+	return *it, true
+}
+
+func (s *Server) next() *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return nil
+	}
+	job := s.queue[0]
+	s.queue = s.queue[1:]
+	return job
+}
+
+// notifyLocked wakes the event streams waiting for job changes.
+func (s *Server) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) jobsChanged() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// run downloads one job with its own client, forwarding progress to the
+// server's subscribers.
+func (s *Server) run(ctx context.Context, job *Job) {
+	opts := job.Request.Apply(s.cfg.Defaults)
+	warn := func(msg string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		job.Warnings = append(job.Warnings, msg)
+		s.notifyLocked()
+	}
+	clientOpts := append([]ytdl.Option{ytdl.WithOptions(opts), ytdl.WithLogger(s.cfg.Logger), ytdl.WithWarnings(warn)},
+		s.cfg.ClientOptions...)
+	client := ytdl.New(clientOpts...)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sub := client.Subscribe(0, ytdl.Block)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for u := range sub.C {
+			s.update(job.ID, u)
+			s.bus.Publish(u)
+		}
+	}()
+
+	s.mu.Lock()
+	now := time.Now()
+	job.Status, job.StartedAt = JobRunning, &now
+	s.active = client
+	s.cancel[job.ID] = cancel
+	s.notifyLocked()
+	s.mu.Unlock()
+	s.cfg.Logger.Info("job started", "job", job.ID, "output", opts.OutputDir)
+
+	results, runErr := client.Run(ctx, job.Request.jobs()...)
+	client.Close()
+	<-forwarded
+	if _, err := client.SaveRecords(results); err != nil {
+		s.cfg.Logger.Warn("records not written", "job", job.ID, "err", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			job.Skipped++
+		case r.Success:
+			job.Succeeded++
+		default:
+			job.Failed++
+		}
+	}
+	finished := time.Now()
+	job.FinishedAt = &finished
+	switch {
+	case ctx.Err() != nil:
+		job.Status = JobCancelled
+	case runErr != nil:
+		job.Status, job.Error = JobFailed, runErr.Error()
+	case job.Failed > 0:
+		job.Status, job.Error = JobFailed, fmt.Sprintf("%d download(s) failed", job.Failed)
+	default:
+		job.Status = JobDone
+	}
+	s.active = nil
+	delete(s.cancel, job.ID)
+	s.notifyLocked()
+	s.cfg.Logger.Info("job finished", "job", job.ID, "status", job.Status,
+		"succeeded", job.Succeeded, "failed", job.Failed)
+}
+
+// update folds a progress update into the item it belongs to.
+func (s *Server) update(jobID int, u ytdl.Progress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.items[u.Key]
+	if !ok {
+		it = &Item{Key: u.Key}
+		s.items[u.Key] = it
+		s.order = append(s.order, u.Key)
+	}
+	it.JobID = jobID
+	it.UpdatedAt = time.Now()
+	if u.Status == "hook_failed" {
+		it.HookError = u.HookError // the item keeps its final status
+		return
+	}
+	it.Status = u.Status
+	if u.VideoID != "" {
+		it.VideoID = u.VideoID
+	}
+	if u.Title != "" {
+		it.Title = u.Title
+	}
+	it.Percent, it.Speed, it.ETA = u.Percent, u.Speed, u.ETA
+	if u.TotalBytes > 0 {
+		it.TotalBytes = u.TotalBytes
+	}
+	it.Error, it.ErrorKind, it.HookError = u.Error, string(u.ErrorKind), u.HookError
+	it.Attempt = u.Attempt
+	it.ResumeAt = nil
+	if !u.ResumeAt.IsZero() {
+		t := u.ResumeAt
+		it.ResumeAt = &t
+	}
+	if u.Filename != "" {
+		it.File = s.fileLink(u.Filename)
+		it.Subtitles = nil
+		for _, sub := range u.Subtitles {
+			if link := s.fileLink(sub); link != "" {
+				it.Subtitles = append(it.Subtitles, link)
+			}
+		}
+	}
+}
+
+// fileLink returns the /files/ path of a downloaded file, or "" when it is
+// outside the output directory.
+func (s *Server) fileLink(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(s.root, abs)
+	if err != nil || !filepath.IsLocal(rel) {
+		return ""
+	}
+	return filesPrefix + escapePath(filepath.ToSlash(rel))
+}
+
+func escapePath(rel string) string {
+	parts := strings.Split(rel, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
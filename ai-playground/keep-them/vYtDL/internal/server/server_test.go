@@ -0,0 +1,341 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+	"github.com/innate/yt-dl/pkg/ytdl/ytdltest"
+)
+
+const playlistURL = "https://www.youtube.com/playlist?list=PLtest"
+
+// start runs a server over a simulated yt-dlp: a playlist with a video that
+// downloads with English subtitles and one that fails its first attempt.
+func start(t *testing.T) *httptest.Server {
+	t.Helper()
+	defaults := ytdl.DefaultOptions()
+	defaults.OutputDir = t.TempDir()
+	defaults.SubtitleLangs = []string{"en"}
+	defaults.MaxRetries = 0
+	backend := ytdltest.NewBackend(ytdltest.Scenario{
+		Playlists: map[string]ytdltest.Playlist{playlistURL: {Title: "Lectures", Entries: []string{"ok", "flaky"}}},
+		Videos: []ytdltest.Video{
+			{ID: "ok", Title: "Intro", Subtitles: []string{"en"}},
+			{ID: "flaky", Title: "Flaky", Fail: []ytdl.ErrorKind{ytdl.ErrorNetwork}},
+		},
+	})
+	srv, err := New(Config{Defaults: defaults, ClientOptions: []ytdl.Option{ytdl.WithBackend(backend)},
+		AllowedHosts: []string{"127.0.0.1", "localhost"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Run(ctx)
+	}()
+	hs := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		hs.Close()
+	})
+	return hs
+}
+
+func call(t *testing.T, hs *httptest.Server, method, path, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, hs.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return send(t, req, out)
+}
+
+func send(t *testing.T, req *http.Request, out any) int {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: %v\n%s", req.Method, req.URL.Path, err, data)
+		}
+	}
+	return resp.StatusCode
+}
+
+// waitJob polls until the job has finished.
+func waitJob(t *testing.T, hs *httptest.Server, id int) Job {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		var jobs []Job
+		call(t, hs, http.MethodGet, "/api/jobs", "", &jobs)
+		for _, job := range jobs {
+			if job.ID == id && job.FinishedAt != nil {
+				return job
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("job %d did not finish", id)
+	return Job{}
+}
+
+func TestDownloadBrowseAndRetry(t *testing.T) {
+	hs := start(t)
+
+	var job Job
+	if code := call(t, hs, http.MethodPost, "/api/jobs", `{"urls": ["`+playlistURL+`"], "playlist": true}`, &job); code != http.StatusAccepted {
+		t.Fatalf("submit = %d", code)
+	}
+	job = waitJob(t, hs, job.ID)
+	if job.Status != JobFailed || job.Succeeded != 1 || job.Failed != 1 {
+		t.Fatalf("job = %+v", job)
+	}
+
+	var items []Item
+	call(t, hs, http.MethodGet, "/api/items", "", &items)
+	if len(items) != 2 || items[0].Status != "done" || items[0].File != "/files/Lectures/Intro.mp4" ||
+		len(items[0].Subtitles) != 1 || items[1].Status != "error" || items[1].ErrorKind != string(ytdl.ErrorNetwork) {
+		t.Fatalf("items = %+v", items)
+	}
+
+	var playlists []PlaylistSummary
+	call(t, hs, http.MethodGet, "/api/playlists", "", &playlists)
+	if len(playlists) != 1 || playlists[0].Dir != "Lectures" || playlists[0].Counts["succeeded"] != 1 || playlists[0].Counts["failed"] != 1 {
+		t.Fatalf("playlists = %+v", playlists)
+	}
+	var detail PlaylistDetail
+	call(t, hs, http.MethodGet, "/api/playlist?dir=Lectures", "", &detail)
+	if len(detail.Entries) != 2 || detail.Entries[0].File != "/files/Lectures/Intro.mp4" || !detail.Entries[1].Retryable {
+		t.Fatalf("playlist = %+v", detail)
+	}
+
+	var records []RecordFile
+	call(t, hs, http.MethodGet, "/api/records", "", &records)
+	if len(records) != 2 {
+		t.Fatalf("records = %+v", records)
+	}
+	for _, path := range []string{records[0].File, detail.Entries[0].File, detail.Entries[0].SubFiles[0]} {
+		if code := call(t, hs, http.MethodGet, path, "", nil); code != http.StatusOK {
+			t.Errorf("GET %s = %d", path, code)
+		}
+	}
+	for _, path := range []string{"/files/Lectures/.playlist_state.json", "/files/.logs/run.log", "/files/../etc/passwd", "/files/Lectures"} {
+		if code := call(t, hs, http.MethodGet, path, "", nil); code == http.StatusOK {
+			t.Errorf("GET %s served", path)
+		}
+	}
+
+	// Retrying without keys picks the failed entry; its second attempt works.
+	var retry Job
+	if code := call(t, hs, http.MethodPost, "/api/playlist/retry", `{"dir": "Lectures"}`, &retry); code != http.StatusAccepted {
+		t.Fatalf("retry = %d", code)
+	}
+	if got := retry.Request.SelectedEntries; len(got) != 1 || got[0] != "flaky" {
+		t.Fatalf("retry selected %v", got)
+	}
+	if retry = waitJob(t, hs, retry.ID); retry.Status != JobDone || retry.Succeeded != 1 {
+		t.Fatalf("retry job = %+v", retry)
+	}
+	call(t, hs, http.MethodGet, "/api/playlist?dir=Lectures", "", &detail)
+	if detail.Entries[1].Status != "succeeded" {
+		t.Fatalf("after retry = %+v", detail.Entries[1])
+	}
+	if code := call(t, hs, http.MethodPost, "/api/playlist/retry", `{"dir": "Lectures"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("retry with nothing failed = %d", code)
+	}
+}
+
+func TestRejectsBadRequests(t *testing.T) {
+	hs := start(t)
+	for _, body := range []string{
+		`{"urls": []}`,
+		`{"urls": ["file:///etc/passwd"]}`,
+		`{"urls": ["https://example.com/v"], "output": "../elsewhere"}`,
+		`{"urls": ["https://example.com/v"], "output": "/tmp"}`,
+		`{"urls": ["https://example.com/v"], "items": "x"}`,
+		`{"urls": ["https://example.com/v"], "qualty": "720"}`,
+	} {
+		if code := call(t, hs, http.MethodPost, "/api/jobs", body, nil); code != http.StatusBadRequest {
+			t.Errorf("POST %s = %d", body, code)
+		}
+	}
+	if code := call(t, hs, http.MethodPost, "/api/commands", `{"action": "pause", "key": "ok"}`, nil); code != http.StatusConflict {
+		t.Errorf("command without a running job = %d", code)
+	}
+	if code := call(t, hs, http.MethodPost, "/api/commands", `{"action": "explode", "key": "ok"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("unknown command = %d", code)
+	}
+	if code := call(t, hs, http.MethodDelete, "/api/jobs/42", "", nil); code != http.StatusNotFound {
+		t.Errorf("cancel unknown job = %d", code)
+	}
+}
+
+func TestEventsStreamJobsAndProgress(t *testing.T) {
+	hs := start(t)
+	resp, err := http.Get(hs.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %s", ct)
+	}
+	call(t, hs, http.MethodPost, "/api/jobs", `{"urls": ["https://www.youtube.com/watch?v=ok"], "playlist": false}`, nil)
+
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		sc := bufio.NewScanner(resp.Body)
+		var event string
+		for sc.Scan() {
+			line := sc.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+				events <- event + " " + data
+			}
+		}
+	}()
+	timeout := time.After(10 * time.Second)
+	sawDone, sawFinished := false, false
+	for !sawDone || !sawFinished {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatal("stream ended")
+			}
+			name, data, _ := strings.Cut(ev, " ")
+			switch name {
+			case "progress":
+				var it Item
+				if err := json.Unmarshal([]byte(data), &it); err != nil {
+					t.Fatal(err)
+				}
+				sawDone = sawDone || (it.Status == "done" && it.File != "")
+			case "jobs":
+				var jobs []Job
+				if err := json.Unmarshal([]byte(data), &jobs); err != nil {
+					t.Fatal(err)
+				}
+				sawFinished = len(jobs) == 1 && jobs[0].Status == JobDone
+			}
+		case <-timeout:
+			t.Fatalf("done=%v finished=%v", sawDone, sawFinished)
+		}
+	}
+}
+
+func TestServesUI(t *testing.T) {
+	hs := start(t)
+	resp, err := http.Get(hs.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "app.js") {
+		t.Fatalf("GET / = %d\n%s", resp.StatusCode, body)
+	}
+}
+
+func TestRefusesCrossSiteRequests(t *testing.T) {
+	hs := start(t)
+	const body = `{"urls": ["https://www.youtube.com/watch?v=ok"]}`
+	request := func(contentType, origin, host string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, hs.URL+"/api/jobs", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if host != "" {
+			req.Host = host
+		}
+		return send(t, req, nil)
+	}
+
+	// A page elsewhere can post text/plain without a preflight.
+	if status := request("text/plain", "", ""); status != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain body = %d", status)
+	}
+	if status := request("application/json", "http://evil.example", ""); status != http.StatusForbidden {
+		t.Errorf("foreign Origin = %d", status)
+	}
+	// DNS rebinding: the browser believes it talks to evil.example.
+	if status := request("application/json", "", "evil.example:8080"); status != http.StatusForbidden {
+		t.Errorf("foreign Host = %d", status)
+	}
+	req, _ := http.NewRequest(http.MethodGet, hs.URL+"/api/jobs", nil)
+	req.Host = "evil.example"
+	if status := send(t, req, nil); status != http.StatusForbidden {
+		t.Errorf("GET with foreign Host = %d", status)
+	}
+
+	// The UI's own requests carry its origin.
+	if status := request("application/json; charset=utf-8", hs.URL, ""); status != http.StatusAccepted {
+		t.Errorf("same-origin submit = %d", status)
+	}
+}
+
+func TestServesPlaylistPodcast(t *testing.T) {
+	hs := start(t)
+	var job Job
+	call(t, hs, http.MethodPost, "/api/jobs", `{"urls": ["`+playlistURL+`"], "playlist": true}`, &job)
+	waitJob(t, hs, job.ID)
+
+	var playlists []PlaylistSummary
+	call(t, hs, http.MethodGet, "/api/playlists", "", &playlists)
+	if len(playlists) != 1 || playlists[0].Feed != "/podcast/Lectures/feed.xml" {
+		t.Fatalf("playlists = %+v", playlists)
+	}
+	resp, err := http.Get(hs.URL + playlists[0].Feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	media := hs.URL + "/podcast/Lectures/media/Intro.mp4"
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(feed), `url="`+media+`"`) || strings.Contains(string(feed), "Flaky") {
+		t.Fatalf("feed %d:\n%s", resp.StatusCode, feed)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, media, nil)
+	req.Header.Set("Range", "bytes=0-7")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "ytdltest" {
+		t.Fatalf("range = %d %q", resp.StatusCode, body)
+	}
+
+	for _, path := range []string{"/podcast/feed.xml", "/podcast/Lectures/media/.playlist_state.json", "/podcast/../Lectures/feed.xml"} {
+		if code := call(t, hs, http.MethodGet, path, "", nil); code == http.StatusOK {
+			t.Errorf("GET %s served", path)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// uiFS is the web UI: static files that talk to the API.
+var uiFS, _ = fs.Sub(uiFiles, "ui")
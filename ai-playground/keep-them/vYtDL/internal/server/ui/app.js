@@ -0,0 +1,254 @@
+"use strict";
+
+// The UI keeps no state of its own beyond what the API reports: jobs and
+// items arrive over /api/events, playlists and records are fetched on demand.
+
+const items = new Map(); // key → Item
+let currentPlaylist = "";
+
+const $ = (sel) => document.querySelector(sel);
+
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [name, value] of Object.entries(attrs)) {
+    if (name === "onclick") node.addEventListener("click", value);
+    else if (value !== undefined && value !== null && value !== false) node.setAttribute(name, value);
+  }
+  for (const child of children) {
+    if (child !== undefined && child !== null) node.append(child);
+  }
+  return node;
+}
+
+async function api(method, path, body) {
+  const opts = { method, headers: {} };
+  if (body !== undefined) {
+    opts.headers["Content-Type"] = "application/json";
+    opts.body = JSON.stringify(body);
+  }
+  const resp = await fetch(path, opts);
+  if (!resp.ok) {
+    let msg = resp.statusText;
+    try { msg = (await resp.json()).error || msg; } catch (_) {}
+    throw new Error(msg);
+  }
+  return resp.status === 204 ? null : resp.json();
+}
+
+function fileLinks(file, subtitles) {
+  const span = el("span");
+  if (file) span.append(el("a", { href: file + "?download", title: decodeURIComponent(file) }, "video"), " ");
+  for (const sub of subtitles || []) {
+    const lang = decodeURIComponent(sub).split(".").slice(-2, -1)[0] || "sub";
+    span.append(el("a", { href: sub + "?download" }, lang), " ");
+  }
+  return span;
+}
+
+function when(ts) {
+  return ts ? new Date(ts).toLocaleString() : "";
+}
+
+// ---- Add ----
+
+$("#add").addEventListener("submit", async (ev) => {
+  ev.preventDefault();
+  const form = ev.target;
+  const data = new FormData(form);
+  const req = {
+    urls: data.get("urls").split("\n").map((u) => u.trim()).filter((u) => u && !u.startsWith("#")),
+  };
+  for (const name of ["output", "format", "quality", "items", "start", "end"]) {
+    const value = data.get(name).trim();
+    if (value) req[name] = value;
+  }
+  const langs = data.get("sub_langs").split(",").map((l) => l.trim()).filter(Boolean);
+  if (langs.length) req.sub_langs = langs;
+  if (data.get("no_subs")) req.no_subs = true;
+  if (data.get("override")) req.override = true;
+  if (data.get("index_prefix")) req.index_prefix = true;
+  if (data.get("playlist")) req.playlist = data.get("playlist") === "true";
+
+  $("#add-error").textContent = "";
+  try {
+    await api("POST", "/api/jobs", req);
+    form.urls.value = "";
+  } catch (err) {
+    $("#add-error").textContent = err.message;
+  }
+});
+
+// ---- Jobs and live progress ----
+
+function renderJobs(jobs) {
+  const body = $("#jobs tbody");
+  body.replaceChildren();
+  for (const job of jobs.slice().reverse()) {
+    const active = job.status === "queued" || job.status === "running";
+    body.append(el("tr", {},
+      el("td", {}, String(job.id)),
+      el("td", { class: "title" }, job.request.urls.join(" ")),
+      el("td", { class: "status-" + job.status, title: job.error || "" }, job.status,
+        ...(job.warnings || []).map((w) => el("div", { class: "warning" }, w))),
+      el("td", {}, String(job.succeeded)),
+      el("td", {}, String(job.failed)),
+      el("td", {}, active ? el("button", { class: "small", onclick: () => cancelJob(job.id) }, "Cancel") : null),
+    ));
+  }
+  if (jobs.some((j) => j.status !== "queued" && j.status !== "running")) loadSide();
+}
+
+async function cancelJob(id) {
+  try { await api("DELETE", "/api/jobs/" + id); } catch (err) { alert(err.message); }
+}
+
+async function command(action, key) {
+  try { await api("POST", "/api/commands", { action, key }); } catch (err) { alert(err.message); }
+}
+
+function itemActions(it) {
+  const b = (label, action) => el("button", { class: "small", onclick: () => command(action, it.key) }, label);
+  switch (it.status) {
+    case "downloading": case "starting": case "merging": case "queued": case "waiting":
+      return [b("Pause", "pause"), b("Cancel", "cancel")];
+    case "paused":
+      return [b("Resume", "resume"), b("Cancel", "cancel")];
+    case "error":
+      return [b("Retry", "retry")];
+  }
+  return [];
+}
+
+function renderItems() {
+  const body = $("#items tbody");
+  body.replaceChildren();
+  for (const it of Array.from(items.values()).reverse()) {
+    const bar = it.status === "downloading" ? el("progress", { max: "100", value: String(it.percent || 0) }) : null;
+    const status = it.error ? it.status + ": " + it.error : it.status;
+    body.append(el("tr", {},
+      el("td", { class: "title", title: it.key }, it.title || it.key),
+      el("td", { class: "status-" + it.status }, status),
+      el("td", {}, bar, it.percent ? " " + it.percent.toFixed(1) + "%" : ""),
+      el("td", {}, it.speed || ""),
+      el("td", {}, it.eta || ""),
+      el("td", {}, fileLinks(it.file, it.subtitles)),
+      el("td", {}, ...itemActions(it)),
+    ));
+  }
+}
+
+let renderQueued = false;
+function scheduleRender() {
+  if (renderQueued) return;
+  renderQueued = true;
+  requestAnimationFrame(() => { renderQueued = false; renderItems(); });
+}
+
+function connect() {
+  const events = new EventSource("/api/events");
+  events.addEventListener("open", () => {
+    $("#connection").textContent = "live";
+    $("#connection").classList.add("live");
+  });
+  events.addEventListener("error", () => {
+    $("#connection").textContent = "reconnecting…";
+    $("#connection").classList.remove("live");
+  });
+  events.addEventListener("jobs", (ev) => renderJobs(JSON.parse(ev.data)));
+  events.addEventListener("progress", (ev) => {
+    const it = JSON.parse(ev.data);
+    items.set(it.key, it);
+    scheduleRender();
+  });
+}
+
+// ---- Playlists and records ----
+
+async function loadPlaylists() {
+  const list = await api("GET", "/api/playlists");
+  const body = $("#playlists tbody");
+  body.replaceChildren();
+  for (const p of list) {
+    const failed = (p.counts.failed || 0) + (p.counts.unavailable || 0);
+    body.append(el("tr", { class: p.dir === currentPlaylist ? "selected" : null },
+      el("td", { class: "title", title: p.url }, p.title || p.dir),
+      el("td", {}, String(p.entries)),
+      el("td", {}, String(p.counts.succeeded || 0)),
+      el("td", { class: failed ? "status-failed" : "" }, String(failed)),
+      el("td", {}, when(p.updated_at)),
+      el("td", {},
+        el("button", { class: "small", onclick: () => showPlaylist(p.dir) }, "Open"), " ",
+        el("a", { href: p.feed, title: "Podcast feed" }, "feed")),
+    ));
+  }
+  if (currentPlaylist) showPlaylist(currentPlaylist);
+}
+
+async function showPlaylist(dir) {
+  currentPlaylist = dir;
+  let detail;
+  try {
+    detail = await api("GET", "/api/playlist?dir=" + encodeURIComponent(dir));
+  } catch (err) {
+    $("#playlist").hidden = true;
+    return;
+  }
+  $("#playlist").hidden = false;
+  $("#playlist-title").textContent = detail.title || detail.dir;
+  $("#retry-failed").disabled = !detail.entries.some((e) => e.retryable);
+  const body = $("#playlist tbody");
+  body.replaceChildren();
+  for (const e of detail.entries) {
+    body.append(el("tr", {},
+      el("td", {}, e.playlist_index ? String(e.playlist_index) : "-"),
+      el("td", { class: "title", title: e.url }, e.title || e.key),
+      el("td", { class: "status-" + e.status }, e.status, ...upstreamNotes(e).map((n) => el("div", { class: "warning" }, n))),
+      el("td", {}, String(e.attempts)),
+      el("td", { class: "muted" }, e.error_kind ? e.error_kind + ": " + (e.error || "") : (e.error || "")),
+      el("td", {}, fileLinks(e.file, e.subtitle_files)),
+      el("td", {}, e.retryable ? el("button", { class: "small", onclick: () => retry(dir, [e.key]) }, "Retry") : null),
+    ));
+  }
+}
+
+function upstreamNotes(e) {
+  const notes = [];
+  if (e.removed_upstream) notes.push("removed from the playlist");
+  if (e.listed_as) notes.push("listed as " + e.listed_as);
+  const history = e.title_history || [];
+  if (history.length) notes.push("renamed from \u201c" + history[history.length - 1].from + "\u201d");
+  return notes;
+}
+
+async function retry(dir, keys) {
+  try { await api("POST", "/api/playlist/retry", { dir, keys }); } catch (err) { alert(err.message); }
+}
+
+async function loadRecords() {
+  const files = await api("GET", "/api/records");
+  const list = $("#records");
+  list.replaceChildren();
+  if (!files.length) list.append(el("li", { class: "muted" }, "No records yet"));
+  for (const f of files) {
+    const name = decodeURIComponent(f.file.replace(/^\/files\//, ""));
+    list.append(el("li", {},
+      el("a", { href: f.file + "?download" }, name),
+      el("span", { class: "muted" }, " " + f.kind + ", " + when(f.updated_at)),
+    ));
+  }
+}
+
+let sideTimer = null;
+function loadSide() {
+  clearTimeout(sideTimer);
+  sideTimer = setTimeout(() => {
+    loadPlaylists().catch(() => {});
+    loadRecords().catch(() => {});
+  }, 300);
+}
+
+$("#refresh").addEventListener("click", loadSide);
+$("#retry-failed").addEventListener("click", () => retry(currentPlaylist, []));
+
+connect();
+loadSide();
@@ -0,0 +1,218 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/innate/yt-dl/internal/downloader"
+)
+
+// ErrCancelled is returned by interactive pickers when the user quits without choosing.
+var ErrCancelled = errors.New("selection cancelled")
+
+var cursorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true)
+
+// pickerOption is one selectable row in the format picker.
+type pickerOption struct {
+	label  string
+	format *downloader.Format
+}
+
+// FormatPicker lets the user choose a video stream and then an audio stream.
+type FormatPicker struct {
+	title    string
+	video    []pickerOption
+	audio    []pickerOption
+	stage    int // 0 = video, 1 = audio
+	cursor   int
+	chosenV  *downloader.Format
+	selector string
+	quitting bool
+}
+
+// NewFormatPicker builds a picker for the formats of one video.
+func NewFormatPicker(list downloader.FormatList) *FormatPicker {
+	var videos, audios []downloader.Format
+	for _, f := range list.Formats {
+		switch {
+		case f.HasVideo():
+			videos = append(videos, f)
+		case f.HasAudio():
+			audios = append(audios, f)
+		}
+	}
+	sort.SliceStable(videos, func(i, j int) bool {
+		if videos[i].Height != videos[j].Height {
+			return videos[i].Height > videos[j].Height
+		}
+		return videos[i].TBR > videos[j].TBR
+	})
+	sort.SliceStable(audios, func(i, j int) bool {
+		return audios[i].TBR > audios[j].TBR
+	})
+
+	p := &FormatPicker{title: list.Title}
+	for i := range videos {
+		p.video = append(p.video, pickerOption{label: FormatRow(videos[i]), format: &videos[i]})
+	}
+	p.video = append(p.video, pickerOption{label: "(no video — audio only)"})
+	for i := range audios {
+		p.audio = append(p.audio, pickerOption{label: FormatRow(audios[i]), format: &audios[i]})
+	}
+	p.audio = append(p.audio, pickerOption{label: "(no audio)"})
+	return p
+}
+
+// FormatRow renders a single format as a fixed-width table row.
+func FormatRow(f downloader.Format) string {
+	fps := ""
+	if f.FPS > 0 {
+		fps = fmt.Sprintf("%g", f.FPS)
+	}
+	codec := f.VCodec
+	if !f.HasVideo() {
+		codec = f.ACodec
+	} else if f.HasAudio() {
+		codec = f.VCodec + "+" + f.ACodec
+	}
+	return fmt.Sprintf("%-8s %-5s %-11s %-4s %-22s %8s %10s",
+		f.ID, f.Ext, f.Resolution(), fps, truncate(codec, 22),
+		HumanBitrate(f.TBR), HumanBytes(f.Size()))
+}
+
+func (p *FormatPicker) options() []pickerOption {
+	if p.stage == 0 {
+		return p.video
+	}
+	return p.audio
+}
+
+func (p *FormatPicker) Init() tea.Cmd { return nil }
+
+func (p *FormatPicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+	opts := p.options()
+	switch key.String() {
+	case "ctrl+c", "q", "esc":
+		p.quitting = true
+		return p, tea.Quit
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(opts)-1 {
+			p.cursor++
+		}
+	case "enter", " ":
+		chosen := opts[p.cursor].format
+		if p.stage == 0 {
+			p.chosenV = chosen
+			if chosen != nil && chosen.HasAudio() {
+				p.selector = chosen.ID
+				return p, tea.Quit
+			}
+			p.stage = 1
+			p.cursor = 0
+			return p, nil
+		}
+		videoID, audioID := "", ""
+		if p.chosenV != nil {
+			videoID = p.chosenV.ID
+		}
+		if chosen != nil {
+			audioID = chosen.ID
+		}
+		p.selector = downloader.FormatSelector(videoID, audioID)
+		if p.selector == "" {
+			// Neither stream chosen; go back to the video list.
+			p.stage = 0
+			p.cursor = 0
+			return p, nil
+		}
+		return p, tea.Quit
+	}
+	return p, nil
+}
+
+func (p *FormatPicker) View() string {
+	if p.quitting || p.selector != "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Choose format — "+p.title) + "\n")
+	if p.stage == 0 {
+		sb.WriteString(dimStyle.Render("Step 1/2: video stream (↑/↓, Enter to select, q to cancel)") + "\n\n")
+	} else {
+		sb.WriteString(dimStyle.Render("Step 2/2: audio stream (↑/↓, Enter to select, q to cancel)") + "\n\n")
+	}
+	sb.WriteString(dimStyle.Render(fmt.Sprintf("    %-8s %-5s %-11s %-4s %-22s %8s %10s",
+		"ID", "EXT", "RESOLUTION", "FPS", "CODEC", "BITRATE", "SIZE")) + "\n")
+	for i, opt := range p.options() {
+		prefix := "    "
+		line := opt.label
+		if i == p.cursor {
+			prefix = cursorStyle.Render("  ➜ ")
+			line = cursorStyle.Render(line)
+		}
+		sb.WriteString(prefix + line + "\n")
+	}
+	return sb.String()
+}
+
+// PickFormat runs the picker and returns the chosen yt-dlp format selector.
+func PickFormat(list downloader.FormatList) (string, error) {
+	p := NewFormatPicker(list)
+	if _, err := tea.NewProgram(p).Run(); err != nil {
+		return "", err
+	}
+	if p.selector == "" {
+		return "", ErrCancelled
+	}
+	return p.selector, nil
+}
+
+// HumanBytes formats a byte count with binary units; zero renders as "-".
+func HumanBytes(n int64) string {
+	if n <= 0 {
+		return "-"
+	}
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// HumanBitrate formats a kbit/s value; zero renders as "-".
+func HumanBitrate(kbps float64) string {
+	if kbps <= 0 {
+		return "-"
+	}
+	if kbps >= 1000 {
+		return fmt.Sprintf("%.1fM", kbps/1000)
+	}
+	return fmt.Sprintf("%.0fk", kbps)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 3 {
+		return s[:n]
+	}
+	return s[:n-3] + "..."
+}
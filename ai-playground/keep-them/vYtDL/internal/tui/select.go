@@ -0,0 +1,222 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// SelectRow is one playlist entry offered by the entry selector.
+type SelectRow struct {
+	Key      string
+	Title    string
+	Duration float64
+	Status   string // playlist state status; empty when never seen before
+	Selected bool
+}
+
+// EntrySelector is a checkbox list with filtering for picking playlist entries.
+type EntrySelector struct {
+	title     string
+	rows      []SelectRow
+	visible   []int // indexes into rows that match the filter
+	cursor    int   // position within visible
+	offset    int   // first visible row drawn
+	height    int
+	filter    string
+	filtering bool
+	confirmed bool
+	quitting  bool
+}
+
+// NewEntrySelector builds a selector over rows; Selected sets the initial ticks.
+func NewEntrySelector(title string, rows []SelectRow) *EntrySelector {
+	s := &EntrySelector{title: title, rows: rows, height: 20}
+	s.applyFilter()
+	return s
+}
+
+func (s *EntrySelector) applyFilter() {
+	s.visible = s.visible[:0]
+	needle := strings.ToLower(s.filter)
+	for i, row := range s.rows {
+		if needle == "" || strings.Contains(strings.ToLower(row.Title), needle) ||
+			strings.Contains(strings.ToLower(row.Status), needle) {
+			s.visible = append(s.visible, i)
+		}
+	}
+	if s.cursor >= len(s.visible) {
+		s.cursor = max(len(s.visible)-1, 0)
+	}
+	s.scroll()
+}
+
+func (s *EntrySelector) scroll() {
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+s.height {
+		s.offset = s.cursor - s.height + 1
+	}
+}
+
+func (s *EntrySelector) Init() tea.Cmd { return nil }
+
+func (s *EntrySelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// Header, filter line and footer take six lines.
+		s.height = max(msg.Height-6, 3)
+		s.scroll()
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			s.quitting = true
+			return s, tea.Quit
+		}
+		if s.filtering {
+			switch msg.Type {
+			case tea.KeyEnter, tea.KeyEsc:
+				s.filtering = false
+			case tea.KeyBackspace:
+				if r := []rune(s.filter); len(r) > 0 {
+					s.filter = string(r[:len(r)-1])
+				}
+			case tea.KeySpace:
+				s.filter += " "
+			case tea.KeyRunes:
+				s.filter += string(msg.Runes)
+			}
+			s.applyFilter()
+			return s, nil
+		}
+		switch msg.String() {
+		case "q", "esc":
+			s.quitting = true
+			return s, tea.Quit
+		case "up", "k":
+			if s.cursor > 0 {
+				s.cursor--
+			}
+		case "down", "j":
+			if s.cursor < len(s.visible)-1 {
+				s.cursor++
+			}
+		case "pgup":
+			s.cursor = max(s.cursor-s.height, 0)
+		case "pgdown":
+			s.cursor = min(s.cursor+s.height, max(len(s.visible)-1, 0))
+		case " ", "x":
+			if len(s.visible) > 0 {
+				row := &s.rows[s.visible[s.cursor]]
+				row.Selected = !row.Selected
+			}
+		case "a":
+			s.setVisible(func(*SelectRow) bool { return true })
+		case "n":
+			s.setVisible(func(*SelectRow) bool { return false })
+		case "i":
+			s.setVisible(func(r *SelectRow) bool { return !r.Selected })
+		case "/":
+			s.filtering = true
+		case "enter":
+			s.confirmed = true
+			return s, tea.Quit
+		}
+		s.scroll()
+	}
+	return s, nil
+}
+
+// setVisible applies f to every row matching the current filter.
+func (s *EntrySelector) setVisible(f func(*SelectRow) bool) {
+	for _, i := range s.visible {
+		s.rows[i].Selected = f(&s.rows[i])
+	}
+}
+
+func (s *EntrySelector) selectedCount() int {
+	n := 0
+	for _, row := range s.rows {
+		if row.Selected {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *EntrySelector) View() string {
+	if s.quitting || s.confirmed {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Select entries — "+s.title) + "\n")
+	sb.WriteString(dimStyle.Render(fmt.Sprintf("%d of %d selected • space toggle • a all • n none • i invert • / filter • Enter download • q cancel",
+		s.selectedCount(), len(s.rows))) + "\n")
+	if s.filtering || s.filter != "" {
+		line := "filter: " + s.filter
+		if s.filtering {
+			line += "█"
+		}
+		sb.WriteString(focusStyle.Render(line) + "\n")
+	} else {
+		sb.WriteString("\n")
+	}
+
+	end := min(s.offset+s.height, len(s.visible))
+	for pos := s.offset; pos < end; pos++ {
+		i := s.visible[pos]
+		row := s.rows[i]
+		box := "[ ]"
+		if row.Selected {
+			box = successStyle.Render("[x]")
+		}
+		status := row.Status
+		if status == "" {
+			status = "new"
+		}
+		line := fmt.Sprintf("%s %4d. %-60s %8s  %s", box, i+1, truncate(row.Title, 60),
+			FormatDuration(row.Duration), statusStyle(status).Render(status))
+		if pos == s.cursor {
+			sb.WriteString(cursorStyle.Render("➜ ") + line + "\n")
+		} else {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	if len(s.visible) == 0 {
+		sb.WriteString(dimStyle.Render("  no entries match the filter") + "\n")
+	} else if len(s.visible) > s.height {
+		sb.WriteString(dimStyle.Render(fmt.Sprintf("  %d–%d of %d", s.offset+1, end, len(s.visible))) + "\n")
+	}
+	return sb.String()
+}
+
+func statusStyle(status string) lipgloss.Style {
+	switch status {
+	case "succeeded":
+		return successStyle
+	case "failed", "unavailable":
+		return errorStyle
+	default:
+		return dimStyle
+	}
+}
+
+// SelectEntries runs the selector and returns the keys of the chosen rows.
+func SelectEntries(title string, rows []SelectRow) ([]string, error) {
+	s := NewEntrySelector(title, rows)
+	if _, err := tea.NewProgram(s, tea.WithAltScreen()).Run(); err != nil {
+		return nil, err
+	}
+	if !s.confirmed {
+		return nil, ErrCancelled
+	}
+	var keys []string
+	for _, row := range s.rows {
+		if row.Selected {
+			keys = append(keys, row.Key)
+		}
+	}
+	return keys, nil
+}
@@ -20,6 +20,7 @@ var (
 	dimStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
 	barFill      = lipgloss.NewStyle().Foreground(lipgloss.Color("33"))
 	barEmpty     = lipgloss.NewStyle().Foreground(lipgloss.Color("237"))
+	paneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("237")).Padding(0, 1)
 )
 
 const barWidth = 30
@@ -38,15 +39,48 @@ func renderBar(pct float64) string {
 
 // itemState tracks progress for one video.
 type itemState struct {
-	id      string
-	title   string
-	percent float64
-	speed   string
-	eta     string
-	status  string // downloading / merging / done / error
-	errMsg  string
+	id         string
+	title      string
+	percent    float64
+	speed      string
+	eta        string
+	status     string // queued / waiting / starting / downloading / merging / done / error / skipped
+	errMsg     string
+	errKind    downloader.ErrorKind
+	totalBytes int64
+	outputDir  string
+	filename   string
+	subtitles  []string
+	startedAt  time.Time
+	finishedAt time.Time
+	resumeAt   time.Time // when a "waiting" or "retrying" item starts again
+	attempt    int
+	hookErr    string // failed post-download hook
 }
 
+func (it *itemState) active() bool {
+	switch it.status {
+	case "starting", "downloading", "merging":
+		return true
+	}
+	return false
+}
+
+// itemFilter selects which items the list shows; it cycles with the f key.
+type itemFilter int
+
+const (
+	filterAll itemFilter = iota
+	filterActive
+	filterFailed
+	filterDone
+	filterCount
+)
+
+var filterNames = [filterCount]string{"all", "active", "failed", "done"}
+
+func (f itemFilter) String() string { return filterNames[f] }
+
 type progressMsg downloader.ProgressUpdate
 type doneMsg struct{}
 type tickMsg time.Time
@@ -60,16 +94,33 @@ type Model struct {
 	quitting bool
 	// channel from which the model receives updates
 	updates <-chan downloader.ProgressUpdate
+	// channel for per-item commands back to the downloader (may be nil)
+	commands chan<- downloader.Command
+
+	width, height int
+	cursor        int // index into the filtered rows
+	offset        int // first filtered row drawn
+	filter        itemFilter
+	showDetail    bool
+	follow        bool // keep the cursor on the newest active item
+	startedAt     time.Time
 }
 
 // New creates a new TUI model that reads from updates.
 func New(updates <-chan downloader.ProgressUpdate) *Model {
 	return &Model{
-		items:   make(map[string]*itemState),
-		updates: updates,
+		items:     make(map[string]*itemState),
+		updates:   updates,
+		follow:    true,
+		startedAt: time.Now(),
 	}
 }
 
+// SetCommands enables the cancel, pause/resume and retry key bindings.
+func (m *Model) SetCommands(commands chan<- downloader.Command) {
+	m.commands = commands
+}
+
 func (m *Model) Init() tea.Cmd {
 	return tea.Batch(m.waitForUpdate(), tickCmd())
 }
@@ -92,11 +143,52 @@ func (m *Model) waitForUpdate() tea.Cmd {
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.mu.Lock()
+		m.width, m.height = msg.Width, msg.Height
+		m.clampCursor()
+		m.mu.Unlock()
+
 	case tea.KeyMsg:
-		if msg.String() == "ctrl+c" || msg.String() == "q" {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		switch msg.String() {
+		case "ctrl+c", "q":
 			m.quitting = true
 			return m, tea.Quit
+		case "up", "k":
+			m.follow = false
+			m.cursor--
+		case "down", "j":
+			m.follow = false
+			m.cursor++
+		case "pgup":
+			m.follow = false
+			m.cursor -= m.listHeight()
+		case "pgdown":
+			m.follow = false
+			m.cursor += m.listHeight()
+		case "home", "g":
+			m.follow = false
+			m.cursor = 0
+		case "end", "G":
+			m.follow = false
+			m.cursor = len(m.rows()) - 1
+		case "f", "tab":
+			m.filter = (m.filter + 1) % filterCount
+			m.cursor = 0
+		case "enter", "d":
+			m.showDetail = !m.showDetail
+		case "a":
+			m.follow = true
+		case "x":
+			m.command(downloader.CommandCancel)
+		case "p":
+			m.command(downloader.CommandPause)
+		case "r":
+			m.command(downloader.CommandRetry)
 		}
+		m.clampCursor()
 
 	case progressMsg:
 		m.mu.Lock()
@@ -116,14 +208,11 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.items[key] = item
 			m.order = append(m.order, key)
 		}
-		if msg.Title != "" {
-			item.title = msg.Title
+		m.apply(item, downloader.ProgressUpdate(msg))
+		if m.follow && item.active() {
+			m.cursor = m.indexOf(key)
 		}
-		item.percent = msg.Percent
-		item.speed = msg.Speed
-		item.eta = msg.ETA
-		item.status = msg.Status
-		item.errMsg = msg.Error
+		m.clampCursor()
 		m.mu.Unlock()
 		return m, m.waitForUpdate()
 
@@ -138,15 +227,238 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// command sends an action for the selected item, if it applies to its state,
+// and shows the expected outcome until the downloader confirms it.
+func (m *Model) command(action downloader.CommandAction) {
+	rows := m.rows()
+	if m.commands == nil || m.cursor >= len(rows) {
+		return
+	}
+	key := rows[m.cursor]
+	item := m.items[key]
+	switch {
+	case action == downloader.CommandCancel && (item.active() || item.status == "queued" || item.status == "waiting" || item.status == "retrying" || item.status == "cooldown" || item.status == "paused"):
+		item.status, item.errMsg = "skipped", "cancelled by user"
+	case action == downloader.CommandPause && item.status == "paused":
+		action = downloader.CommandResume
+		item.status = "queued"
+	case action == downloader.CommandPause && (item.active() || item.status == "queued" || item.status == "waiting" || item.status == "retrying" || item.status == "cooldown"):
+		item.status = "paused"
+	case action == downloader.CommandRetry && (item.status == "error" || (item.status == "skipped" && item.errMsg != "")):
+		item.status, item.errMsg = "queued", ""
+	default:
+		return
+	}
+	select {
+	case m.commands <- downloader.Command{Action: action, Key: key}:
+	default:
+		// The downloader is not keeping up; drop rather than freeze the UI.
+	}
+}
+
+// apply merges one update into an item.
+func (m *Model) apply(item *itemState, msg downloader.ProgressUpdate) {
+	if msg.Title != "" {
+		item.title = msg.Title
+	}
+	if msg.VideoID != "" {
+		item.id = msg.VideoID
+	}
+	if msg.Status == "starting" && item.startedAt.IsZero() {
+		item.startedAt = time.Now()
+	}
+	if msg.Status == "starting" || msg.HookError != "" {
+		item.hookErr = msg.HookError
+	}
+	if msg.Status == "hook_failed" {
+		return // the item keeps its final status
+	}
+	if (msg.Status == "done" || msg.Status == "error") && item.finishedAt.IsZero() {
+		item.finishedAt = time.Now()
+	}
+	item.percent = msg.Percent
+	item.speed = msg.Speed
+	item.eta = msg.ETA
+	item.status = msg.Status
+	item.errMsg = msg.Error
+	item.errKind = msg.ErrorKind
+	item.resumeAt = msg.ResumeAt
+	item.attempt = msg.Attempt
+	if msg.TotalBytes > 0 {
+		item.totalBytes = msg.TotalBytes
+	}
+	if msg.OutputDir != "" {
+		item.outputDir = msg.OutputDir
+	}
+	if msg.Filename != "" {
+		item.filename = msg.Filename
+	}
+	if len(msg.Subtitles) > 0 {
+		item.subtitles = msg.Subtitles
+	}
+}
+
+// rows returns the keys visible under the current filter.
+func (m *Model) rows() []string {
+	if m.filter == filterAll {
+		return m.order
+	}
+	var keys []string
+	for _, key := range m.order {
+		item := m.items[key]
+		switch {
+		case m.filter == filterActive && item.active(),
+			m.filter == filterFailed && item.status == "error",
+			m.filter == filterDone && (item.status == "done" || item.status == "skipped"):
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (m *Model) indexOf(key string) int {
+	for i, k := range m.rows() {
+		if k == key {
+			return i
+		}
+	}
+	return m.cursor
+}
+
+// selected returns the item under the cursor, or nil.
+func (m *Model) selected() *itemState {
+	rows := m.rows()
+	if m.cursor < 0 || m.cursor >= len(rows) {
+		return nil
+	}
+	return m.items[rows[m.cursor]]
+}
+
+// listHeight is the number of items that fit between header and detail pane.
+func (m *Model) listHeight() int {
+	if m.height == 0 {
+		return 1 << 20 // size unknown: draw everything, like a plain log
+	}
+	reserved := 6 // title, help, summary, filter line, blank, footer
+	if m.showDetail {
+		reserved += 9
+	}
+	if m.done {
+		reserved += 2
+	}
+	return max((m.height-reserved)/2, 1) // every item takes two lines
+}
+
+func (m *Model) clampCursor() {
+	n := len(m.rows())
+	if m.cursor >= n {
+		m.cursor = n - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	h := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+h {
+		m.offset = m.cursor - h + 1
+	}
+	if m.offset > max(n-h, 0) {
+		m.offset = max(n-h, 0)
+	}
+}
+
+// summary aggregates counts, sizes and throughput over all items.
+type summary struct {
+	total, finished, failed, skipped, active int
+	bytes                                    int64
+	speed                                    float64 // bytes per second over active items
+	eta                                      time.Duration
+	cooldown                                 time.Time // set while the queue waits out a rate limit
+}
+
+func (m *Model) summarize() summary {
+	var s summary
+	var finishedTime time.Duration
+	var remainingBytes float64
+	for _, key := range m.order {
+		item := m.items[key]
+		s.total++
+		s.bytes += item.totalBytes
+		switch {
+		case item.status == "done":
+			s.finished++
+			if !item.startedAt.IsZero() {
+				finishedTime += item.finishedAt.Sub(item.startedAt)
+			}
+		case item.status == "error":
+			s.failed++
+		case item.status == "skipped":
+			s.skipped++
+		case item.status == "cooldown":
+			s.cooldown = item.resumeAt
+		case item.active():
+			s.active++
+			s.speed += float64(downloader.ParseByteSize(item.speed))
+			remainingBytes += float64(item.totalBytes) * (100 - item.percent) / 100
+		}
+	}
+	queued := s.total - s.finished - s.failed - s.skipped - s.active
+	switch {
+	case s.finished > 0 && queued+s.active > 0:
+		// Average item time for the queue, bytes/speed for what is running.
+		s.eta = finishedTime / time.Duration(s.finished) * time.Duration(queued)
+		if s.speed > 0 {
+			s.eta += time.Duration(remainingBytes / s.speed * float64(time.Second))
+		}
+	case s.speed > 0:
+		s.eta = time.Duration(remainingBytes / s.speed * float64(time.Second))
+	}
+	return s
+}
+
 func (m *Model) View() string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	var sb strings.Builder
 	sb.WriteString(titleStyle.Render("yt-dl — YouTube Downloader") + "\n")
-	sb.WriteString(dimStyle.Render("Press q / Ctrl+C to cancel\n\n"))
+	help := "↑/↓ move • f filter • Enter details • a follow active • q / Ctrl+C quit"
+	if m.commands != nil {
+		help = "↑/↓ move • f filter • Enter details • a follow • x cancel • p pause/resume • r retry • q quit"
+	}
+	sb.WriteString(dimStyle.Render(help) + "\n")
 
-	for _, key := range m.order {
+	s := m.summarize()
+	header := fmt.Sprintf("%d/%d done", s.finished+s.skipped, s.total)
+	if s.failed > 0 {
+		header += ", " + errorStyle.Render(fmt.Sprintf("%d failed", s.failed))
+	}
+	if s.active > 0 {
+		header += fmt.Sprintf(", %d active", s.active)
+	}
+	if s.bytes > 0 {
+		header += ", " + HumanBytes(s.bytes) + " total"
+	}
+	if s.speed > 0 {
+		header += ", " + HumanBytes(int64(s.speed)) + "/s"
+	}
+	if s.eta > 0 {
+		header += ", ETA " + formatETA(s.eta)
+	}
+	if !s.cooldown.IsZero() {
+		header += ", " + errorStyle.Render("rate limited, cooldown until "+s.cooldown.Format("15:04:05"))
+	}
+	header += dimStyle.Render(fmt.Sprintf("  (elapsed %s)", formatETA(time.Since(m.startedAt))))
+	sb.WriteString(header + "\n")
+
+	rows := m.rows()
+	sb.WriteString(dimStyle.Render(fmt.Sprintf("filter: %s (%d items)", m.filter, len(rows))) + "\n\n")
+
+	end := min(m.offset+m.listHeight(), len(rows))
+	for i := m.offset; i < end; i++ {
+		key := rows[i]
 		item := m.items[key]
 		title := item.title
 		if title == "" {
@@ -155,23 +467,73 @@ func (m *Model) View() string {
 		if len(title) > 55 {
 			title = title[:52] + "..."
 		}
+		pointer := "  "
+		if i == m.cursor {
+			pointer = cursorStyle.Render("➜ ")
+		}
 
 		switch item.status {
 		case "done":
-			sb.WriteString(fmt.Sprintf("  %s  %s\n",
+			hook := ""
+			if item.hookErr != "" {
+				hook = "  " + errorStyle.Render("(hook failed)")
+			}
+			sb.WriteString(fmt.Sprintf("%s%s  %s%s\n\n",
+				pointer,
 				successStyle.Render("✓"),
 				title,
+				hook,
 			))
 		case "skipped":
-			sb.WriteString(fmt.Sprintf("  %s  %s\n",
+			reason := "already downloaded"
+			if item.errMsg != "" {
+				reason = item.errMsg
+			}
+			sb.WriteString(fmt.Sprintf("%s%s  %s\n\n",
+				pointer,
 				dimStyle.Render("•"),
-				title+" (already downloaded)",
+				title+dimStyle.Render(" ("+reason+")"),
+			))
+		case "paused":
+			sb.WriteString(fmt.Sprintf("%s%s  %s\n\n",
+				pointer,
+				dimStyle.Render("Ⅱ"),
+				title+dimStyle.Render(" (paused — p to resume)"),
+			))
+		case "waiting":
+			sb.WriteString(fmt.Sprintf("%s%s  %s\n\n",
+				pointer,
+				dimStyle.Render("◷"),
+				title+dimStyle.Render(" (waiting for download window, opens "+item.resumeAt.Format("Mon 15:04")+")"),
+			))
+		case "retrying":
+			wait := max(time.Until(item.resumeAt), 0).Round(time.Second)
+			sb.WriteString(fmt.Sprintf("%s%s  %s\n     %s\n",
+				pointer,
+				dimStyle.Render("↻"),
+				title+dimStyle.Render(fmt.Sprintf(" (attempt %d in %s)", item.attempt, wait)),
+				errorStyle.Render(truncate(item.errMsg, 100)),
+			))
+		case "cooldown":
+			wait := max(time.Until(item.resumeAt), 0).Round(time.Second)
+			sb.WriteString(fmt.Sprintf("%s%s  %s\n     %s\n",
+				pointer,
+				errorStyle.Render("⏸"),
+				title+dimStyle.Render(fmt.Sprintf(" (queue paused, retrying in %s)", wait)),
+				errorStyle.Render(truncate(item.errMsg, 100)),
+			))
+		case "queued":
+			sb.WriteString(fmt.Sprintf("%s%s  %s\n\n",
+				pointer,
+				dimStyle.Render("·"),
+				dimStyle.Render(title),
 			))
 		case "error":
-			sb.WriteString(fmt.Sprintf("  %s  %s\n     %s\n",
+			sb.WriteString(fmt.Sprintf("%s%s  %s\n     %s\n",
+				pointer,
 				errorStyle.Render("✗"),
 				title,
-				errorStyle.Render(item.errMsg),
+				errorStyle.Render(truncate(item.errMsg, 100)),
 			))
 		default:
 			bar := renderBar(item.percent)
@@ -179,7 +541,8 @@ func (m *Model) View() string {
 			if item.speed != "" {
 				info = fmt.Sprintf(" %s  ETA %s", item.speed, item.eta)
 			}
-			sb.WriteString(fmt.Sprintf("  %s %s %5.1f%%%s\n     %s\n",
+			sb.WriteString(fmt.Sprintf("%s%s %s %5.1f%%%s\n     %s\n",
+				pointer,
 				bar,
 				dimStyle.Render(item.status),
 				item.percent,
@@ -188,6 +551,13 @@ func (m *Model) View() string {
 			))
 		}
 	}
+	if len(rows) > end-m.offset {
+		sb.WriteString(dimStyle.Render(fmt.Sprintf("  showing %d–%d of %d", m.offset+1, end, len(rows))) + "\n")
+	}
+
+	if m.showDetail {
+		sb.WriteString(m.detailView())
+	}
 
 	if m.done {
 		sb.WriteString("\n" + successStyle.Render("All downloads completed.") + "\n")
@@ -196,9 +566,69 @@ func (m *Model) View() string {
 	return sb.String()
 }
 
-// Run starts the TUI and blocks until it exits.
-func Run(updates <-chan downloader.ProgressUpdate) error {
+// detailView renders the pane for the item under the cursor.
+func (m *Model) detailView() string {
+	item := m.selected()
+	if item == nil {
+		return paneStyle.Render(dimStyle.Render("no item selected")) + "\n"
+	}
+	var lines []string
+	add := func(label, value string) {
+		if value != "" {
+			lines = append(lines, dimStyle.Render(fmt.Sprintf("%-10s", label))+" "+value)
+		}
+	}
+	add("title", item.title)
+	add("video id", item.id)
+	add("status", item.status)
+	add("error", string(item.errKind))
+	if item.hookErr != "" {
+		add("hook", errorStyle.Render(item.hookErr))
+	}
+	if item.totalBytes > 0 {
+		add("size", HumanBytes(item.totalBytes))
+	}
+	if !item.startedAt.IsZero() && !item.finishedAt.IsZero() {
+		add("took", item.finishedAt.Sub(item.startedAt).Round(time.Second).String())
+	}
+	add("directory", item.outputDir)
+	add("file", item.filename)
+	for i, sub := range item.subtitles {
+		label := ""
+		if i == 0 {
+			label = "subtitles"
+		}
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("%-10s", label))+" "+sub)
+	}
+	if item.errMsg != "" {
+		width := 80
+		if m.width > 10 {
+			width = m.width - 6
+		}
+		lines = append(lines, errorStyle.Width(width).Render(item.errMsg))
+	}
+	return paneStyle.Render(strings.Join(lines, "\n")) + "\n"
+}
+
+func formatETA(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d.Hours())
+	mins := int(d.Minutes()) % 60
+	secs := int(d.Seconds()) % 60
+	if h > 0 {
+		return fmt.Sprintf("%dh%02dm", h, mins)
+	}
+	if mins > 0 {
+		return fmt.Sprintf("%dm%02ds", mins, secs)
+	}
+	return fmt.Sprintf("%ds", secs)
+}
+
+// Run starts the TUI and blocks until it exits. commands may be nil, which
+// disables the per-item key bindings.
+func Run(updates <-chan downloader.ProgressUpdate, commands chan<- downloader.Command) error {
 	m := New(updates)
+	m.SetCommands(commands)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	_, err := p.Run()
 	return err
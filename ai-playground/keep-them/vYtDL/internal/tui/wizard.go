@@ -0,0 +1,468 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/innate/yt-dl/internal/downloader"
+)
+
+var (
+	labelStyle   = lipgloss.NewStyle().Width(16)
+	focusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
+	buttonStyle  = lipgloss.NewStyle().Padding(0, 2).Background(lipgloss.Color("237"))
+	activeButton = buttonStyle.Background(lipgloss.Color("205")).Foreground(lipgloss.Color("0"))
+)
+
+// WizardJob is one URL collected by the wizard.
+type WizardJob struct {
+	URL      string
+	Playlist bool
+}
+
+// WizardResult is what the wizard hands to the download runner.
+type WizardResult struct {
+	Jobs    []WizardJob
+	Options downloader.Options
+}
+
+// WizardConfig wires the wizard to the downloader without importing cmd.
+type WizardConfig struct {
+	// Defaults pre-fills the form and supplies every option the form doesn't show.
+	Defaults downloader.Options
+	// FetchPlaylist lists playlist entries for the preview step.
+	FetchPlaylist func(url string) (downloader.PlaylistInfo, error)
+	// Start begins downloading and returns the progress stream; the stream
+	// must be closed when every job has finished.
+	Start func(WizardResult) <-chan downloader.ProgressUpdate
+	// Commands optionally receives per-item commands from the progress view.
+	Commands chan<- downloader.Command
+}
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	choiceField
+)
+
+type formField struct {
+	label       string
+	kind        fieldKind
+	value       string
+	placeholder string
+	choices     []string
+	choice      int
+}
+
+func (f *formField) current() string {
+	if f.kind == choiceField {
+		return f.choices[f.choice]
+	}
+	return strings.TrimSpace(f.value)
+}
+
+const (
+	fieldURLs = iota
+	fieldQuality
+	fieldFormat
+	fieldSubs
+	fieldStart
+	fieldEnd
+	fieldOutput
+	fieldCount
+)
+
+const (
+	stageForm = iota
+	stagePreview
+	stageProgress
+)
+
+type previewMsg struct {
+	url  string
+	info downloader.PlaylistInfo
+	err  error
+}
+
+type preview struct {
+	job     WizardJob
+	loading bool
+	info    downloader.PlaylistInfo
+	err     error
+}
+
+// Wizard is the interactive form shown when yt-dl runs without arguments.
+type Wizard struct {
+	cfg      WizardConfig
+	fields   []*formField
+	focus    int // fieldCount means the Continue button
+	stage    int
+	formErr  string
+	previews []*preview
+	selected int // preview row that "p" toggles
+	progress *Model
+	started  bool
+	quitting bool
+}
+
+// NewWizard builds the wizard pre-filled from cfg.Defaults.
+func NewWizard(cfg WizardConfig) *Wizard {
+	d := cfg.Defaults
+	quality := &formField{label: "Quality", kind: choiceField,
+		choices: []string{"best", "2160", "1440", "1080", "720", "480", "360"}}
+	for i, c := range quality.choices {
+		if c == strings.TrimSuffix(d.Quality, "p") {
+			quality.choice = i
+		}
+	}
+	format := &formField{label: "Format", kind: choiceField, choices: []string{"mp4", "mkv", "webm"}}
+	for i, c := range format.choices {
+		if c == d.Format {
+			format.choice = i
+		}
+	}
+	subs := ""
+	if d.WriteSubtitles {
+		subs = strings.Join(d.SubtitleLangs, ",")
+	}
+	output := d.OutputDir
+	if output == "" {
+		output = "."
+	}
+
+	fields := make([]*formField, fieldCount)
+	fields[fieldURLs] = &formField{label: "URLs", placeholder: "paste one or more URLs, separated by spaces"}
+	fields[fieldQuality] = quality
+	fields[fieldFormat] = format
+	fields[fieldSubs] = &formField{label: "Subtitles", value: subs, placeholder: "e.g. en,zh (empty = none)"}
+	fields[fieldStart] = &formField{label: "Clip start", placeholder: "HH:MM:SS (optional)"}
+	fields[fieldEnd] = &formField{label: "Clip end", placeholder: "HH:MM:SS (optional)"}
+	fields[fieldOutput] = &formField{label: "Output dir", value: output}
+	return &Wizard{cfg: cfg, fields: fields}
+}
+
+// Started reports whether the wizard got as far as starting downloads.
+func (w *Wizard) Started() bool { return w.started }
+
+func (w *Wizard) Init() tea.Cmd { return nil }
+
+func (w *Wizard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if w.stage == stageProgress {
+		_, cmd := w.progress.Update(msg)
+		return w, cmd
+	}
+
+	switch msg := msg.(type) {
+	case previewMsg:
+		for _, p := range w.previews {
+			if p.job.URL == msg.url {
+				p.loading = false
+				p.info = msg.info
+				p.err = msg.err
+			}
+		}
+		return w, nil
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			w.quitting = true
+			return w, tea.Quit
+		}
+		if w.stage == stagePreview {
+			return w.updatePreview(msg)
+		}
+		return w.updateForm(msg)
+	}
+	return w, nil
+}
+
+func (w *Wizard) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		w.quitting = true
+		return w, tea.Quit
+	case "up", "shift+tab":
+		if w.focus > 0 {
+			w.focus--
+		}
+		return w, nil
+	case "down", "tab":
+		if w.focus < fieldCount {
+			w.focus++
+		}
+		return w, nil
+	case "enter":
+		if w.focus < fieldCount {
+			w.focus++
+			return w, nil
+		}
+		return w, w.toPreview()
+	}
+	if w.focus == fieldCount {
+		return w, nil
+	}
+
+	f := w.fields[w.focus]
+	if f.kind == choiceField {
+		switch msg.String() {
+		case "left", "h":
+			f.choice = (f.choice + len(f.choices) - 1) % len(f.choices)
+		case "right", "l", " ":
+			f.choice = (f.choice + 1) % len(f.choices)
+		}
+		return w, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyBackspace:
+		if r := []rune(f.value); len(r) > 0 {
+			f.value = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		f.value = ""
+	case tea.KeySpace:
+		f.value += " "
+	case tea.KeyRunes:
+		// Pasted multi-line lists arrive as a single rune batch.
+		f.value += strings.NewReplacer("\r", " ", "\n", " ", "\t", " ").Replace(string(msg.Runes))
+	}
+	return w, nil
+}
+
+func (w *Wizard) urls() []string {
+	return strings.FieldsFunc(w.fields[fieldURLs].value, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+}
+
+func (w *Wizard) toPreview() tea.Cmd {
+	urls := w.urls()
+	if len(urls) == 0 {
+		w.formErr = "enter at least one URL"
+		w.focus = fieldURLs
+		return nil
+	}
+	w.formErr = ""
+	w.stage = stagePreview
+	w.previews = w.previews[:0]
+	w.selected = 0
+
+	var cmds []tea.Cmd
+	for _, url := range urls {
+		p := &preview{job: WizardJob{URL: url, Playlist: downloader.LooksLikePlaylist(url)}}
+		w.previews = append(w.previews, p)
+		if p.job.Playlist && w.cfg.FetchPlaylist != nil {
+			p.loading = true
+			fetch, target := w.cfg.FetchPlaylist, url
+			cmds = append(cmds, func() tea.Msg {
+				info, err := fetch(target)
+				return previewMsg{url: target, info: info, err: err}
+			})
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+func (w *Wizard) updatePreview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "backspace":
+		w.stage = stageForm
+		return w, nil
+	case "up", "k":
+		if w.selected > 0 {
+			w.selected--
+		}
+		return w, nil
+	case "down", "j":
+		if w.selected < len(w.previews)-1 {
+			w.selected++
+		}
+		return w, nil
+	case "p":
+		// Toggle playlist mode on the selected URL for links the heuristic
+		// got wrong.
+		if w.selected < len(w.previews) {
+			p := w.previews[w.selected]
+			p.job.Playlist = !p.job.Playlist
+			p.info, p.err = downloader.PlaylistInfo{}, nil
+			if p.job.Playlist && w.cfg.FetchPlaylist != nil {
+				p.loading = true
+				fetch, target := w.cfg.FetchPlaylist, p.job.URL
+				return w, func() tea.Msg {
+					info, err := fetch(target)
+					return previewMsg{url: target, info: info, err: err}
+				}
+			}
+		}
+		return w, nil
+	case "enter":
+		return w, w.start()
+	}
+	return w, nil
+}
+
+func (w *Wizard) result() WizardResult {
+	opts := w.cfg.Defaults
+	quality := w.fields[fieldQuality].current()
+	if quality == "best" {
+		quality = ""
+	}
+	opts.Quality = quality
+	opts.Format = w.fields[fieldFormat].current()
+	opts.StartTime = w.fields[fieldStart].current()
+	opts.EndTime = w.fields[fieldEnd].current()
+	if out := w.fields[fieldOutput].current(); out != "" {
+		opts.OutputDir = out
+	}
+	var langs []string
+	for _, lang := range strings.Split(w.fields[fieldSubs].current(), ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			langs = append(langs, lang)
+		}
+	}
+	opts.SubtitleLangs = langs
+	opts.WriteSubtitles = len(langs) > 0
+
+	res := WizardResult{Options: opts}
+	for _, p := range w.previews {
+		res.Jobs = append(res.Jobs, p.job)
+	}
+	return res
+}
+
+func (w *Wizard) start() tea.Cmd {
+	if w.cfg.Start == nil {
+		w.quitting = true
+		return tea.Quit
+	}
+	w.started = true
+	w.stage = stageProgress
+	w.progress = New(w.cfg.Start(w.result()))
+	w.progress.SetCommands(w.cfg.Commands)
+	return w.progress.Init()
+}
+
+func (w *Wizard) View() string {
+	switch w.stage {
+	case stageProgress:
+		return w.progress.View()
+	case stagePreview:
+		return w.viewPreview()
+	}
+	if w.quitting {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("yt-dl — New download") + "\n")
+	sb.WriteString(dimStyle.Render("↑/↓ move • ←/→ change choice • Enter next • Esc quit") + "\n\n")
+	for i, f := range w.fields {
+		label := labelStyle.Render(f.label)
+		value := f.value
+		if f.kind == choiceField {
+			value = "‹ " + f.current() + " ›"
+		} else if value == "" && i != w.focus {
+			value = dimStyle.Render(f.placeholder)
+		}
+		if i == w.focus {
+			label = focusStyle.Render(labelStyle.Render(f.label))
+			if f.kind == textField {
+				value += "█"
+			}
+			sb.WriteString(cursorStyle.Render("➜ ") + label + value + "\n")
+		} else {
+			sb.WriteString("  " + label + value + "\n")
+		}
+	}
+	sb.WriteString("\n")
+	if w.focus == fieldCount {
+		sb.WriteString("  " + activeButton.Render("Continue") + "\n")
+	} else {
+		sb.WriteString("  " + buttonStyle.Render("Continue") + "\n")
+	}
+	if w.formErr != "" {
+		sb.WriteString("\n" + errorStyle.Render(w.formErr) + "\n")
+	}
+	return sb.String()
+}
+
+const previewLimit = 10
+
+func (w *Wizard) viewPreview() string {
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("yt-dl — Review") + "\n")
+	sb.WriteString(dimStyle.Render("↑/↓ select URL • p toggle playlist mode • Enter start • Esc back") + "\n\n")
+
+	res := w.result()
+	o := res.Options
+	quality := o.Quality
+	if quality == "" {
+		quality = "best"
+	}
+	sb.WriteString(fmt.Sprintf("  %s %s, quality %s, subtitles %s, output %s\n",
+		dimStyle.Render("Options:"), o.Format, quality, orNone(strings.Join(o.SubtitleLangs, ",")), o.OutputDir))
+	if o.StartTime != "" || o.EndTime != "" {
+		sb.WriteString(fmt.Sprintf("  %s %s – %s\n", dimStyle.Render("Clip:"), orNone(o.StartTime), orNone(o.EndTime)))
+	}
+	sb.WriteString("\n")
+
+	for i, p := range w.previews {
+		marker := "  "
+		if i == w.selected {
+			marker = cursorStyle.Render("➜ ")
+		}
+		if !p.job.Playlist {
+			sb.WriteString(fmt.Sprintf("%s%s  %s\n", marker, dimStyle.Render("video   "), p.job.URL))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%s%s  %s\n", marker, focusStyle.Render("playlist"), p.job.URL))
+		switch {
+		case p.loading:
+			sb.WriteString(dimStyle.Render("      loading entries…") + "\n")
+		case p.err != nil:
+			sb.WriteString(errorStyle.Render("      cannot list entries: "+p.err.Error()) + "\n")
+		default:
+			sb.WriteString(fmt.Sprintf("      %s — %d entries\n", p.info.Title, len(p.info.Entries)))
+			for i, e := range p.info.Entries {
+				if i == previewLimit {
+					sb.WriteString(dimStyle.Render(fmt.Sprintf("      … and %d more", len(p.info.Entries)-previewLimit)) + "\n")
+					break
+				}
+				sb.WriteString(fmt.Sprintf("      %3d. %s %s\n", i+1, truncate(e.Title, 60),
+					dimStyle.Render(FormatDuration(e.Duration))))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// FormatDuration renders seconds as H:MM:SS or M:SS; zero renders as "".
+func FormatDuration(seconds float64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	total := int(seconds)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// RunWizard shows the wizard and, once confirmed, the progress view in the
+// same program. It blocks until the user quits or every download finished.
+func RunWizard(cfg WizardConfig) (*Wizard, error) {
+	w := NewWizard(cfg)
+	p := tea.NewProgram(w, tea.WithAltScreen())
+	_, err := p.Run()
+	return w, err
+}
@@ -0,0 +1,280 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/innate/yt-dl/internal/downloader"
+	"github.com/innate/yt-dl/internal/record"
+)
+
+// Client runs downloads with one configuration. Its methods may be called
+// from several goroutines; every run publishes progress to the same
+// subscribers.
+type Client struct {
+	opts      Options
+	backend   Backend
+	logger    *slog.Logger
+	warn      func(string)
+	callbacks []func(Progress)
+
+	bus      *downloader.Bus
+	handlers sync.WaitGroup
+
+	mu       sync.Mutex
+	controls map[*downloader.Control]bool // one per running Run
+}
+
+// New creates a client from DefaultOptions and the given options.
+func New(options ...Option) *Client {
+	c := &Client{
+		opts:     downloader.DefaultOptions(),
+		bus:      downloader.NewBus(),
+		controls: map[*downloader.Control]bool{},
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	for _, fn := range c.callbacks {
+		sub := c.bus.Subscribe(0, downloader.Block)
+		c.handlers.Add(1)
+		go func() {
+			defer c.handlers.Done()
+			for upd := range sub.C {
+				fn(upd)
+			}
+		}()
+	}
+	return c
+}
+
+// Options returns a copy of the client's configuration.
+func (c *Client) Options() Options {
+	return c.opts
+}
+
+// Subscribe returns a channel of every update published from now on. buffer
+// and policy decide what happens while the subscriber falls behind; buffer
+// <= 0 picks a default. C closes on Close or Unsubscribe.
+func (c *Client) Subscribe(buffer int, policy Policy) *Subscription {
+	return c.bus.Subscribe(buffer, policy)
+}
+
+// Close ends all subscriptions once they have received everything published
+// and waits for the WithProgress callbacks to return. The client must not
+// download anything afterwards.
+func (c *Client) Close() {
+	c.bus.Close()
+	c.handlers.Wait()
+}
+
+// Job is one URL of a Run together with how it should be fetched.
+type Job struct {
+	URL      string
+	Playlist bool
+	// FormatSelector overrides the client's format selector when set.
+	FormatSelector string
+	// SelectedEntries restricts a playlist to these entry keys (see
+	// PlaylistEntry.Key), replacing the client's WithItems.
+	SelectedEntries []string
+}
+
+// options returns opts adjusted for the job.
+func (job Job) options(opts Options) Options {
+	opts.IsPlaylist = job.Playlist
+	if job.FormatSelector != "" {
+		opts.FormatSelector = job.FormatSelector
+	}
+	if len(job.SelectedEntries) > 0 {
+		// An explicit choice already accounts for the item range.
+		opts.SelectedEntries = job.SelectedEntries
+		opts.Items = ""
+	}
+	return opts
+}
+
+func (c *Client) downloader(opts Options) *downloader.Downloader {
+	dl := downloader.New(opts, nil)
+	dl.SetBus(c.bus)
+	dl.SetLogger(c.logger)
+	dl.SetWarnings(c.warn)
+	if c.backend != nil {
+		dl.SetBackend(c.backend)
+	}
+	return dl
+}
+
+// Run downloads the jobs one after another and returns a result per video.
+// Failed videos are results, not errors. Cancelling ctx stops the running
+// download, keeping its partial file for a later run, and leaves the
+// remaining jobs alone; Run then returns what finished with ctx.Err().
+// Options that cannot work, such as a WithWindow spec that does not parse,
+// fail Run before anything is downloaded.
+//
+// Single videos that failed or were cancelled are downloaded again before Run
+// returns when a CommandRetry for them arrives through Send.
+func (c *Client) Run(ctx context.Context, jobs ...Job) ([]Result, error) {
+	if _, err := ParseSchedule(c.opts.Window, c.opts.WeekdayWindows); err != nil {
+		return nil, fmt.Errorf("download window: %w", err)
+	}
+	if err := os.MkdirAll(c.opts.OutputDir, 0o755); err != nil {
+		err = fmt.Errorf("cannot create output directory: %w", err)
+		c.bus.Publish(Progress{Key: c.opts.OutputDir, Title: c.opts.OutputDir, Status: "error", Error: err.Error()})
+		return nil, err
+	}
+
+	ctrl := downloader.NewControl()
+	c.mu.Lock()
+	c.controls[ctrl] = true
+	c.mu.Unlock()
+	stop := context.AfterFunc(ctx, ctrl.Abort)
+	defer func() {
+		stop()
+		c.mu.Lock()
+		delete(c.controls, ctrl)
+		c.mu.Unlock()
+	}()
+
+	var results []Result
+	singles := map[string]int{} // result key → index in results
+	singleJobs := map[string]Job{}
+	newDownloader := func(job Job) *downloader.Downloader {
+		dl := c.downloader(job.options(c.opts))
+		dl.SetControl(ctrl)
+		return dl
+	}
+	retrySingles := func() {
+		for key, i := range singles {
+			if results[i].Success || !ctrl.TakeRetry(key) {
+				continue
+			}
+			results[i] = newDownloader(singleJobs[key]).DownloadSingle(singleJobs[key].URL)
+		}
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		dl := newDownloader(job)
+		if job.Playlist {
+			results = append(results, dl.DownloadPlaylistContext(ctx, job.URL)...)
+		} else {
+			result := dl.DownloadSingle(job.URL)
+			singles[result.Key] = len(results)
+			singleJobs[result.Key] = job
+			results = append(results, result)
+		}
+		retrySingles()
+	}
+	return results, ctx.Err()
+}
+
+// Download fetches a single video. A failed download returns its result and
+// a *KindError saying why; a cancelled one returns its result with Skipped
+// set and no error.
+func (c *Client) Download(ctx context.Context, url string) (Result, error) {
+	results, err := c.Run(ctx, Job{URL: url})
+	if len(results) == 0 {
+		return Result{URL: url}, err
+	}
+	result := results[0]
+	if err == nil && !result.Success && !result.Skipped {
+		err = &KindError{Kind: result.ErrorKind, Err: errors.New(result.Error)}
+	}
+	return result, err
+}
+
+// DownloadPlaylist fetches the pending entries of a playlist into its own
+// directory, resuming from the saved state. Per-entry failures are in the
+// results; the error is only set when the run could not start or ctx ended.
+func (c *Client) DownloadPlaylist(ctx context.Context, url string) ([]Result, error) {
+	return c.Run(ctx, Job{URL: url, Playlist: true})
+}
+
+// Send cancels, pauses, resumes or retries an item of a running download,
+// addressed by Progress.Key. Commands for unknown keys are ignored.
+func (c *Client) Send(cmd Command) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ctrl := range c.controls {
+		ctrl.Apply(cmd)
+	}
+}
+
+// Listen sends every command from commands until the channel is closed.
+func (c *Client) Listen(commands <-chan Command) {
+	for cmd := range commands {
+		c.Send(cmd)
+	}
+}
+
+// Formats lists the formats available for a single video.
+func (c *Client) Formats(ctx context.Context, url string) (FormatList, error) {
+	return c.downloader(c.opts).FetchFormatsContext(ctx, url)
+}
+
+// Playlist lists a playlist's entries without downloading anything.
+func (c *Client) Playlist(ctx context.Context, url string) (PlaylistInfo, error) {
+	return c.downloader(c.opts).FetchPlaylistContext(ctx, url)
+}
+
+// PlaylistDir is the directory a playlist with this title is downloaded to;
+// LoadPlaylistState reads its saved state.
+func (c *Client) PlaylistDir(title, url string) string {
+	return c.downloader(c.opts).PlaylistDir(title, url)
+}
+
+// Plan reports what Run would do for job without downloading or writing
+// anything.
+func (c *Client) Plan(ctx context.Context, job Job) (Plan, error) {
+	return c.downloader(job.options(c.opts)).Plan(ctx, job.URL)
+}
+
+// ResolveBinary returns the yt-dlp binary downloads would run.
+func (c *Client) ResolveBinary() (string, error) {
+	return c.downloader(c.opts).ResolveBinary()
+}
+
+// DetectCapabilities finds out what the binary downloads would use supports.
+// The answer is cached per binary in the user cache directory until the
+// binary changes. Pass the result to WithCapabilities.
+func (c *Client) DetectCapabilities() (Capabilities, error) {
+	bin, err := c.ResolveBinary()
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return downloader.CachedCapabilities(bin)
+}
+
+// DetectCapabilities runs bin --version and bin --help.
+func DetectCapabilities(bin string) (Capabilities, error) {
+	return downloader.DetectCapabilities(bin)
+}
+
+// ValidateCookiesFile checks that path is a Netscape-format cookies file.
+func ValidateCookiesFile(path string) error {
+	return downloader.ValidateCookiesFile(path)
+}
+
+// RecordFiles names the files SaveRecords wrote.
+type RecordFiles struct {
+	Record  string
+	Mapping string
+}
+
+// SaveRecords appends results to the download record and subtitle mapping
+// files in the output directory. Cancelled items are left out.
+func (c *Client) SaveRecords(results []Result) (RecordFiles, error) {
+	mgr := record.NewManager(c.opts.LogFormat, c.opts.RecordFile, c.opts.MappingFile, c.opts.OutputDir)
+	for _, r := range results {
+		if !r.Skipped {
+			mgr.Add(r)
+		}
+	}
+	return RecordFiles{Record: mgr.RecordPath(), Mapping: mgr.MappingPath()}, mgr.Flush()
+}
@@ -0,0 +1,148 @@
+package ytdl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBackend writes a file per URL; URLs in fail fail with their kind, and
+// block holds a download until its context ends.
+type fakeBackend struct {
+	fail    map[string]ErrorKind
+	block   bool
+	started chan struct{}
+	once    sync.Once
+}
+
+func (b *fakeBackend) Name() string { return "fake" }
+
+func (b *fakeBackend) Probe(ctx context.Context, url string) (FormatList, error) {
+	return FormatList{VideoID: "vid", Title: "Video"}, nil
+}
+
+func (b *fakeBackend) ListPlaylist(ctx context.Context, url string) (PlaylistInfo, error) {
+	return PlaylistInfo{Title: "List", Entries: []PlaylistEntry{
+		{ID: "a", Title: "A", URL: "https://example.com/a"},
+		{ID: "b", Title: "B", URL: "https://example.com/b"},
+	}}, nil
+}
+
+func (b *fakeBackend) Download(ctx context.Context, url, outDir string, log io.Writer, progress func(BackendProgress)) (Fetched, error) {
+	name := filepath.Base(url)
+	info := VideoInfo{ID: name, Title: name, Filename: filepath.Join(outDir, name+".mp4")}
+	progress(BackendProgress{Status: "downloading", Percent: 50, Info: &info})
+	if b.block {
+		b.once.Do(func() { close(b.started) })
+		<-ctx.Done()
+		return Fetched{Info: info}, ctx.Err()
+	}
+	if kind, ok := b.fail[url]; ok {
+		return Fetched{Info: info}, &KindError{Kind: kind, Err: fmt.Errorf("%s failed", name)}
+	}
+	return Fetched{Info: info}, os.WriteFile(info.Filename, []byte("video"), 0o644)
+}
+
+func TestClientDownloadsAndReportsProgress(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var statuses []string
+	client := New(
+		WithOutputDir(dir),
+		WithoutSubtitles(),
+		WithRetries(0, 0),
+		WithBackend(&fakeBackend{fail: map[string]ErrorKind{"https://example.com/b": ErrorPrivate}}),
+		WithProgress(func(p Progress) {
+			mu.Lock()
+			statuses = append(statuses, p.Status)
+			mu.Unlock()
+		}),
+	)
+
+	result, err := client.Download(context.Background(), "https://example.com/one")
+	if err != nil || !result.Success {
+		t.Fatalf("Download = %+v, %v", result, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "one.mp4")); err != nil {
+		t.Fatalf("video not written: %v", err)
+	}
+
+	results, err := client.DownloadPlaylist(context.Background(), "https://example.com/list")
+	if err != nil || len(results) != 2 {
+		t.Fatalf("DownloadPlaylist = %d results, %v", len(results), err)
+	}
+	if !results[0].Success || results[1].Success || results[1].ErrorKind != ErrorPrivate {
+		t.Fatalf("playlist results = %+v", results)
+	}
+	state, err := LoadPlaylistState(client.PlaylistDir("List", "https://example.com/list"))
+	if err != nil || len(state.Entries) != 2 || state.Entries[1].Status != StatusUnavailable {
+		t.Fatalf("playlist state = %+v, %v", state, err)
+	}
+
+	var kindErr *KindError
+	_, err = client.Download(context.Background(), "https://example.com/b")
+	if !errors.As(err, &kindErr) || kindErr.Kind != ErrorPrivate {
+		t.Fatalf("failed Download error = %v", err)
+	}
+
+	files, err := client.SaveRecords(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(files.Record); err != nil {
+		t.Fatalf("record not written: %v", err)
+	}
+
+	client.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(statuses) == 0 || statuses[len(statuses)-1] != "error" {
+		t.Fatalf("progress statuses = %v", statuses)
+	}
+}
+
+func TestClientRunStopsWhenContextEnds(t *testing.T) {
+	backend := &fakeBackend{block: true, started: make(chan struct{})}
+	client := New(WithOutputDir(t.TempDir()), WithoutSubtitles(), WithBackend(backend))
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-backend.started
+		cancel()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Run(ctx, Job{URL: "https://example.com/slow"}, Job{URL: "https://example.com/next"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Run error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+}
+
+func TestClientRunRejectsABadWindow(t *testing.T) {
+	backend := &fakeBackend{}
+	client := New(WithOutputDir(t.TempDir()), WithoutSubtitles(), WithBackend(backend), WithWindow("22-07", nil))
+	defer client.Close()
+
+	results, err := client.Run(context.Background(), Job{URL: "https://example.com/a"})
+	if err == nil || len(results) != 0 {
+		t.Fatalf("Run = %+v, %v; want an error and no downloads", results, err)
+	}
+	if _, err := client.Plan(context.Background(), Job{URL: "https://example.com/a"}); err == nil {
+		t.Fatal("Plan accepted the bad window")
+	}
+}
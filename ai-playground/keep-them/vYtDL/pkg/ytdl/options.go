@@ -0,0 +1,282 @@
+package ytdl
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithOptions replaces the whole configuration. Options given after it still
+// apply on top.
+func WithOptions(opts Options) Option {
+	return func(c *Client) { c.opts = opts }
+}
+
+// WithOutputDir sets the destination directory. Playlists get a sub-directory
+// named after their title.
+func WithOutputDir(dir string) Option {
+	return func(c *Client) { c.opts.OutputDir = dir }
+}
+
+// WithFormat sets the container format, e.g. "mp4" or "webm".
+func WithFormat(format string) Option {
+	return func(c *Client) { c.opts.Format = format }
+}
+
+// WithQuality sets the video height, e.g. "720". Empty picks the best.
+func WithQuality(quality string) Option {
+	return func(c *Client) { c.opts.Quality = quality }
+}
+
+// WithFormatSelector sets an explicit yt-dlp format selector such as
+// "137+140". It takes precedence over WithQuality.
+func WithFormatSelector(selector string) Option {
+	return func(c *Client) { c.opts.FormatSelector = selector }
+}
+
+// WithVideoCodecs sets the video codecs to prefer, in order, and those to
+// avoid altogether.
+func WithVideoCodecs(prefer, avoid []string) Option {
+	return func(c *Client) {
+		c.opts.PreferVideoCodecs = prefer
+		c.opts.AvoidVideoCodecs = avoid
+	}
+}
+
+// WithAudioCodecs sets the audio codecs to prefer, in order, and those to
+// avoid altogether.
+func WithAudioCodecs(prefer, avoid []string) Option {
+	return func(c *Client) {
+		c.opts.PreferAudioCodecs = prefer
+		c.opts.AvoidAudioCodecs = avoid
+	}
+}
+
+// WithMaxFPS caps the frame rate of the chosen video stream, e.g. "30".
+func WithMaxFPS(fps string) Option {
+	return func(c *Client) { c.opts.MaxFPS = fps }
+}
+
+// WithNoHDR excludes HDR video streams.
+func WithNoHDR() Option {
+	return func(c *Client) { c.opts.NoHDR = true }
+}
+
+// WithMaxFilesize caps the size of each chosen stream, e.g. "500M".
+func WithMaxFilesize(size string) Option {
+	return func(c *Client) { c.opts.MaxFilesize = size }
+}
+
+// WithTimeRange downloads only the clip between start and end (HH:MM:SS or
+// seconds).
+func WithTimeRange(start, end string) Option {
+	return func(c *Client) {
+		c.opts.StartTime = start
+		c.opts.EndTime = end
+	}
+}
+
+// WithSubtitles downloads subtitles in langs, including auto-generated ones
+// when auto is set and no manual subtitles exist.
+func WithSubtitles(auto bool, langs ...string) Option {
+	return func(c *Client) {
+		c.opts.WriteSubtitles = true
+		c.opts.WriteAutoSubs = auto
+		c.opts.SubtitleLangs = langs
+	}
+}
+
+// WithoutSubtitles disables subtitle downloads.
+func WithoutSubtitles() Option {
+	return func(c *Client) {
+		c.opts.WriteSubtitles = false
+		c.opts.WriteAutoSubs = false
+	}
+}
+
+// WithItems restricts playlist downloads to 1-based positions, e.g.
+// "1-10,15,20-".
+func WithItems(spec string) Option {
+	return func(c *Client) { c.opts.Items = spec }
+}
+
+// WithIndexPrefix starts playlist filenames with the entry's playlist
+// position, e.g. "0007 - Title.mp4". Feed listings and custom backends
+// are not prefixed.
+func WithIndexPrefix() Option {
+	return func(c *Client) { c.opts.IndexPrefix = true }
+}
+
+// WithResetPlaylistState discards saved playlist state before downloading.
+func WithResetPlaylistState() Option {
+	return func(c *Client) { c.opts.ResetPlaylistState = true }
+}
+
+// WithOverridePlaylistConfig replaces the options a playlist was started
+// with, saved in its .vytdl.yaml, instead of applying them.
+func WithOverridePlaylistConfig() Option {
+	return func(c *Client) { c.opts.OverridePlaylistConfig = true }
+}
+
+// WithFeed lists playlist jobs from their Atom or RSS feed (a URL or a local
+// file) instead of the download tool; see FeedURL. Only entries missing from
+// the playlist state, or the archive, are downloaded.
+func WithFeed() Option {
+	return func(c *Client) { c.opts.Feed = true }
+}
+
+// WithArchive skips playlist entries recorded in a yt-dlp style download
+// archive and adds new downloads to it.
+func WithArchive(path string) Option {
+	return func(c *Client) { c.opts.Archive = path }
+}
+
+// WithRecordFiles sets the format ("json" or "csv") and the base names,
+// without extension, of the files SaveRecords writes.
+func WithRecordFiles(format, recordFile, mappingFile string) Option {
+	return func(c *Client) {
+		c.opts.LogFormat = format
+		c.opts.RecordFile = recordFile
+		c.opts.MappingFile = mappingFile
+	}
+}
+
+// WithBinary sets the yt-dlp or youtube-dl binary.
+func WithBinary(path string) Option {
+	return func(c *Client) { c.opts.YTDLPBin = path }
+}
+
+// WithBackendName picks the download tool: BackendYTDLP, BackendYoutubeDL or
+// BackendHTTP. BackendAuto chooses per URL.
+func WithBackendName(name string) Option {
+	return func(c *Client) { c.opts.Backend = name }
+}
+
+// WithBackend downloads every URL through b instead of an external tool.
+func WithBackend(b Backend) Option {
+	return func(c *Client) { c.backend = b }
+}
+
+// WithCapabilities tells the client what the installed yt-dlp supports, so
+// options it lacks are left out. See Client.DetectCapabilities.
+func WithCapabilities(caps *Capabilities) Option {
+	return func(c *Client) { c.opts.Capabilities = caps }
+}
+
+// WithProxy sets an HTTP, HTTPS or SOCKS proxy URL.
+func WithProxy(proxy string) Option {
+	return func(c *Client) { c.opts.Proxy = proxy }
+}
+
+// WithCookiesFile passes a Netscape-format cookies file.
+func WithCookiesFile(path string) Option {
+	return func(c *Client) { c.opts.CookiesFile = path }
+}
+
+// WithCookiesFromBrowser loads cookies from a local browser profile, e.g.
+// "firefox".
+func WithCookiesFromBrowser(browser string) Option {
+	return func(c *Client) { c.opts.CookiesFromBrowser = browser }
+}
+
+// WithUserAgent overrides the User-Agent header.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.opts.UserAgent = ua }
+}
+
+// WithExtractorArgs forwards extractor options, e.g.
+// "youtube:player_client=web,android".
+func WithExtractorArgs(args string) Option {
+	return func(c *Client) { c.opts.ExtractorArgs = args }
+}
+
+// WithNetwork sets yt-dlp's own retry count and socket timeout in seconds,
+// and forces IPv4 when ipv4 is set.
+func WithNetwork(retries, socketTimeout string, ipv4 bool) Option {
+	return func(c *Client) {
+		c.opts.Retries = retries
+		c.opts.SocketTimeout = socketTimeout
+		c.opts.ForceIPv4 = ipv4
+	}
+}
+
+// WithLimitRate caps download bandwidth, e.g. "2M".
+func WithLimitRate(rate string) Option {
+	return func(c *Client) { c.opts.LimitRate = rate }
+}
+
+// WithRetries re-runs a download up to max times after a transient failure,
+// waiting delay before the first re-run and doubling it after.
+func WithRetries(max int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.opts.MaxRetries = max
+		c.opts.RetryDelay = delay
+	}
+}
+
+// WithRateLimitCooldown pauses the queue this long after a rate limit.
+func WithRateLimitCooldown(d time.Duration) Option {
+	return func(c *Client) { c.opts.RateLimitCooldown = d }
+}
+
+// WithMaxCooldowns fails an item once n rate-limit cooldowns followed each
+// other without a successful download.
+func WithMaxCooldowns(n int) Option {
+	return func(c *Client) { c.opts.MaxCooldowns = n }
+}
+
+// WithMaxAttempts gives up on a playlist entry after n attempts across runs.
+func WithMaxAttempts(n int) Option {
+	return func(c *Client) { c.opts.MaxAttempts = n }
+}
+
+// WithRetryKinds only retries previously failed playlist entries of kinds.
+func WithRetryKinds(kinds ...ErrorKind) Option {
+	return func(c *Client) { c.opts.RetryKinds = kinds }
+}
+
+// WithWindow only starts downloads inside a daily window such as
+// "22:00-07:00". weekdays overrides it per day, keyed by mon … sun. Run and
+// Plan fail if they do not parse; check them first with ParseSchedule.
+func WithWindow(window string, weekdays map[string]string) Option {
+	return func(c *Client) {
+		c.opts.Window = window
+		c.opts.WeekdayWindows = weekdays
+	}
+}
+
+// WithExecAfter runs a shell command after each finished item.
+func WithExecAfter(command string) Option {
+	return func(c *Client) { c.opts.ExecAfter = command }
+}
+
+// WithWebhook POSTs a HookPayload to url after each finished item.
+func WithWebhook(url string) Option {
+	return func(c *Client) { c.opts.Webhook = url }
+}
+
+// WithHookTimeout bounds each exec-after command and webhook request.
+func WithHookTimeout(d time.Duration) Option {
+	return func(c *Client) { c.opts.HookTimeout = d }
+}
+
+// WithLogger receives the client's decisions: retries, skips, state saves.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) { c.logger = l }
+}
+
+// WithWarnings calls fn with messages meant for the user, such as options a
+// resumed playlist kept from its .vytdl.yaml. fn runs on the downloading
+// goroutine and should return quickly.
+func WithWarnings(fn func(msg string)) Option {
+	return func(c *Client) { c.warn = fn }
+}
+
+// WithProgress calls fn for every update, in order, on a goroutine of its own.
+// fn may be slow: updates are buffered, not dropped. Close waits for the last
+// call to return.
+func WithProgress(fn func(Progress)) Option {
+	return func(c *Client) { c.callbacks = append(c.callbacks, fn) }
+}
@@ -0,0 +1,206 @@
+// Package ytdl downloads videos and playlists with yt-dlp, youtube-dl or plain
+// HTTP. It is the public face of the yt-dl command: the same queueing,
+// playlist resume state, retries, hooks and record files, usable from other Go
+// programs without shelling out to the binary.
+//
+//	client := ytdl.New(ytdl.WithOutputDir("downloads"), ytdl.WithQuality("1080"),
+//		ytdl.WithProgress(func(p ytdl.Progress) { log.Println(p.Title, p.Status, p.Percent) }))
+//	defer client.Close()
+//	result, err := client.Download(ctx, "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+//
+// Most types below are aliases of the command's internal types, not copies:
+// a field added to, renamed in or removed from the downloader, playlist state
+// or record packages changes them in the same release. They carry no
+// compatibility promise, and neither do the string values of Status, Action
+// and ErrorKind; pin the module version and review its changes when you
+// upgrade.
+package ytdl
+
+import (
+	"github.com/innate/yt-dl/internal/downloader"
+	"github.com/innate/yt-dl/internal/feed"
+	"github.com/innate/yt-dl/internal/playliststate"
+	"github.com/innate/yt-dl/internal/record"
+)
+
+type (
+	// Options is the complete download configuration. The With… options set
+	// single fields; WithOptions replaces all of them.
+	Options = downloader.Options
+
+	// Progress is one update about one item. Status is one of "queued",
+	// "waiting", "starting", "downloading", "merging", "done", "error",
+	// "skipped", "paused", "retrying" or "cooldown".
+	Progress = downloader.ProgressUpdate
+
+	// Result is the outcome of one video.
+	Result = downloader.DownloadResult
+
+	// ErrorKind classifies why a download failed.
+	ErrorKind = downloader.ErrorKind
+
+	// FormatList and Format describe the streams available for a video.
+	FormatList = downloader.FormatList
+	Format     = downloader.Format
+
+	// PlaylistInfo and PlaylistEntry are the flat listing of a playlist.
+	PlaylistInfo  = downloader.PlaylistInfo
+	PlaylistEntry = downloader.PlaylistEntry
+
+	// Plan and PlanEntry describe what a download would do.
+	Plan      = downloader.Plan
+	PlanEntry = downloader.PlanEntry
+
+	// Capabilities lists what the installed yt-dlp supports.
+	Capabilities = downloader.Capabilities
+
+	// ItemSet is a parsed playlist position list such as "1-10,15,20-".
+	ItemSet = downloader.ItemSet
+
+	// Schedule is a parsed download window.
+	Schedule = downloader.Schedule
+
+	// Command cancels, pauses, resumes or retries one item by its Progress.Key.
+	Command       = downloader.Command
+	CommandAction = downloader.CommandAction
+
+	// Policy and Subscription are a progress subscriber's buffering.
+	Policy       = downloader.Policy
+	Subscription = downloader.Subscription
+
+	// Bus fans progress out to subscribers; every Client has one. Programs
+	// that run several clients can forward them into a Bus of their own.
+	Bus = downloader.Bus
+
+	// Backend replaces the download tool, e.g. with a fake in tests.
+	Backend         = downloader.Backend
+	BackendProgress = downloader.Progress
+	Fetched         = downloader.Fetched
+	VideoInfo       = downloader.VideoInfo
+	KindError       = downloader.KindError
+
+	// HookPayload is the JSON sent to --exec-after and --webhook hooks.
+	HookPayload = downloader.HookPayload
+
+	// Record is one entry of the download record file.
+	Record = record.DownloadRecord
+
+	// PlaylistState is a playlist's saved resume state.
+	PlaylistState      = playliststate.State
+	PlaylistEntryState = playliststate.EntryState
+)
+
+// LogDir is the directory, inside an output directory, that holds the
+// per-item tool logs.
+const LogDir = downloader.LogDir
+
+// Error kinds.
+const (
+	ErrorPrivate       = downloader.ErrorPrivate
+	ErrorUnavailable   = downloader.ErrorUnavailable
+	ErrorMembersOnly   = downloader.ErrorMembersOnly
+	ErrorAgeRestricted = downloader.ErrorAgeRestricted
+	ErrorGeoBlocked    = downloader.ErrorGeoBlocked
+	ErrorRateLimited   = downloader.ErrorRateLimited
+	ErrorBotCheck      = downloader.ErrorBotCheck
+	ErrorNetwork       = downloader.ErrorNetwork
+	ErrorFFmpeg        = downloader.ErrorFFmpeg
+	ErrorFormat        = downloader.ErrorFormat
+	ErrorDisk          = downloader.ErrorDisk
+	ErrorBinary        = downloader.ErrorBinary
+	ErrorUnknown       = downloader.ErrorUnknown
+)
+
+// Commands.
+const (
+	CommandCancel = downloader.CommandCancel
+	CommandRetry  = downloader.CommandRetry
+	CommandPause  = downloader.CommandPause
+	CommandResume = downloader.CommandResume
+)
+
+// Subscription policies; see Client.Subscribe.
+const (
+	Block            = downloader.Block
+	DropProgress     = downloader.DropProgress
+	CoalesceProgress = downloader.CoalesceProgress
+)
+
+// Plan actions.
+const (
+	ActionDownload = downloader.ActionDownload
+	ActionRetry    = downloader.ActionRetry
+	ActionSkip     = downloader.ActionSkip
+)
+
+// Backend names for WithBackendName.
+const (
+	BackendAuto      = downloader.BackendAuto
+	BackendYTDLP     = downloader.BackendYTDLP
+	BackendYoutubeDL = downloader.BackendYoutubeDL
+	BackendHTTP      = downloader.BackendHTTP
+)
+
+// Playlist entry statuses in PlaylistEntryState.Status.
+const (
+	StatusPending     = playliststate.StatusPending
+	StatusRunning     = playliststate.StatusRunning
+	StatusSucceeded   = playliststate.StatusSucceeded
+	StatusFailed      = playliststate.StatusFailed
+	StatusUnavailable = playliststate.StatusUnavailable
+	StatusSkipped     = playliststate.StatusSkipped
+)
+
+// Placeholders in PlaylistEntryState.ListedAs.
+const (
+	ListedPrivate = playliststate.ListedPrivate
+	ListedDeleted = playliststate.ListedDeleted
+)
+
+// DefaultOptions returns the defaults New starts from.
+func DefaultOptions() Options {
+	return downloader.DefaultOptions()
+}
+
+// NewRecord converts a result into its record file entry.
+func NewRecord(r Result) Record {
+	return record.FromResult(r)
+}
+
+// LoadPlaylistState reads the resume state saved in a playlist directory, as
+// returned by Client.PlaylistDir.
+func LoadPlaylistState(playlistDir string) (PlaylistState, error) {
+	return playliststate.Load(playliststate.StatePath(playlistDir))
+}
+
+// ParseErrorKinds parses a comma-separated list of error kinds.
+func ParseErrorKinds(spec string) ([]ErrorKind, error) {
+	return downloader.ParseErrorKinds(spec)
+}
+
+// ParseItems parses a playlist position list for WithItems.
+func ParseItems(spec string) (ItemSet, error) {
+	return downloader.ParseItems(spec)
+}
+
+// ParseSchedule parses the arguments of WithWindow.
+func ParseSchedule(window string, weekdays map[string]string) (*Schedule, error) {
+	return downloader.ParseSchedule(window, weekdays)
+}
+
+// NewBus returns a Bus without subscribers.
+func NewBus() *Bus {
+	return downloader.NewBus()
+}
+
+// FeedURL returns the Atom feed of a YouTube channel (/channel/UC…) or
+// playlist URL. Other URLs and local paths are returned unchanged.
+func FeedURL(rawURL string) (string, error) {
+	return feed.URLFor(rawURL)
+}
+
+// LooksLikePlaylist guesses whether rawURL is a playlist or channel rather
+// than a single video.
+func LooksLikePlaylist(rawURL string) bool {
+	return downloader.LooksLikePlaylist(rawURL)
+}
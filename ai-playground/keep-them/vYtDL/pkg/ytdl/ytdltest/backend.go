@@ -0,0 +1,194 @@
+package ytdltest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+// Backend is an in-process ytdl.Backend playing a Scenario. Pass it to
+// ytdl.WithBackend. It is safe for concurrent use.
+type Backend struct {
+	scenario Scenario
+
+	mu       sync.Mutex
+	attempts map[string]int // video ID → downloads started
+}
+
+// NewBackend returns a backend for s.
+func NewBackend(s Scenario) *Backend {
+	return &Backend{scenario: s, attempts: map[string]int{}}
+}
+
+// Name implements ytdl.Backend.
+func (b *Backend) Name() string { return "ytdltest" }
+
+// Attempts reports how many downloads of the video have started.
+func (b *Backend) Attempts(id string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempts[id]
+}
+
+// Probe implements ytdl.Backend.
+func (b *Backend) Probe(ctx context.Context, url string) (ytdl.FormatList, error) {
+	v, ok := b.scenario.video(url)
+	if !ok {
+		return ytdl.FormatList{}, fmt.Errorf("list formats: %s", StderrLine(ytdl.ErrorUnavailable, url))
+	}
+	return ytdl.FormatList{VideoID: v.ID, Title: v.Title, Duration: v.Duration, Formats: v.formats()}, nil
+}
+
+// ListPlaylist implements ytdl.Backend.
+func (b *Backend) ListPlaylist(ctx context.Context, url string) (ytdl.PlaylistInfo, error) {
+	p, ok := b.scenario.Playlists[url]
+	if !ok {
+		return ytdl.PlaylistInfo{}, fmt.Errorf("no playlist at %s", url)
+	}
+	info := ytdl.PlaylistInfo{Title: p.Title}
+	for _, e := range b.scenario.entries(p) {
+		info.Entries = append(info.Entries, ytdl.PlaylistEntry{ID: e.ID, Title: e.Title, URL: e.WebpageURL, Duration: e.Duration})
+	}
+	return info, nil
+}
+
+// Download implements ytdl.Backend. Failures carry yt-dlp's stderr line, so
+// the downloader classifies them exactly as it would real output.
+func (b *Backend) Download(ctx context.Context, url, outDir string, log io.Writer, progress func(ytdl.BackendProgress)) (ytdl.Fetched, error) {
+	var fetched ytdl.Fetched
+	v, ok := b.scenario.video(url)
+	if !ok {
+		fetched.Stderr = []string{StderrLine(ytdl.ErrorUnavailable, url)}
+		fmt.Fprintln(log, fetched.Stderr[0])
+		return fetched, errors.New("exit status 1")
+	}
+	b.mu.Lock()
+	b.attempts[v.ID]++
+	attempt := b.attempts[v.ID]
+	b.mu.Unlock()
+
+	for _, step := range v.steps(outDir) {
+		if err := sleep(ctx, v.Delay); err != nil {
+			return fetched, err
+		}
+		fmt.Fprintln(log, step.line)
+		if step.progress != nil {
+			progress(*step.progress)
+		}
+	}
+
+	if kind := v.failure(attempt); kind != "" {
+		fetched.Stderr = []string{StderrLine(kind, v.ID)}
+		fmt.Fprintln(log, fetched.Stderr[0])
+		return fetched, errors.New("exit status 1")
+	}
+	info := v.info(outDir)
+	if err := v.writeFiles(outDir, true); err != nil {
+		return fetched, err
+	}
+	if v.Merge {
+		progress(ytdl.BackendProgress{Status: "merging", Percent: 100, Info: &info})
+	}
+	fetched.Info = info
+	return fetched, nil
+}
+
+// step is one line of simulated yt-dlp output.
+type step struct {
+	line     string
+	progress *ytdl.BackendProgress // nil for lines that report nothing
+}
+
+// steps is the output of a download before it fails or finishes.
+func (v Video) steps(outDir string) []step {
+	percents := v.Progress
+	if len(percents) == 0 {
+		percents = []float64{0, 50, 100}
+	}
+	size := v.Size
+	if size == "" {
+		size = "10.00MiB"
+	}
+	steps := []step{{line: fmt.Sprintf("[youtube] Extracting URL: %s", v.PageURL())}}
+	for _, pct := range percents {
+		eta := "00:00"
+		if pct < 100 {
+			eta = fmt.Sprintf("00:%02d", int(100-pct)/10)
+		}
+		steps = append(steps, step{
+			line: fmt.Sprintf("[download] %5.1f%% of %s at  1.00MiB/s ETA %s", pct, size, eta),
+			progress: &ytdl.BackendProgress{Status: "downloading", Percent: pct, Speed: "1.00MiB/s", ETA: eta,
+				TotalBytes: parseSize(size)},
+		})
+	}
+	if v.Merge {
+		steps = append(steps, step{line: fmt.Sprintf("[Merger] Merging formats into %q", v.Filename(outDir))})
+	}
+	return steps
+}
+
+func (v Video) info(outDir string) ytdl.VideoInfo {
+	formatIDs := make([]string, 0, 2)
+	for _, f := range v.formats() {
+		formatIDs = append(formatIDs, f.ID)
+	}
+	return ytdl.VideoInfo{ID: v.ID, Title: v.Title, Ext: v.ext(), FormatID: strings.Join(formatIDs, "+"),
+		Duration: v.Duration, UploadDate: v.UploadDate, Thumbnail: v.Thumbnail, Description: v.Description,
+		Filename: v.Filename(outDir)}
+}
+
+// writeFiles creates the video and, when asked for, its subtitle files.
+func (v Video) writeFiles(outDir string, subtitles bool) error {
+	if err := os.WriteFile(v.Filename(outDir), []byte("ytdltest video "+v.ID+"\n"), 0o644); err != nil {
+		return err
+	}
+	if !subtitles {
+		return nil
+	}
+	for _, lang := range v.Subtitles {
+		sub := filepath.Join(outDir, fileBase(v.Title)+"."+lang+".vtt")
+		if err := os.WriteFile(sub, []byte("WEBVTT\n\n00:00.000 --> 00:01.000\n"+v.Title+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// parseSize reads sizes like "10.00MiB" from progress lines.
+func parseSize(s string) int64 {
+	units := []struct {
+		suffix string
+		mult   float64
+	}{{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"B", 1}}
+	for _, u := range units {
+		if num, ok := strings.CutSuffix(s, u.suffix); ok {
+			var f float64
+			if _, err := fmt.Sscanf(num, "%f", &f); err == nil {
+				return int64(f * u.mult)
+			}
+		}
+	}
+	return 0
+}
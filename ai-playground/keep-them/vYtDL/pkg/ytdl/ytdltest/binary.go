@@ -0,0 +1,208 @@
+package ytdltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+// envScenario points a re-executed test binary at its scenario file.
+const envScenario = "YTDLTEST_SCENARIO"
+
+// Binary is a fake yt-dlp executable written by WriteBinary.
+type Binary struct {
+	// Path is the executable; pass it to ytdl.WithBinary.
+	Path string
+	dir  string
+}
+
+// WriteBinary writes an executable to dir that behaves like yt-dlp playing s.
+// It starts the running test binary again, so the test package must call
+// RunIfFake at the top of TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		ytdltest.RunIfFake()
+//		os.Exit(m.Run())
+//	}
+func WriteBinary(dir string, s Scenario) (*Binary, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	scenarioPath := filepath.Join(dir, "ytdltest-scenario.json")
+	if err := os.WriteFile(scenarioPath, data, 0o644); err != nil {
+		return nil, err
+	}
+
+	b := &Binary{dir: dir}
+	var launcher string
+	if runtime.GOOS == "windows" {
+		b.Path = filepath.Join(dir, "yt-dlp.bat")
+		launcher = fmt.Sprintf("@set %s=%s\r\n@\"%s\" %%*\r\n", envScenario, scenarioPath, exe)
+	} else {
+		b.Path = filepath.Join(dir, "yt-dlp")
+		launcher = fmt.Sprintf("#!/bin/sh\n%s=%s exec %s \"$@\"\n", envScenario, shellQuote(scenarioPath), shellQuote(exe))
+	}
+	if err := os.WriteFile(b.Path, []byte(launcher), 0o755); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Attempts reports how many downloads of the video the binary has started.
+func (b *Binary) Attempts(id string) int {
+	data, err := os.ReadFile(b.attemptsFile(id))
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return n
+}
+
+// Invocations returns the arguments of every run of the binary so far.
+func (b *Binary) Invocations() [][]string {
+	data, err := os.ReadFile(filepath.Join(b.dir, "ytdltest-invocations.log"))
+	if err != nil {
+		return nil
+	}
+	var calls [][]string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var args []string
+		if json.Unmarshal([]byte(line), &args) == nil {
+			calls = append(calls, args)
+		}
+	}
+	return calls
+}
+
+func (b *Binary) attemptsFile(id string) string {
+	return filepath.Join(b.dir, "ytdltest-"+fileBase(id)+".attempts")
+}
+
+// RunIfFake acts as yt-dlp and exits when the process was started by a
+// WriteBinary launcher. Otherwise it returns at once.
+func RunIfFake() {
+	path := os.Getenv(envScenario)
+	if path == "" {
+		return
+	}
+	os.Exit(runFake(path, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// runFake answers one yt-dlp command line from the scenario at path.
+func runFake(path string, args []string, stdout, stderr io.Writer) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(stderr, "ytdltest:", err)
+		return 2
+	}
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		fmt.Fprintln(stderr, "ytdltest:", err)
+		return 2
+	}
+	b := &Binary{dir: filepath.Dir(path)}
+	if line, err := json.Marshal(args); err == nil {
+		if f, err := os.OpenFile(filepath.Join(b.dir, "ytdltest-invocations.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err == nil {
+			fmt.Fprintf(f, "%s\n", line)
+			f.Close()
+		}
+	}
+
+	has := func(flag string) bool { return slices.Contains(args, flag) }
+	url := ""
+	if len(args) > 0 {
+		url = args[len(args)-1]
+	}
+	enc := json.NewEncoder(stdout)
+
+	switch {
+	case has("--version"):
+		version := s.Version
+		if version == "" {
+			version = DefaultVersion
+		}
+		fmt.Fprintln(stdout, version)
+		return 0
+	case has("--help"):
+		fmt.Fprintln(stdout, "Usage: yt-dlp [OPTIONS] URL [URL...]\n\nOptions:")
+		for _, flag := range helpFlags {
+			fmt.Fprintf(stdout, "    %s\n", flag)
+		}
+		return 0
+	case has("--dump-single-json") && has("--flat-playlist"):
+		p, ok := s.Playlists[url]
+		if !ok {
+			fmt.Fprintf(stderr, "ERROR: Unsupported URL: %s\n", url)
+			return 1
+		}
+		_ = enc.Encode(map[string]any{"_type": "playlist", "title": p.Title, "entries": s.entries(p)})
+		return 0
+	case has("--dump-single-json"):
+		v, ok := s.video(url)
+		if !ok {
+			fmt.Fprintln(stderr, StderrLine(ytdl.ErrorUnavailable, url))
+			return 1
+		}
+		_ = enc.Encode(map[string]any{"id": v.ID, "title": v.Title, "duration": v.Duration, "formats": v.formats()})
+		return 0
+	}
+
+	v, ok := s.video(url)
+	if !ok {
+		fmt.Fprintln(stderr, StderrLine(ytdl.ErrorUnavailable, url))
+		return 1
+	}
+	attempt := b.Attempts(v.ID) + 1
+	_ = os.WriteFile(b.attemptsFile(v.ID), []byte(strconv.Itoa(attempt)), 0o644)
+
+	// -o is "<dir>/%(title)s.%(ext)s"; the working directory is the fallback.
+	outDir := "."
+	if i := slices.Index(args, "-o"); i >= 0 && i+1 < len(args) {
+		outDir = filepath.Dir(args[i+1])
+	}
+	for _, step := range v.steps(outDir) {
+		time.Sleep(v.Delay)
+		fmt.Fprintln(stdout, step.line)
+	}
+	if kind := v.failure(attempt); kind != "" {
+		fmt.Fprintln(stderr, StderrLine(kind, v.ID))
+		return 1
+	}
+	if err := v.writeFiles(outDir, has("--write-subs") || has("--write-sub")); err != nil {
+		fmt.Fprintf(stderr, "ERROR: unable to open for writing: %v\n", err)
+		return 1
+	}
+	info := v.info(outDir)
+	_ = enc.Encode(map[string]any{"id": info.ID, "title": info.Title, "ext": info.Ext,
+		"format_id": info.FormatID, "duration": info.Duration, "upload_date": info.UploadDate,
+		"thumbnail": info.Thumbnail, "description": info.Description, "filename": info.Filename})
+	return 0
+}
+
+// helpFlags are the options --help lists: every one the downloader may use.
+var helpFlags = []string{
+	"--version", "--help", "--dump-single-json", "--flat-playlist", "--no-playlist",
+	"-f, --format", "--merge-output-format", "-o, --output", "--write-subs", "--write-auto-subs",
+	"--sub-langs", "--download-sections", "--force-keyframes-at-cuts", "--retries",
+	"--extractor-retries", "--socket-timeout", "--force-ipv4", "--proxy", "--cookies",
+	"--cookies-from-browser", "--user-agent", "--extractor-args", "--limit-rate",
+	"--newline", "--progress", "--print-json",
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,199 @@
+// Package ytdltest simulates yt-dlp for tests of code built on package ytdl.
+//
+// A Scenario declares what the simulated site holds: playlists, videos, how
+// their downloads progress and how individual attempts fail. NewBackend turns
+// it into an in-process ytdl.Backend; WriteBinary turns it into an executable
+// that answers the same command lines as yt-dlp, for tests that go through
+// the real command-line backend. Both count attempts per video, so retries,
+// resume and cancellation can be checked deterministically.
+package ytdltest
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+// Scenario is everything the simulated yt-dlp knows about.
+type Scenario struct {
+	// Playlists are keyed by the URL they are listed under.
+	Playlists map[string]Playlist `json:"playlists,omitempty"`
+	// Videos are found by URL, or by an ID at the end of the requested URL.
+	Videos []Video `json:"videos,omitempty"`
+	// Version is reported by --version. Empty reports DefaultVersion.
+	Version string `json:"version,omitempty"`
+}
+
+// DefaultVersion is the yt-dlp version a Scenario reports by default.
+const DefaultVersion = "2025.09.26"
+
+// Playlist is a flat playlist listing.
+type Playlist struct {
+	Title string `json:"title"`
+	// Entries are video IDs, in playlist order. IDs without a Video are
+	// listed but fail to download as unavailable.
+	Entries []string `json:"entries"`
+}
+
+// Video is one downloadable video and how its downloads go.
+type Video struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// URL defaults to https://www.youtube.com/watch?v=<ID>.
+	URL      string  `json:"url,omitempty"`
+	Ext      string  `json:"ext,omitempty"` // default "mp4"
+	Duration float64 `json:"duration,omitempty"`
+	// UploadDate (YYYYMMDD), Thumbnail and Description are reported with a
+	// finished download.
+	UploadDate  string `json:"upload_date,omitempty"`
+	Thumbnail   string `json:"thumbnail,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Formats are reported by Probe. Empty reports a 1080p H.264 video
+	// stream and an AAC audio stream.
+	Formats []ytdl.Format `json:"formats,omitempty"`
+
+	// Progress lists the percentages reported while downloading. Empty
+	// reports 0, 50 and 100.
+	Progress []float64 `json:"progress,omitempty"`
+	// Size is the total size shown in progress lines, e.g. "12.00MiB".
+	Size string `json:"size,omitempty"`
+	// Merge adds a merge stage after the download.
+	Merge bool `json:"merge,omitempty"`
+	// Delay is the pause before each line of output, for slow downloads and
+	// cancellation tests.
+	Delay time.Duration `json:"delay,omitempty"`
+	// Subtitles are languages written as <title>.<lang>.vtt next to the
+	// video. The binary only writes them when subtitles are requested.
+	Subtitles []string `json:"subtitles,omitempty"`
+
+	// Fail decides how each attempt ends, in order: the first attempt fails
+	// with Fail[0] and so on, and attempts past the end succeed. Use
+	// FailAlways for a video that never downloads.
+	Fail []ytdl.ErrorKind `json:"fail,omitempty"`
+	// FailAlways fails every attempt with this kind.
+	FailAlways ytdl.ErrorKind `json:"fail_always,omitempty"`
+}
+
+// Repeat returns kind n times, for Video.Fail.
+func Repeat(kind ytdl.ErrorKind, n int) []ytdl.ErrorKind {
+	kinds := make([]ytdl.ErrorKind, n)
+	for i := range kinds {
+		kinds[i] = kind
+	}
+	return kinds
+}
+
+// PageURL is the URL the video is downloaded from.
+func (v Video) PageURL() string {
+	if v.URL != "" {
+		return v.URL
+	}
+	return "https://www.youtube.com/watch?v=" + v.ID
+}
+
+func (v Video) ext() string {
+	if v.Ext != "" {
+		return v.Ext
+	}
+	return "mp4"
+}
+
+// Filename is where a successful download of v into dir ends up.
+func (v Video) Filename(dir string) string {
+	return filepath.Join(dir, fileBase(v.Title)+"."+v.ext())
+}
+
+// failure returns how the attempt-th attempt (1-based) ends; "" succeeds.
+func (v Video) failure(attempt int) ytdl.ErrorKind {
+	if v.FailAlways != "" {
+		return v.FailAlways
+	}
+	if attempt <= len(v.Fail) {
+		return v.Fail[attempt-1]
+	}
+	return ""
+}
+
+func (v Video) formats() []ytdl.Format {
+	if len(v.Formats) > 0 {
+		return v.Formats
+	}
+	return []ytdl.Format{
+		{ID: "137", Ext: "mp4", Note: "1080p", Width: 1920, Height: 1080, FPS: 30, VCodec: "avc1.640028", ACodec: "none", TBR: 4000, Filesize: 40_000_000},
+		{ID: "140", Ext: "m4a", Note: "medium", VCodec: "none", ACodec: "mp4a.40.2", TBR: 128, Filesize: 1_300_000},
+	}
+}
+
+// video finds the video a URL refers to.
+func (s Scenario) video(rawURL string) (Video, bool) {
+	for _, v := range s.Videos {
+		if rawURL == v.PageURL() {
+			return v, true
+		}
+	}
+	for _, v := range s.Videos {
+		if v.ID != "" && (strings.HasSuffix(rawURL, "="+v.ID) || strings.HasSuffix(rawURL, "/"+v.ID)) {
+			return v, true
+		}
+	}
+	return Video{}, false
+}
+
+// entries lists a playlist the way --flat-playlist does.
+func (s Scenario) entries(p Playlist) []flatEntry {
+	entries := make([]flatEntry, 0, len(p.Entries))
+	for _, id := range p.Entries {
+		entry := flatEntry{ID: id, WebpageURL: "https://www.youtube.com/watch?v=" + id}
+		for _, v := range s.Videos {
+			if v.ID == id {
+				entry = flatEntry{ID: id, Title: v.Title, WebpageURL: v.PageURL(), Duration: v.Duration}
+				break
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+type flatEntry struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	WebpageURL string  `json:"webpage_url"`
+	Duration   float64 `json:"duration,omitempty"`
+}
+
+// stderrLines are what yt-dlp prints for each kind of failure; the
+// downloader classifies them like real output.
+var stderrLines = map[ytdl.ErrorKind]string{
+	ytdl.ErrorPrivate:       "ERROR: [youtube] %s: Private video. Sign in if you've been granted access to this video",
+	ytdl.ErrorUnavailable:   "ERROR: [youtube] %s: Video unavailable. This video has been removed by the uploader",
+	ytdl.ErrorMembersOnly:   "ERROR: [youtube] %s: Join this channel to get access to members-only content like this video",
+	ytdl.ErrorAgeRestricted: "ERROR: [youtube] %s: Sign in to confirm your age. This video may be inappropriate for some users.",
+	ytdl.ErrorGeoBlocked:    "ERROR: [youtube] %s: The uploader has not made this video available in your country",
+	ytdl.ErrorRateLimited:   "ERROR: [youtube] %s: Unable to download webpage: HTTP Error 429: Too Many Requests",
+	ytdl.ErrorBotCheck:      "ERROR: [youtube] %s: Sign in to confirm you're not a bot. Use --cookies-from-browser or --cookies for the authentication.",
+	ytdl.ErrorNetwork:       "ERROR: [youtube] %s: unable to download video data: <urlopen error [Errno 104] Connection reset by peer>",
+	ytdl.ErrorFFmpeg:        "ERROR: [youtube] %s: Postprocessing: ffmpeg not found. Please install or provide the path using --ffmpeg-location",
+	ytdl.ErrorFormat:        "ERROR: [youtube] %s: Requested format is not available. Use --list-formats for a list of available formats",
+	ytdl.ErrorDisk:          "ERROR: [youtube] %s: unable to open for writing: [Errno 28] No space left on device",
+}
+
+// StderrLine is the error yt-dlp prints when a download of id fails with kind.
+func StderrLine(kind ytdl.ErrorKind, id string) string {
+	format, ok := stderrLines[kind]
+	if !ok {
+		format = "ERROR: [youtube] %s: something unexpected happened"
+	}
+	return fmt.Sprintf(format, id)
+}
+
+// unsafeChars matches what the downloader replaces in file names.
+var unsafeChars = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
+
+func fileBase(title string) string {
+	return strings.TrimSpace(unsafeChars.ReplaceAllString(title, "_"))
+}
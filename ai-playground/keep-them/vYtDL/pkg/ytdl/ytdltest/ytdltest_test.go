@@ -0,0 +1,151 @@
+package ytdltest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/innate/yt-dl/pkg/ytdl"
+)
+
+func TestMain(m *testing.M) {
+	RunIfFake()
+	os.Exit(m.Run())
+}
+
+const playlistURL = "https://www.youtube.com/playlist?list=PLtest"
+
+func scenario() Scenario {
+	return Scenario{
+		Playlists: map[string]Playlist{playlistURL: {Title: "Lectures", Entries: []string{"ok", "flaky", "limited", "gone", "geo"}}},
+		Videos: []Video{
+			{ID: "ok", Title: "Intro", Merge: true, Subtitles: []string{"en"}},
+			{ID: "flaky", Title: "Flaky", Fail: []ytdl.ErrorKind{ytdl.ErrorNetwork}},
+			{ID: "limited", Title: "Limited", Fail: []ytdl.ErrorKind{ytdl.ErrorRateLimited}},
+			{ID: "gone", Title: "Gone", FailAlways: ytdl.ErrorPrivate},
+			{ID: "geo", Title: "Geo", FailAlways: ytdl.ErrorGeoBlocked},
+		},
+	}
+}
+
+// checkPlaylistRun downloads the scenario playlist twice and checks retries,
+// classification and resume.
+func checkPlaylistRun(t *testing.T, client *ytdl.Client, attempts func(string) int) {
+	t.Helper()
+	results, err := client.DownloadPlaylist(context.Background(), playlistURL)
+	if err != nil || len(results) != 5 {
+		t.Fatalf("DownloadPlaylist = %d results, %v", len(results), err)
+	}
+	for i, want := range []ytdl.ErrorKind{"", "", "", ytdl.ErrorPrivate, ytdl.ErrorGeoBlocked} {
+		if r := results[i]; r.ErrorKind != want || r.Success != (want == "") {
+			t.Errorf("result %d (%s): success=%v kind=%q, want kind %q", i, r.VideoID, r.Success, r.ErrorKind, want)
+		}
+	}
+	if len(results[0].Subtitles) != 1 || filepath.Base(results[0].Subtitles[0]) != "Intro.en.vtt" {
+		t.Errorf("subtitles = %v", results[0].Subtitles)
+	}
+	if _, err := os.Stat(results[0].Filename); err != nil {
+		t.Errorf("video file: %v", err)
+	}
+	for id, want := range map[string]int{"ok": 1, "flaky": 2, "limited": 2, "gone": 1, "geo": 1} {
+		if got := attempts(id); got != want {
+			t.Errorf("attempts(%s) = %d, want %d", id, got, want)
+		}
+	}
+
+	// Everything left has failed permanently, so a resume downloads nothing.
+	again, err := client.DownloadPlaylist(context.Background(), playlistURL)
+	if err != nil || len(again) != 0 {
+		t.Fatalf("resume = %+v, %v", again, err)
+	}
+}
+
+func TestBackendPlaysScenario(t *testing.T) {
+	backend := NewBackend(scenario())
+	var mu sync.Mutex
+	var statuses []string
+	client := ytdl.New(
+		ytdl.WithOutputDir(t.TempDir()),
+		ytdl.WithSubtitles(false, "en"),
+		ytdl.WithRetries(1, time.Millisecond),
+		ytdl.WithRateLimitCooldown(0),
+		ytdl.WithBackend(backend),
+		ytdl.WithProgress(func(p ytdl.Progress) {
+			if p.Key == "ok" {
+				mu.Lock()
+				statuses = append(statuses, p.Status)
+				mu.Unlock()
+			}
+		}),
+	)
+	checkPlaylistRun(t, client, backend.Attempts)
+	client.Close()
+
+	// The resume reports the finished entry as skipped.
+	want := []string{"queued", "starting", "downloading", "downloading", "downloading", "merging", "done", "skipped"}
+	if !slices.Equal(statuses, want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestBinaryPlaysScenarioThroughYTDLP(t *testing.T) {
+	bin, err := WriteBinary(t.TempDir(), scenario())
+	if err != nil {
+		t.Fatal(err)
+	}
+	caps, err := ytdl.DetectCapabilities(bin.Path)
+	if err != nil || caps.Version != DefaultVersion || !caps.Supports("--download-sections") {
+		t.Fatalf("capabilities = %+v, %v", caps, err)
+	}
+	client := ytdl.New(
+		ytdl.WithOutputDir(t.TempDir()),
+		ytdl.WithSubtitles(false, "en"),
+		ytdl.WithRetries(1, time.Millisecond),
+		ytdl.WithRateLimitCooldown(0),
+		ytdl.WithBinary(bin.Path),
+		ytdl.WithCapabilities(&caps),
+	)
+	defer client.Close()
+	checkPlaylistRun(t, client, bin.Attempts)
+
+	formats, err := client.Formats(context.Background(), "https://www.youtube.com/watch?v=ok")
+	if err != nil || formats.Title != "Intro" || len(formats.Formats) != 2 {
+		t.Fatalf("Formats = %+v, %v", formats, err)
+	}
+	// --version, --help, listing twice, seven downloads and the probe.
+	if calls := bin.Invocations(); len(calls) != 12 {
+		t.Fatalf("invocations = %d: %v", len(calls), calls)
+	}
+}
+
+func TestSlowDownloadStopsOnCancel(t *testing.T) {
+	backend := NewBackend(Scenario{Videos: []Video{
+		{ID: "slow", Title: "Slow", Progress: []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}, Delay: 50 * time.Millisecond},
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := ytdl.New(ytdl.WithOutputDir(t.TempDir()), ytdl.WithoutSubtitles(), ytdl.WithBackend(backend),
+		ytdl.WithProgress(func(p ytdl.Progress) {
+			if p.Status == "downloading" && p.Percent >= 20 {
+				cancel()
+			}
+		}))
+	defer client.Close()
+
+	start := time.Now()
+	result, err := client.Download(ctx, "https://www.youtube.com/watch?v=slow")
+	if !errors.Is(err, context.Canceled) || result.Success {
+		t.Fatalf("Download = %+v, %v", result, err)
+	}
+	if took := time.Since(start); took > 400*time.Millisecond {
+		t.Fatalf("cancel took %s", took)
+	}
+	if _, err := os.Stat(Video{Title: "Slow"}.Filename(client.Options().OutputDir)); !os.IsNotExist(err) {
+		t.Fatalf("cancelled download left a video file: %v", err)
+	}
+}